@@ -0,0 +1,180 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"slices"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/vector"
+)
+
+const (
+	// accessibleOutline is the width of the dark outline drawn around
+	// symbols in high-contrast mode, relative to the symbol size.
+	accessibleOutline = 0.03
+	// colorShapeBadge is the size of the shape marking the color group of a
+	// tinted symbol, relative to the symbol size.
+	colorShapeBadge = 0.22
+)
+
+// outlineColor is the color of high-contrast outlines and shape badges.
+var outlineColor = color.NRGBA{R: 0x11, G: 0x11, B: 0x11, A: 0xff}
+
+// applyAccessibility prepares a rendered symbol for --high-contrast and
+// --color-shapes: it darkens symbols that are too light for the white card,
+// outlines their silhouette and marks tinted symbols with the shape of their
+// color group.
+func (o options) applyAccessibility(img image.Image, look SymbolLook, src *symbolSource) image.Image {
+	if o.highContrast {
+		img = enforceContrast(img, o.minContrast)
+		img = outlineSilhouette(img, int(math.Ceil(float64(max(img.Bounds().Dx(), img.Bounds().Dy()))*accessibleOutline)))
+	}
+	if o.colorShapes && look.Tint != "" && src.monochrome() {
+		img = drawColorShape(img, o.colorShape(look.Tint))
+	}
+	return img
+}
+
+// colorShape returns the index of the shape marking a tint: its position in
+// the palette, or a value derived from the color for tints fixed by
+// constraints.
+func (o options) colorShape(tint string) int {
+	if i := slices.Index(o.tintPalette, tint); i >= 0 {
+		return i % len(proceduralShapes)
+	}
+	c, _ := parseHexColor(tint)
+	return (int(c.R) + 3*int(c.G) + 7*int(c.B)) % len(proceduralShapes)
+}
+
+// imageContrast returns the contrast ratio between the average visible color
+// of img and the background luminance. Pixels of the background color, like
+// the white around clip art, are not part of the symbol.
+func imageContrast(img image.Image, bg float64) float64 {
+	var lum, weight float64
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A < 0x80 {
+				continue
+			}
+			l := relativeLuminance(c)
+			if contrastRatio(l, bg) < 1.1 {
+				continue
+			}
+			lum += l
+			weight++
+		}
+	}
+	if weight == 0 {
+		return 1
+	}
+	return contrastRatio(lum/weight, bg)
+}
+
+// enforceContrast darkens img until its average color reaches minRatio
+// against white. Darkening keeps the hues, so symbols stay recognizable.
+func enforceContrast(img image.Image, minRatio float64) image.Image {
+	ratio := imageContrast(img, 1)
+	if ratio >= minRatio || ratio <= 1 {
+		return img
+	}
+	// Luminance follows sRGB values to the power of about 2.4, so scaling
+	// the channels by k scales the luminance by about k^2.4.
+	current := 1.05/ratio - 0.05
+	target := 1.05/minRatio - 0.05
+	k := math.Pow(target/current, 1/2.4)
+	return imaging.AdjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		scale := func(v uint8) uint8 { return uint8(float64(v)*k + 0.5) }
+		return color.NRGBA{R: scale(c.R), G: scale(c.G), B: scale(c.B), A: c.A}
+	})
+}
+
+// outlineSilhouette draws a dark outline of the given width in pixels around
+// the visible parts of img. The image grows by the width on every side.
+func outlineSilhouette(img image.Image, width int) image.Image {
+	if width < 1 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx()+2*width, b.Dy()+2*width
+
+	// Chamfer distance of every pixel to the nearest visible pixel.
+	dist := make([]float64, w*h)
+	for i := range dist {
+		dist[i] = math.Inf(1)
+	}
+	for y := range b.Dy() {
+		for x := range b.Dx() {
+			if _, _, _, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA(); a >= 0x8000 {
+				dist[(y+width)*w+x+width] = 0
+			}
+		}
+	}
+	relax := func(x, y, dx, dy int, cost float64) {
+		if nx, ny := x+dx, y+dy; nx >= 0 && ny >= 0 && nx < w && ny < h {
+			dist[y*w+x] = math.Min(dist[y*w+x], dist[ny*w+nx]+cost)
+		}
+	}
+	for y := range h {
+		for x := range w {
+			relax(x, y, -1, 0, 1)
+			relax(x, y, 0, -1, 1)
+			relax(x, y, -1, -1, math.Sqrt2)
+			relax(x, y, 1, -1, math.Sqrt2)
+		}
+	}
+	for y := h - 1; y >= 0; y-- {
+		for x := w - 1; x >= 0; x-- {
+			relax(x, y, 1, 0, 1)
+			relax(x, y, 0, 1, 1)
+			relax(x, y, 1, 1, math.Sqrt2)
+			relax(x, y, -1, 1, math.Sqrt2)
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i, d := range dist {
+		if a := clamp01(float64(width) - d + 0.5); a > 0 {
+			c := outlineColor
+			c.A = uint8(a * 255)
+			dst.SetNRGBA(i%w, i/w, c)
+		}
+	}
+	draw.Draw(dst, b.Sub(b.Min).Add(image.Pt(width, width)), img, b.Min, draw.Over)
+	return dst
+}
+
+// drawColorShape marks the top left corner of img with a dark shape on a
+// white disc, so players who cannot tell the tints apart can tell the color
+// groups by shape.
+func drawColorShape(img image.Image, shape int) image.Image {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+
+	size := float32(float64(max(b.Dx(), b.Dy())) * colorShapeBadge)
+	half := size / 2
+	disc := vector.NewRasterizer(int(size)+1, int(size)+1)
+	addShape := func(r *vector.Rasterizer, pts [][2]float64, scale float32) {
+		for i, p := range pts {
+			x, y := half+float32(p[0])*scale, half+float32(p[1])*scale
+			if i == 0 {
+				r.MoveTo(x, y)
+			} else {
+				r.LineTo(x, y)
+			}
+		}
+		r.ClosePath()
+	}
+	addShape(disc, regularPolygon(32, 1, 0), half)
+	disc.Draw(dst, disc.Bounds(), image.NewUniform(color.White), image.Point{})
+
+	mark := vector.NewRasterizer(int(size)+1, int(size)+1)
+	addShape(mark, proceduralShapes[shape](), half*0.55)
+	mark.Draw(dst, mark.Bounds(), image.NewUniform(outlineColor), image.Point{})
+	return dst
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// anchorFirst returns the symbols of a card with the one at i moved to the
+// front, where --anchor puts the symbol at the center.
+func anchorFirst(card []string, i int) []string {
+	out := append([]string{card[i]}, card[:i]...)
+	return append(out, card[i+1:]...)
+}
+
+// anchoredLayout puts the first symbol at the center of the card, scale
+// times the size of the others, and arranges the others on one ring or two
+// around it, whichever lets them be larger. Rectangular cards use their
+// inscribed circle. It returns nil if the symbols do not fit.
+func anchoredLayout(rng *rand.Rand, shape cardShape, count int, scale float64) []placement {
+	if count == 0 {
+		return nil
+	}
+	radius := math.Min(shape.Width, shape.Height)/2 - shape.Padding
+	phase := rng.Float64() * 2 * math.Pi
+	box := func(c [2]float64, size float64) placement {
+		return placement{X: shape.Width/2 + c[0] - size/2, Y: shape.Height/2 + c[1] - size/2, Size: size}
+	}
+
+	rings := []func(size float64) [][2]float64{
+		func(size float64) [][2]float64 { return ringCenters(count-1, radius-size/math.Sqrt2, phase) },
+		func(size float64) [][2]float64 { return roundTemplates["rings"](count-1, size, radius, phase) },
+	}
+	var best []placement
+	for _, ring := range rings {
+		// Search downwards like templateLayout; the first fit is the
+		// largest this ring allows.
+		for size := 2 * radius / scale; size >= radius*0.05; size *= templateShrinkBy {
+			placements := []placement{box([2]float64{}, size*scale)}
+			for _, c := range ring(size) {
+				p := box(c, size)
+				if shape.collidesAny(p, placements) {
+					break
+				}
+				placements = append(placements, p)
+			}
+			if len(placements) == count && !slices.ContainsFunc(placements, func(p placement) bool { return !shape.contains(p) }) {
+				if best == nil || placements[0].Size > best[0].Size {
+					best = placements
+				}
+				break
+			}
+		}
+	}
+	return best
+}
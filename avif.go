@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+)
+
+// avifConverters are the command line tools AVIF images are decoded with,
+// in order of preference. There is no pure Go AV1 decoder, so AVIF support
+// needs one of them installed. Each writes the image in to the PNG out.
+var avifConverters = []struct {
+	name string
+	args func(in, out string) []string
+}{
+	{"avifdec", func(in, out string) []string { return []string{in, out} }},
+	{"magick", func(in, out string) []string { return []string{in, out} }},
+	{"ffmpeg", func(in, out string) []string {
+		return []string{"-loglevel", "error", "-y", "-i", in, "-frames:v", "1", out}
+	}},
+}
+
+// avifConvertersAllowed is cleared by the serve command, whose images come
+// from the network and must not reach external programs.
+var avifConvertersAllowed = true
+
+var (
+	// errNoAVIFConverter is returned when decoding AVIF without any of the
+	// avifConverters on the PATH.
+	errNoAVIFConverter = errors.New("decoding AVIF needs avifdec (libavif), ImageMagick or ffmpeg on the PATH")
+	// errNotAVIF is returned for ISO media files, like HEIC or MP4, that
+	// match the registered magic but are no AVIF images.
+	errNotAVIF = errors.New("not an AVIF image")
+)
+
+func init() {
+	// Every ISO media file starts with its ftyp box; isAVIF checks the
+	// brands.
+	image.RegisterFormat("avif", "????ftyp", decodeAVIF, decodeAVIFConfig)
+}
+
+// isoBox is a box of the ISO base media file format AVIF is stored in.
+type isoBox struct {
+	typ  string
+	body []byte
+}
+
+// isoBoxes splits data into the boxes it consists of.
+func isoBoxes(data []byte) ([]isoBox, error) {
+	var boxes []isoBox
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, errors.New("truncated box header")
+		}
+		size, header := uint64(binary.BigEndian.Uint32(data)), uint64(8)
+		switch size {
+		case 0: // up to the end
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, errors.New("truncated box header")
+			}
+			size, header = binary.BigEndian.Uint64(data[8:]), 16
+		}
+		if size < header || size > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid size of box %q", data[4:8])
+		}
+		boxes = append(boxes, isoBox{typ: string(data[4:8]), body: data[header:size]})
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+// findBox returns the body of the first box of the given type.
+func findBox(boxes []isoBox, typ string) ([]byte, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b.body, true
+		}
+	}
+	return nil, false
+}
+
+// isAVIF reports whether data starts with an ftyp box naming avif (still
+// images) or avis (image sequences) as its major or a compatible brand.
+// Files written as generic HEIF, with the major brand mif1 or miaf, list
+// avif among the compatible brands.
+func isAVIF(data []byte) bool {
+	if len(data) < 8 || string(data[4:8]) != "ftyp" {
+		return false
+	}
+	size := int(binary.BigEndian.Uint32(data))
+	if size < 16 || size > len(data) {
+		return false
+	}
+	// Major brand and minor version, then the compatible brands.
+	brands := append(slices.Clone(data[8:12]), data[16:size]...)
+	for i := 0; i+4 <= len(brands); i += 4 {
+		if b := string(brands[i : i+4]); b == "avif" || b == "avis" {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeAVIF converts the AVIF image to PNG with the first converter found
+// and decodes that.
+func decodeAVIF(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !isAVIF(data) {
+		return nil, errNotAVIF
+	}
+	if !avifConvertersAllowed {
+		return nil, errors.New("AVIF images are not supported here, convert them to PNG or WebP")
+	}
+	dir, err := os.MkdirTemp("", "dobble-avif-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	in, out := filepath.Join(dir, "in.avif"), filepath.Join(dir, "out.png")
+	if err := os.WriteFile(in, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	for _, c := range avifConverters {
+		bin, err := exec.LookPath(c.name)
+		if err != nil {
+			continue
+		}
+		if output, err := exec.Command(bin, c.args(in, out)...).CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("%s failed: %w: %s", c.name, err, bytes.TrimSpace(output))
+		}
+		f, err := os.Open(out)
+		if err != nil {
+			return nil, fmt.Errorf("%s wrote no image: %w", c.name, err)
+		}
+		defer f.Close()
+		return png.Decode(f)
+	}
+	return nil, errNoAVIFConverter
+}
+
+// decodeAVIFConfig reads the size of an AVIF image from the image spatial
+// extents property of its primary item, so listing images needs no
+// converter. Without one it falls back to decoding the image.
+func decodeAVIFConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	if !isAVIF(data) {
+		return image.Config{}, errNotAVIF
+	}
+	if w, h, err := avifSize(data); err == nil {
+		return image.Config{ColorModel: color.NRGBAModel, Width: w, Height: h}, nil
+	}
+	img, err := decodeAVIF(bytes.NewReader(data))
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: img.ColorModel(), Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}, nil
+}
+
+// avifSize returns the size recorded in the ispe property associated with
+// the primary item (pitm) in the item property associations (ipma).
+func avifSize(data []byte) (int, int, error) {
+	top, err := isoBoxes(data)
+	if err != nil {
+		return 0, 0, err
+	}
+	meta, ok := findBox(top, "meta")
+	if !ok || len(meta) < 4 {
+		return 0, 0, errors.New("no meta box")
+	}
+	boxes, err := isoBoxes(meta[4:]) // after version and flags
+	if err != nil {
+		return 0, 0, err
+	}
+	pitm, ok := findBox(boxes, "pitm")
+	iprp, ok2 := findBox(boxes, "iprp")
+	if !ok || !ok2 || len(pitm) < 6 {
+		return 0, 0, errors.New("no primary item properties")
+	}
+	primary := uint32(binary.BigEndian.Uint16(pitm[4:]))
+	if pitm[0] != 0 {
+		if len(pitm) < 8 {
+			return 0, 0, errors.New("truncated pitm box")
+		}
+		primary = binary.BigEndian.Uint32(pitm[4:])
+	}
+
+	props, err := isoBoxes(iprp)
+	if err != nil {
+		return 0, 0, err
+	}
+	ipcoBody, ok := findBox(props, "ipco")
+	ipma, ok2 := findBox(props, "ipma")
+	if !ok || !ok2 {
+		return 0, 0, errors.New("no item properties")
+	}
+	ipco, err := isoBoxes(ipcoBody)
+	if err != nil {
+		return 0, 0, err
+	}
+	indices, err := itemProperties(ipma, primary)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, i := range indices {
+		// ispe: version and flags, then width and height.
+		if i >= 1 && i <= len(ipco) && ipco[i-1].typ == "ispe" && len(ipco[i-1].body) >= 12 {
+			b := ipco[i-1].body
+			return int(binary.BigEndian.Uint32(b[4:])), int(binary.BigEndian.Uint32(b[8:])), nil
+		}
+	}
+	return 0, 0, errors.New("no ispe property for the primary item")
+}
+
+// itemProperties returns the 1-based ipco indices of the properties the ipma
+// box associates with the item.
+func itemProperties(ipma []byte, item uint32) ([]int, error) {
+	errTruncated := errors.New("truncated ipma box")
+	if len(ipma) < 8 {
+		return nil, errTruncated
+	}
+	version, wide := ipma[0], ipma[3]&1 == 1
+	count := binary.BigEndian.Uint32(ipma[4:])
+	p := ipma[8:]
+	for range count {
+		var id uint32
+		if version < 1 {
+			if len(p) < 3 {
+				return nil, errTruncated
+			}
+			id, p = uint32(binary.BigEndian.Uint16(p)), p[2:]
+		} else {
+			if len(p) < 5 {
+				return nil, errTruncated
+			}
+			id, p = binary.BigEndian.Uint32(p), p[4:]
+		}
+		n := int(p[0])
+		p = p[1:]
+
+		var indices []int
+		for range n {
+			// The top bit marks essential properties.
+			if wide {
+				if len(p) < 2 {
+					return nil, errTruncated
+				}
+				indices, p = append(indices, int(binary.BigEndian.Uint16(p)&0x7fff)), p[2:]
+			} else {
+				if len(p) < 1 {
+					return nil, errTruncated
+				}
+				indices, p = append(indices, int(p[0]&0x7f)), p[1:]
+			}
+		}
+		if id == item {
+			return indices, nil
+		}
+	}
+	return nil, fmt.Errorf("no properties for item %d", item)
+}
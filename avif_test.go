@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"testing"
+)
+
+// mediaBox encodes an ISO media box.
+func mediaBox(typ string, body ...[]byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(bytes.Join(body, nil))))
+	return append(append(b, typ...), bytes.Join(body, nil)...)
+}
+
+func be16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func be32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+func ftypBox(major string, compatible ...string) []byte {
+	body := append([]byte(major), be32(0)...)
+	for _, c := range compatible {
+		body = append(body, c...)
+	}
+	return mediaBox("ftyp", body)
+}
+
+func TestIsAVIF(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"major avif", ftypBox("avif", "mif1"), true},
+		{"sequence", ftypBox("avis", "msf1"), true},
+		{"generic HEIF", ftypBox("mif1", "miaf", "avif"), true},
+		{"HEIC", ftypBox("heic", "mif1", "heic"), false},
+		{"MP4", ftypBox("isom", "mp41"), false},
+		{"truncated", ftypBox("avif")[:10], false},
+	}
+	for _, tt := range tests {
+		if got := isAVIF(tt.data); got != tt.want {
+			t.Errorf("%s: isAVIF = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeAVIFConfig(t *testing.T) {
+	// The payload comes first and contains "ispe", the thumbnail (item 2)
+	// has its own, smaller ispe listed before the primary item's.
+	payload := mediaBox("mdat", []byte("....ispe\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x01"))
+	ipco := mediaBox("ipco",
+		mediaBox("ispe", be32(0), be32(64), be32(32)),
+		mediaBox("ispe", be32(0), be32(640), be32(480)))
+	ipma := mediaBox("ipma", be32(0), be32(2),
+		be16(2), []byte{1, 0x81},
+		be16(1), []byte{1, 0x82})
+	meta := mediaBox("meta", be32(0),
+		mediaBox("hdlr", be32(0), be32(0), []byte("pict")),
+		mediaBox("pitm", be32(0), be16(1)),
+		mediaBox("iprp", ipco, ipma))
+	data := bytes.Join([][]byte{ftypBox("mif1", "avif"), payload, meta}, nil)
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "avif" || cfg.Width != 640 || cfg.Height != 480 {
+		t.Errorf("DecodeConfig = %s %dx%d, want avif 640x480", format, cfg.Width, cfg.Height)
+	}
+
+	heic := bytes.Join([][]byte{ftypBox("heic", "mif1"), meta}, nil)
+	if _, err := decodeAVIFConfig(bytes.NewReader(heic)); !errors.Is(err, errNotAVIF) {
+		t.Errorf("decodeAVIFConfig of HEIC = %v, want errNotAVIF", err)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/go-pdf/fpdf"
+)
+
+// cardBack configures the reverse side of the cards. Backs are printed on
+// the page following each page of fronts, mirrored so they line up when the
+// sheet is printed double-sided.
+type cardBack struct {
+	Color    color.RGBA
+	Pattern  string // "none", "dots", "stripes" or "grid"
+	Image    string // optional image drawn centered on the back
+	FlipEdge string // "long" or "short", the edge the printer flips on
+}
+
+var backPatterns = map[string]bool{"none": true, "dots": true, "stripes": true, "grid": true}
+
+func (b *cardBack) validate() error {
+	if !backPatterns[b.Pattern] {
+		return fmt.Errorf("unknown back pattern %q (want none, dots, stripes or grid)", b.Pattern)
+	}
+	if b.FlipEdge != "long" && b.FlipEdge != "short" {
+		return fmt.Errorf("unknown duplex flip edge %q (want long or short)", b.FlipEdge)
+	}
+	return nil
+}
+
+// parseHexColor parses colors of the form "#rrggbb" or "rrggbb".
+func parseHexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q: want #rrggbb", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q: %w", s, err)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// mirrorPosition returns where the back of a card at (x, y) has to be placed
+// so that it ends up behind the front after the sheet is flipped.
+func (b *cardBack) mirrorPosition(x, y, w, h, pageWidth, pageHeight float64) (float64, float64) {
+	if b.FlipEdge == "short" {
+		return x, pageHeight - y - h
+	}
+	return pageWidth - x - w, y
+}
+
+// drawBackPage adds a page with the backs of the given cards at their front
+// positions.
+func (r *renderer) drawBackPage(positions []fpdf.PointType, cards []int) error {
+	r.pdf.AddPage()
+	pageWidth, pageHeight := r.pdf.GetPageSize()
+	w, h := r.opts.cardSize()
+
+	for i, pos := range positions {
+		x, y := r.back.mirrorPosition(pos.X, pos.Y, w, h, pageWidth, pageHeight)
+		if err := r.drawBack(x, y, w, h); err != nil {
+			return err
+		}
+		r.drawCropMarks(x, y)
+		if r.opts.cardNumbers == "back" {
+			r.drawCardLabel(x, y, cards[i], true)
+		}
+		if r.opts.qrCodes == "back" {
+			if err := r.drawCardQR(x, y, cards[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// drawBack fills the back of the card at (x, y), extending the background
+// into the bleed area so no white edge shows after cutting.
+func (r *renderer) drawBack(x, y, w, h float64) error {
+	pdf := r.pdf
+	c := r.back.Color
+	pdf.SetFillColor(int(c.R), int(c.G), int(c.B))
+	pdf.SetDrawColor(0, 0, 0)
+
+	bleed := r.opts.bleed
+	r.cardOutline(x, y, -bleed, "F")
+	r.clipCard(x, y, bleed)
+
+	r.drawBackPattern(x-bleed, y-bleed, w+2*bleed, h+2*bleed)
+
+	if r.back.Image != "" {
+		if err := r.drawBackImage(x, y, w, h); err != nil {
+			pdf.ClipEnd()
+			return err
+		}
+	}
+
+	pdf.ClipEnd()
+	r.drawCutLine(x, y)
+
+	return nil
+}
+
+// drawBackPattern overlays the pattern in a lighter tint of the back color.
+func (r *renderer) drawBackPattern(x, y, w, h float64) {
+	const spacing = 4.0
+
+	pdf := r.pdf
+	c := r.back.Color
+	tint := func(v uint8) int { return int(v) + (255-int(v))/3 }
+	pdf.SetDrawColor(tint(c.R), tint(c.G), tint(c.B))
+	pdf.SetFillColor(tint(c.R), tint(c.G), tint(c.B))
+
+	switch r.back.Pattern {
+	case "dots":
+		for py := y + spacing/2; py < y+h; py += spacing {
+			for px := x + spacing/2; px < x+w; px += spacing {
+				pdf.Circle(px, py, 0.6, "F")
+			}
+		}
+	case "stripes":
+		for offset := -h; offset < w; offset += spacing {
+			pdf.Line(x+offset, y+h, x+offset+h, y)
+		}
+	case "grid":
+		for px := x; px < x+w; px += spacing {
+			pdf.Line(px, y, px, y+h)
+		}
+		for py := y; py < y+h; py += spacing {
+			pdf.Line(x, py, x+w, py)
+		}
+	}
+}
+
+func (r *renderer) drawBackImage(x, y, w, h float64) error {
+	img, err := imaging.Open(r.back.Image, imaging.AutoOrientation(true))
+	if err != nil {
+		return fmt.Errorf("failed to open back image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	size := math.Min(w, h) * 0.8
+	scale := math.Min(size/float64(bounds.Dx()), size/float64(bounds.Dy()))
+	imgW, imgH := float64(bounds.Dx())*scale, float64(bounds.Dy())*scale
+
+	if r.opts.printReady {
+		img = flatten(img, r.back.Color)
+	} else {
+		img = matteTransparent(img, r.back.Color)
+	}
+	return r.embedImage(img, x+(w-imgW)/2, y+(h-imgH)/2, imgW, imgH)
+}
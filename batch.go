@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// batchFile describes several decks to generate in one run. Flags use the
+// names of the generate command without dashes; Defaults apply to every job
+// unless the job sets the flag itself.
+//
+//	{
+//	  "defaults": {"page-size": "A4", "symbols": 6},
+//	  "jobs": [
+//	    {"name": "animals", "flags": {"img-dir": "img/animals"}},
+//	    {"name": "fruit", "flags": {"img-dir": "img/fruit", "round": true}}
+//	  ]
+//	}
+type batchFile struct {
+	Defaults map[string]any `json:"defaults"`
+	Jobs     []batchJob     `json:"jobs"`
+}
+
+// batchJob is a single deck of a batch. Its name also names the outputs
+// that the job does not set explicitly.
+type batchJob struct {
+	Name  string         `json:"name"`
+	Flags map[string]any `json:"flags"`
+}
+
+// args turns the job into generate arguments. Lists become repeated flags.
+func (j batchJob) args(defaults map[string]any) ([]string, error) {
+	flags := map[string]any{
+		"output":   j.Name + ".pdf",
+		"manifest": j.Name + ".json",
+		"png-dir":  j.Name + "-png",
+		"svg-dir":  j.Name + "-svg",
+		"tts-dir":  j.Name + "-tts",
+		"html-dir": j.Name + "-html",
+
+		"generated-dir": j.Name + "-generated",
+	}
+	for k, v := range defaults {
+		flags[k] = v
+	}
+	for k, v := range j.Flags {
+		flags[k] = v
+	}
+	if _, ok := flags["interactive"]; ok {
+		return nil, errors.New("interactive jobs are not supported")
+	}
+
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var args []string
+	for _, name := range names {
+		values, ok := flags[name].([]any)
+		if !ok {
+			values = []any{flags[name]}
+		}
+		for _, v := range values {
+			s, err := flagValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("flag %s: %w", name, err)
+			}
+			args = append(args, "--"+name+"="+s)
+		}
+	}
+
+	return args, nil
+}
+
+func flagValue(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+// runBatch implements the batch command. A failing job does not stop the
+// others; all failures are reported at the end.
+func runBatch(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dobble batch <jobs.json>")
+		fs.PrintDefaults()
+	}
+	var logs logConfig
+	logs.register(fs)
+	fs.Parse(args)
+	if err := logs.apply(fs); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one job file")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read job file: %w", err)
+	}
+	var batch batchFile
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return fmt.Errorf("failed to parse job file: %w", err)
+	}
+
+	var errs []error
+	for i, job := range batch.Jobs {
+		if job.Name == "" {
+			job.Name = fmt.Sprintf("deck_%d", i+1)
+		}
+		slog.Info("Starting job", "job", job.Name)
+
+		jobArgs, err := job.args(batch.Defaults)
+		if err == nil {
+			err = runGenerate(jobArgs)
+		}
+		if err != nil {
+			slog.Error("Job failed", "job", job.Name, "error", err)
+			errs = append(errs, fmt.Errorf("job %s: %w", job.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d jobs failed: %w", len(errs), len(batch.Jobs), errors.Join(errs...))
+	}
+	slog.Info("All jobs finished", "jobs", len(batch.Jobs))
+	return nil
+}
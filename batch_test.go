@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBatchContinuesAfterInvalidFlags(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	jobs := filepath.Join(t.TempDir(), "jobs.json")
+	data := `{"jobs": [
+		{"name": "broken", "flags": {"formats": "bogus"}},
+		{"name": "plan", "flags": {"dry-run": true}}
+	]}`
+	if err := os.WriteFile(jobs, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runBatch([]string{"--log-level", "error", jobs})
+	if err == nil {
+		t.Fatal("runBatch succeeded with an invalid job")
+	}
+	if !errors.Is(err, ErrInvalidParameters) {
+		t.Errorf("runBatch error %v is not ErrInvalidParameters", err)
+	}
+	if !strings.Contains(err.Error(), "1 of 2 jobs failed") {
+		t.Errorf("runBatch error %q, want only the broken job to fail", err)
+	}
+}
+
+func TestBatchJobArgsNameOutputs(t *testing.T) {
+	job := batchJob{Name: "fruit", Flags: map[string]any{"png-dir": "pngs"}}
+	args, err := job.args(map[string]any{"formats": "pdf,html"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--html-dir=fruit-html", "--tts-dir=fruit-tts", "--png-dir=pngs", "--formats=pdf,html"} {
+		if !slices.Contains(args, want) {
+			t.Errorf("job args %q lack %s", args, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sync/atomic"
+	"time"
+)
+
+// benchPhase is a step of the symbol pipeline timed with --bench.
+type benchPhase int
+
+const (
+	phaseDecode benchPhase = iota
+	phaseResize
+	phaseRotate
+	phaseEncode
+	phaseEmbed
+	benchPhases
+)
+
+var benchPhaseNames = [benchPhases]string{"decode", "resize", "rotate", "encode", "embed"}
+
+// benchTimes accumulates the time spent in each phase. Phases that run on
+// several goroutines add up, so their total can exceed the wall time.
+type benchTimes struct {
+	enabled atomic.Bool
+	total   [benchPhases]atomic.Int64
+	calls   [benchPhases]atomic.Int64
+}
+
+// bench collects the phase times of the current run.
+var bench benchTimes
+
+// start begins timing a phase and returns the function that ends it. It
+// costs nothing unless --bench is set.
+func (b *benchTimes) start(p benchPhase) func() {
+	if !b.enabled.Load() {
+		return func() {}
+	}
+	t := time.Now()
+	return func() {
+		b.total[p].Add(int64(time.Since(t)))
+		b.calls[p].Add(1)
+	}
+}
+
+// report writes the time spent in each phase and the wall time of the run.
+func (b *benchTimes) report(w io.Writer, wall time.Duration) {
+	fmt.Fprintf(w, "%-8s %10s %8s %10s\n", "Phase", "Total", "Calls", "Average")
+	for p := range benchPhases {
+		total := time.Duration(b.total[p].Load())
+		calls := b.calls[p].Load()
+		avg := time.Duration(0)
+		if calls > 0 {
+			avg = total / time.Duration(calls)
+		}
+		fmt.Fprintf(w, "%-8s %10s %8d %10s\n", benchPhaseNames[p], total.Round(time.Microsecond), calls, avg.Round(time.Microsecond))
+	}
+	fmt.Fprintf(w, "%-8s %10s\n", "wall", wall.Round(time.Millisecond))
+}
+
+// startProfiling writes a CPU profile of the run to dir/cpu.pprof. The
+// returned function stops it and adds a heap profile as dir/heap.pprof.
+func startProfiling(dir string) (func(), error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	cpu, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+	}
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		cpu.Close()
+		return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+
+	return func() {
+		pprof.StopCPUProfile()
+		cpu.Close()
+
+		heap, err := os.Create(filepath.Join(dir, "heap.pprof"))
+		if err != nil {
+			slog.Error("Failed to create heap profile", "error", err)
+			return
+		}
+		defer heap.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(heap); err != nil {
+			slog.Error("Failed to write heap profile", "error", err)
+			return
+		}
+		slog.Info("Profiles written", "dir", dir)
+	}, nil
+}
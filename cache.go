@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/disintegration/imaging"
+	"github.com/go-pdf/fpdf"
+)
+
+// symbolSource is a decoded symbol file: either a raster image or the paths
+// of an SVG.
+type symbolSource struct {
+	img image.Image
+	svg *fpdf.SVGBasicType
+
+	monoOnce sync.Once // guards mono, computed on first use by monochrome
+	mono     bool
+}
+
+// render scales the symbol to fit a square of sizePx pixels with the given
+// filter and rotates it by rotation degrees.
+func (s *symbolSource) render(sizePx, rotation int, filter imaging.ResampleFilter) image.Image {
+	stop := bench.start(phaseResize)
+	var img image.Image
+	if s.svg != nil {
+		img = rasterizeSVG(s.svg, sizePx)
+	} else {
+		img = imaging.Fit(s.img, sizePx, sizePx, filter)
+	}
+	stop()
+
+	defer bench.start(phaseRotate)()
+	return imaging.Rotate(img, float64(rotation), color.Transparent)
+}
+
+// resampleFilterNames lists the values accepted by --resample.
+var resampleFilterNames = []string{"lanczos", "catmullrom", "linear", "box", "nearest"}
+
+var resampleFilters = map[string]imaging.ResampleFilter{
+	"lanczos":    imaging.Lanczos,
+	"catmullrom": imaging.CatmullRom,
+	"linear":     imaging.Linear,
+	"box":        imaging.Box,
+	"nearest":    imaging.NearestNeighbor,
+}
+
+// resampleFilter returns the resampling filter selected with --resample.
+func (o options) resampleFilter() imaging.ResampleFilter {
+	if f, ok := resampleFilters[o.resample]; ok {
+		return f
+	}
+	return imaging.Lanczos
+}
+
+// fitBox returns the size of an image with the given bounds scaled to fit a
+// square of the given edge length.
+func fitBox(bounds image.Rectangle, size float64) (float64, float64) {
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	if w <= 0 || h <= 0 {
+		return size, size
+	}
+	scale := size / math.Max(w, h)
+	return w * scale, h * scale
+}
+
+// rendition is a processed symbol encoded as PNG or JPEG.
+type rendition struct {
+	data      []byte
+	bounds    image.Rectangle
+	imageType string // fpdf image type: "PNG" or "JPEG"
+	name      string // image name in the PDF, derived from the content
+}
+
+func newRendition(data []byte, bounds image.Rectangle, imageType string) rendition {
+	sum := sha256.Sum256(data)
+	return rendition{
+		data:      data,
+		bounds:    bounds,
+		imageType: imageType,
+		name:      "image-" + hex.EncodeToString(sum[:12]),
+	}
+}
+
+// renditionKey identifies a processed symbol. Sizes are in pixels so that
+// symbols whose sizes only differ by a fraction of a pixel share a rendition.
+type renditionKey struct {
+	hash     string
+	sizePx   int
+	rotation int
+	look     SymbolLook
+}
+
+// symbolCache keeps decoded symbol files and their processed renditions, so a
+// symbol used on many cards is only decoded and encoded once per size and
+// rotation. Files are keyed by content hash, which also merges copies of the
+// same image stored under different names.
+type symbolCache struct {
+	hashes     map[string]string
+	sources    map[string]*symbolSource
+	renditions map[renditionKey]rendition
+
+	// limit is the longest side, in pixels, raster sources are reduced to
+	// after decoding; 0 keeps them at full size. Large photos take far more
+	// memory decoded than any rendition needs.
+	limit int
+	// filter resamples sources reduced to limit.
+	filter imaging.ResampleFilter
+	// uses counts the remaining placements of each file once expect has been
+	// called, so decoded sources can be dropped after their last use.
+	uses map[string]int
+}
+
+func newSymbolCache() *symbolCache {
+	return &symbolCache{
+		hashes:     make(map[string]string),
+		sources:    make(map[string]*symbolSource),
+		renditions: make(map[renditionKey]rendition),
+		filter:     imaging.Lanczos,
+	}
+}
+
+// source returns the content hash and decoded contents of the symbol file.
+func (c *symbolCache) source(path string) (string, *symbolSource, error) {
+	if hash, ok := c.hashes[path]; ok {
+		if src, ok := c.sources[hash]; ok {
+			return hash, src, nil
+		}
+	}
+
+	hash, src, err := c.decode(path)
+	if err != nil {
+		return "", nil, err
+	}
+	c.hashes[path] = hash
+	c.sources[hash] = src
+
+	return hash, src, nil
+}
+
+// decode reads and decodes the symbol file without storing it, unless a
+// file with the same content is already cached. It only reads the cache, so
+// several files can be decoded concurrently.
+func (c *symbolCache) decode(path string) (string, *symbolSource, error) {
+	defer bench.start(phaseDecode)()
+
+	data, err := readSymbolFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open image file: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	if src, ok := c.sources[hash]; ok {
+		return hash, src, nil
+	}
+
+	src, err := decodeSymbol(path, data)
+	if err != nil {
+		return "", nil, err
+	}
+	if c.limit > 0 && src.img != nil {
+		if b := src.img.Bounds(); max(b.Dx(), b.Dy()) > c.limit {
+			src.img = imaging.Fit(src.img, c.limit, c.limit, c.filter)
+		}
+	}
+
+	return hash, src, nil
+}
+
+// load decodes the files that are not cached yet on up to jobs goroutines.
+func (c *symbolCache) load(paths []string, jobs int) error {
+	var todo []string
+	for _, p := range paths {
+		if hash, ok := c.hashes[p]; ok && c.sources[hash] != nil {
+			continue
+		}
+		if !slices.Contains(todo, p) {
+			todo = append(todo, p)
+		}
+	}
+
+	hashes := make([]string, len(todo))
+	sources := make([]*symbolSource, len(todo))
+	err := parallel(len(todo), jobs, func(i int) error {
+		var err error
+		hashes[i], sources[i], err = c.decode(todo[i])
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, p := range todo {
+		c.hashes[p] = hashes[i]
+		if _, ok := c.sources[hashes[i]]; !ok {
+			c.sources[hashes[i]] = sources[i]
+		}
+	}
+	return nil
+}
+
+// expect records that the placements of cards will be drawn times times, so
+// done can release sources that are no longer needed.
+func (c *symbolCache) expect(cards []ManifestCard, times int) {
+	c.uses = make(map[string]int)
+	for _, card := range cards {
+		for _, s := range card.Placements {
+			c.uses[s.File] += times
+		}
+	}
+}
+
+// done records that one placement of path has been drawn and drops its
+// decoded source after the last one. Renditions stay cached.
+func (c *symbolCache) done(path string) {
+	if c.uses == nil {
+		return
+	}
+	c.uses[path]--
+	if c.uses[path] > 0 {
+		return
+	}
+
+	hash := c.hashes[path]
+	for p, h := range c.hashes {
+		if h == hash && c.uses[p] > 0 {
+			return
+		}
+	}
+	delete(c.sources, hash)
+}
+
+func decodeSymbol(path string, data []byte) (*symbolSource, error) {
+	if isSVG(path) {
+		sig, err := fpdf.SVGBasicParse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid SVG: %w", ErrImageDecode, err)
+		}
+		return &symbolSource{svg: &sig}, nil
+	}
+
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrImageDecode, err)
+	}
+	// Phone cameras store photos as shot and record in EXIF how to turn
+	// them upright, so JPEG symbols are rotated accordingly.
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(format == "jpeg"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrImageDecode, err)
+	}
+
+	if format == "jpeg" {
+		img = flatten(img, color.White)
+	} else {
+		img = trimTransparent(img)
+	}
+
+	return &symbolSource{img: img}, nil
+}
+
+// trimAlpha is the opacity below which pixels count as padding, so faint
+// halos left by image editors do not keep a margin alive.
+const trimAlpha = 0x0800
+
+// trimTransparent crops img to the bounding box of its visible pixels, so
+// symbols with large transparent margins fill their placement like others.
+// Fully transparent images are returned unchanged.
+func trimTransparent(img image.Image) image.Image {
+	b := img.Bounds()
+	box := image.Rectangle{Min: b.Max, Max: b.Min}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a < trimAlpha {
+				continue
+			}
+			box.Min.X = min(box.Min.X, x)
+			box.Min.Y = min(box.Min.Y, y)
+			box.Max.X = max(box.Max.X, x+1)
+			box.Max.Y = max(box.Max.Y, y+1)
+		}
+	}
+
+	if box.Empty() || box == b {
+		return img
+	}
+	return imaging.Crop(img, box)
+}
+
+// parallel calls fn for every index below n on up to jobs goroutines and
+// returns the errors joined.
+func parallel(n, jobs int, fn func(i int) error) error {
+	errs := make([]error, n)
+	sem := make(chan struct{}, max(jobs, 1))
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(i)
+			<-sem
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// cancelOnSignal returns a context that is cancelled on SIGINT or SIGTERM.
+// Exports then stop after the card at hand, remove their temporary files
+// and report how far they got. A second signal quits immediately.
+func cancelOnSignal() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, func() {
+		stop()
+		slog.Warn("Interrupted, stopping after the current card; interrupt again to quit immediately")
+	})
+	return ctx, stop
+}
+
+// context returns the context of the run, which never ends for runs that
+// are not cancellable, such as the server's.
+func (o options) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	cardLabelSize  = 5.0 // font size of card numbers in pt
+	cardLabelInset = 1.5 // distance of the label from the card edge in mm
+)
+
+// cardLabelSides lists the values accepted by --card-numbers.
+var cardLabelSides = []string{"none", "front", "back"}
+
+// defaultDeckID derives a short identifier from the seed, so every printed
+// deck can be told apart without naming it.
+func defaultDeckID(seed int64) string {
+	id := strconv.FormatUint(uint64(seed)%(36*36*36*36*36*36), 36)
+	return strings.ToUpper(fmt.Sprintf("%06s", id))
+}
+
+// validateDeckID checks that id can be printed with the PDF core fonts.
+func validateDeckID(id string) error {
+	if len(id) > 16 {
+		return fmt.Errorf("deck ID must be at most 16 characters")
+	}
+	for _, r := range id {
+		if r < ' ' || r > '~' {
+			return fmt.Errorf("deck ID must be plain ASCII")
+		}
+	}
+	return nil
+}
+
+// cardLabel returns the text identifying a card within its deck.
+func cardLabel(index int, deckID string) string {
+	return strings.TrimSpace(fmt.Sprintf("#%d %s", index+1, deckID))
+}
+
+// drawCardLabel prints the label of the card at (x, y) in small type near
+// the bottom edge: in the corner of rectangular cards, at the bottom of
+// round cards and at the foot of the right edge of hexagonal ones. Labels
+// are grey on fronts and white on the colored backs.
+func (r *renderer) drawCardLabel(x, y float64, index int, back bool) {
+	pdf := r.pdf
+	label := cardLabel(index, r.opts.deckID)
+	pdf.SetFont("Helvetica", "", cardLabelSize)
+	if back {
+		pdf.SetTextColor(255, 255, 255)
+	} else {
+		pdf.SetTextColor(110, 110, 110)
+	}
+
+	w, h := r.opts.cardSize()
+	tw := pdf.GetStringWidth(label)
+	switch {
+	case r.opts.roundCards:
+		pdf.Text(x+(w-tw)/2, y+h-2*cardLabelInset, label)
+	case r.opts.hexCards:
+		pdf.Text(x+w-cardLabelInset-tw, y+3*h/4-cardLabelInset, label)
+	default:
+		inset := cardLabelInset + r.opts.cornerRadius*0.3
+		pdf.Text(x+w-inset-tw, y+h-inset, label)
+	}
+
+	pdf.SetTextColor(0, 0, 0)
+}
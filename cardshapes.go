@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// defaultCornerRadius is the corner radius of rounded cards in mm, matching
+// common playing cards.
+const defaultCornerRadius = 3.0
+
+// cardShapeNames lists the values accepted by --shape.
+var cardShapeNames = []string{"rect", "rounded", "round", "hex"}
+
+// applyShape sets the card outline selected with --shape. Hexagonal cards
+// are pointy-topped and fill the card box; unless a height was given, the
+// box is made regular.
+func (o *options) applyShape(name string, heightSet bool) error {
+	switch name {
+	case "":
+	case "rect":
+		o.roundCards, o.cornerRadius = false, 0
+	case "rounded":
+		o.roundCards = false
+		if o.cornerRadius == 0 {
+			o.cornerRadius = defaultCornerRadius
+		}
+	case "round":
+		o.roundCards = true
+	case "hex":
+		o.roundCards, o.hexCards = false, true
+		if !heightSet {
+			o.cardHeight = math.Floor(o.cardWidth*2/math.Sqrt(3)*10) / 10
+		}
+	default:
+		return fmt.Errorf("unknown card shape %q", name)
+	}
+
+	w, h := o.cardSize()
+	if o.cornerRadius < 0 || o.cornerRadius > math.Min(w, h)/2 {
+		return fmt.Errorf("corner radius must be between 0 and half the card width")
+	}
+	return nil
+}
+
+// layoutShape returns the outline symbols are laid out in, keeping clear of
+// QR codes printed on the front.
+func (o options) layoutShape() cardShape {
+	w, h := o.cardSize()
+	shape := cardShape{
+		Round:   o.roundCards,
+		Hex:     o.hexCards,
+		Corner:  o.cornerRadius,
+		Width:   w,
+		Height:  h,
+		Padding: o.padding,
+		Spacing: o.spacing,
+		Overlap: o.overlap / 100,
+	}
+	if o.qrCodes == "front" {
+		shape.Keepout = o.qrBox()
+	}
+	return shape
+}
+
+// distance returns the signed distance of a point from the card edge:
+// negative inside the card, positive outside. Inside the card it is exact,
+// so a point keeps Padding from the edge when distance <= -Padding.
+func (c cardShape) distance(x, y float64) float64 {
+	switch {
+	case c.Round:
+		r := math.Min(c.Width, c.Height) / 2
+		return math.Hypot(x-r, y-r) - r
+	case c.Hex:
+		return hexDistance(x, y, c.Width, c.Height)
+	default:
+		return roundedRectDistance(x, y, c.Width, c.Height, c.Corner)
+	}
+}
+
+// roundedRectDistance is the signed distance from the edge of a w×h
+// rectangle at the origin with corners rounded by radius r.
+func roundedRectDistance(x, y, w, h, r float64) float64 {
+	qx := math.Abs(x-w/2) - w/2 + r
+	qy := math.Abs(y-h/2) - h/2 + r
+	outside := math.Hypot(math.Max(qx, 0), math.Max(qy, 0))
+	inside := math.Min(math.Max(qx, qy), 0)
+	return outside + inside - r
+}
+
+// hexDistance is the signed distance from the edge of the pointy-top
+// hexagon filling a w×h box at the origin. Outside the hexagon it is only
+// approximate, which is enough for anti-aliasing.
+func hexDistance(x, y, w, h float64) float64 {
+	halfW, halfH := w/2, h/2
+	dx, dy := math.Abs(x-halfW), math.Abs(y-halfH)
+	k := halfH / (2 * halfW) // slope of the slanted edges
+	return math.Max(dx-halfW, (k*dx+dy-halfH)/math.Hypot(1, k))
+}
+
+// hexPoints returns the corners of the pointy-top hexagon filling the w×h
+// box at (x, y), clockwise from the top.
+func hexPoints(x, y, w, h float64) []fpdf.PointType {
+	return []fpdf.PointType{
+		{X: x + w/2, Y: y},
+		{X: x + w, Y: y + h/4},
+		{X: x + w, Y: y + 3*h/4},
+		{X: x + w/2, Y: y + h},
+		{X: x, Y: y + 3*h/4},
+		{X: x, Y: y + h/4},
+	}
+}
+
+// cardOutline draws the outline of the card at (x, y) with the given fpdf
+// style, moved inwards by inset (outwards if negative).
+func (r *renderer) cardOutline(x, y, inset float64, style string) {
+	w, h := r.opts.cardSize()
+	x, y, w, h = x+inset, y+inset, w-2*inset, h-2*inset
+
+	switch {
+	case r.opts.roundCards:
+		r.pdf.Circle(x+w/2, y+h/2, w/2, style)
+	case r.opts.hexCards:
+		r.pdf.Polygon(hexPoints(x, y, w, h), style)
+	case r.opts.cornerRadius > 0:
+		r.pdf.RoundedRect(x, y, w, h, math.Max(r.opts.cornerRadius-inset, 0), "1234", style)
+	default:
+		r.pdf.Rect(x, y, w, h, style)
+	}
+}
+
+// clipCard restricts drawing to the card at (x, y), grown by outset, until
+// the next ClipEnd.
+func (r *renderer) clipCard(x, y, outset float64) {
+	w, h := r.opts.cardSize()
+	x, y, w, h = x-outset, y-outset, w+2*outset, h+2*outset
+
+	switch {
+	case r.opts.roundCards:
+		r.pdf.ClipCircle(x+w/2, y+h/2, w/2, false)
+	case r.opts.hexCards:
+		r.pdf.ClipPolygon(hexPoints(x, y, w, h), false)
+	case r.opts.cornerRadius > 0:
+		r.pdf.ClipRoundedRect(x, y, w, h, r.opts.cornerRadius+outset, false)
+	default:
+		r.pdf.ClipRect(x, y, w, h, false)
+	}
+}
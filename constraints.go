@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// constraintsFileName is the sidecar file in the image directory that is
+// used when --constraints is not given.
+const constraintsFileName = "constraints.json"
+
+// symbolConstraint fixes the rotation, scale or color of the symbols matching
+// Pattern, a glob matched against the file name, its path relative to the
+// image directory and its full path. Fields left out stay randomized.
+type symbolConstraint struct {
+	Pattern  string   `json:"pattern"`
+	Rotation *int     `json:"rotation,omitempty"` // degrees, a multiple of 90
+	Scale    *float64 `json:"scale,omitempty"`    // size relative to the box reserved by the layout
+	Color    string   `json:"color,omitempty"`    // #rrggbb tint of monochrome symbols
+}
+
+// symbolConstraints are the rules of a constraints file. For each field the
+// first matching rule that sets it wins.
+type symbolConstraints struct {
+	dir   string // image directory patterns may be relative to
+	rules []symbolConstraint
+}
+
+// loadConstraints reads a JSON array of rules, e.g.
+//
+//	[{"pattern": "words/*", "rotation": 0}, {"pattern": "logo.png", "rotation": 0, "scale": 1},
+//	 {"pattern": "sun.svg", "color": "#f5a623"}]
+func loadConstraints(file, imgDir string) (symbolConstraints, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return symbolConstraints{}, fmt.Errorf("failed to read constraints: %w", err)
+	}
+	c := symbolConstraints{dir: imgDir}
+	if err := json.Unmarshal(data, &c.rules); err != nil {
+		return symbolConstraints{}, fmt.Errorf("failed to parse constraints %s: %w", file, err)
+	}
+
+	for i, r := range c.rules {
+		var err error
+		switch {
+		case r.Pattern == "":
+			err = errors.New("missing pattern")
+		case r.Rotation != nil && *r.Rotation%90 != 0:
+			err = fmt.Errorf("rotation %d is not a multiple of 90", *r.Rotation)
+		case r.Scale != nil && (*r.Scale <= 0 || *r.Scale > 1):
+			err = fmt.Errorf("scale %g is not between 0 and 1", *r.Scale)
+		case r.Color != "":
+			if _, err = parseHexColor(r.Color); err == nil {
+				err = checkPattern(r.Pattern)
+			}
+		default:
+			err = checkPattern(r.Pattern)
+		}
+		if err != nil {
+			return symbolConstraints{}, fmt.Errorf("constraint %d in %s: %w", i+1, file, err)
+		}
+	}
+	return c, nil
+}
+
+// match reports whether the rule applies to the symbol file.
+func (c symbolConstraints) match(r symbolConstraint, file string) bool {
+	names := []string{filepath.Base(file), filepath.ToSlash(file)}
+	if rel, err := filepath.Rel(c.dir, file); err == nil {
+		names = append(names, filepath.ToSlash(rel))
+	}
+	for _, name := range names {
+		if ok, _ := path.Match(r.Pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// apply replaces the random rotation and scale of a symbol with the ones
+// fixed for it.
+func (c symbolConstraints) apply(file string, rotation int, scale float64) (int, float64) {
+	var rotationSet, scaleSet bool
+	for _, r := range c.rules {
+		if !c.match(r, file) {
+			continue
+		}
+		if r.Rotation != nil && !rotationSet {
+			rotation, rotationSet = ((*r.Rotation%360)+360)%360, true
+		}
+		if r.Scale != nil && !scaleSet {
+			scale, scaleSet = *r.Scale, true
+		}
+	}
+	return rotation, scale
+}
+
+// tint returns the color fixed for the symbol, or fallback.
+func (c symbolConstraints) tint(file, fallback string) string {
+	for _, r := range c.rules {
+		if r.Color != "" && c.match(r, file) {
+			return r.Color
+		}
+	}
+	return fallback
+}
+
+// readConstraints loads the file given with --constraints, or the sidecar
+// file in the image directory if there is one.
+func (o *options) readConstraints() error {
+	file := o.constraintsFile
+	if file == "" {
+		sidecar := filepath.Join(o.imgDir, constraintsFileName)
+		if _, err := os.Stat(sidecar); err != nil {
+			return nil
+		}
+		file = sidecar
+	}
+
+	c, err := loadConstraints(file, o.imgDir)
+	if err != nil {
+		return err
+	}
+	o.constraints = c
+	slog.Info("Symbol constraints loaded", "path", file, "rules", len(c.rules))
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// rulesText explains the basic game and a few popular variants.
+var rulesText = []struct{ title, body string }{
+	{"How to play", "Every pair of cards has exactly one symbol in common. Symbols may differ in size and rotation, but they are always the same picture. Spot the match, name it out loud and the card is yours."},
+	{"The Tower", "Deal one card face down to every player and place the rest face up in a draw pile. Players turn their cards over at the same time. The first player to name the symbol shared by their own top card and the draw pile takes the top card of the pile and puts it on their stack. The player with the most cards when the pile runs out wins."},
+	{"The Well", "Place one card face up in the middle and deal all other cards evenly face down. Players turn over their stacks and try to get rid of their cards by finding the symbol shared with the middle card. Whoever names it first puts their card on top of the middle pile. The first player without cards wins."},
+	{"Hot Potato", "Deal one card to every player, held face down. Everyone reveals their card at the same time. The first player to spot a symbol shared with another player's card names it and gives their card to that player. The player left holding all the cards loses the round."},
+	{"Tips", "Call symbols by whatever name everyone agreed on before the game. When two players name the match at the same time, the card goes to neither and is put aside."},
+}
+
+// drawIntroPages prepends the requested cover and rules pages. With card
+// backs an odd number of intro pages is padded with a blank page, so fronts
+// and backs still end up on the two sides of one sheet in duplex printing.
+func (r *renderer) drawIntroPages(m *Manifest) {
+	pages := 0
+	if r.opts.cover {
+		r.drawCoverPage(m)
+		pages++
+	}
+	if r.opts.rules {
+		r.drawRulesPage()
+		pages++
+	}
+	if r.back != nil && pages%2 == 1 {
+		r.pdf.AddPage()
+	}
+}
+
+// drawCoverPage adds a title page with the deck name and its numbers.
+func (r *renderer) drawCoverPage(m *Manifest) {
+	pdf := r.pdf
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.AddPage()
+	_, pageHeight := pdf.GetPageSize()
+
+	symbols := make(map[string]bool)
+	for _, card := range m.Cards {
+		for _, s := range card.Symbols {
+			symbols[s] = true
+		}
+	}
+
+	pdf.SetY(pageHeight / 3)
+	pdf.SetFont("Helvetica", "B", 36)
+	pdf.CellFormat(0, 16, tr(r.opts.deckName), "", 1, "C", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 14)
+	pdf.Ln(8)
+	pdf.CellFormat(0, 8, fmt.Sprintf("%d cards with %d symbols each", len(m.Cards), m.SymbolsPerCard), "", 1, "C", false, 0, "")
+	pdf.CellFormat(0, 8, fmt.Sprintf("%d different symbols", len(symbols)), "", 1, "C", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(110, 110, 110)
+	pdf.SetY(pageHeight - 30)
+	footer := time.Now().Format("2 January 2006")
+	if m.DeckID != "" {
+		footer += "  -  Deck " + m.DeckID
+	}
+	pdf.CellFormat(0, 6, footer, "", 1, "C", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// drawRulesPage adds a page explaining how to play.
+func (r *renderer) drawRulesPage() {
+	pdf := r.pdf
+	pdf.AddPage()
+	pdf.SetY(20)
+
+	for i, section := range rulesText {
+		if i == 0 {
+			pdf.SetFont("Helvetica", "B", 20)
+			pdf.CellFormat(0, 10, section.title, "", 1, "L", false, 0, "")
+		} else {
+			pdf.SetFont("Helvetica", "B", 13)
+			pdf.CellFormat(0, 8, section.title, "", 1, "L", false, 0, "")
+		}
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.MultiCell(0, 5.5, section.body, "", "L", false)
+		pdf.Ln(5)
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// deckDiff describes how a deck changed between two manifests. Cards are
+// matched by their index, symbols by file, except for renamed symbols,
+// which sit on the same cards under a new file.
+type deckDiff struct {
+	Params         []paramChange     `json:"params,omitempty"`
+	RenamedSymbols map[string]string `json:"renamedSymbols,omitempty"` // old file to new file
+	AddedSymbols   []string          `json:"addedSymbols,omitempty"`
+	RemovedSymbols []string          `json:"removedSymbols,omitempty"`
+	Cards          []cardDiff        `json:"cards,omitempty"`
+	AddedCards     int               `json:"addedCards,omitempty"`
+	RemovedCards   int               `json:"removedCards,omitempty"`
+}
+
+// paramChange is a deck parameter that differs between the manifests.
+type paramChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// cardDiff lists the changes to a card present in both manifests.
+type cardDiff struct {
+	Card          int      `json:"card"` // 1-based, as printed
+	Added         []string `json:"added,omitempty"`
+	Removed       []string `json:"removed,omitempty"`
+	LayoutChanged bool     `json:"layoutChanged,omitempty"`
+}
+
+// empty reports whether the manifests describe the same deck.
+func (d deckDiff) empty() bool {
+	return len(d.Params) == 0 && len(d.RenamedSymbols) == 0 && len(d.AddedSymbols) == 0 &&
+		len(d.RemovedSymbols) == 0 && len(d.Cards) == 0 && d.AddedCards == 0 && d.RemovedCards == 0
+}
+
+// diffManifests compares the deck of a with its later generation b.
+func diffManifests(a, b *Manifest) deckDiff {
+	var d deckDiff
+	param := func(name string, old, new any) {
+		if o, n := fmt.Sprint(old), fmt.Sprint(new); o != n {
+			d.Params = append(d.Params, paramChange{Name: name, Old: o, New: n})
+		}
+	}
+	param("seed", a.Seed, b.Seed)
+	param("deckId", a.DeckID, b.DeckID)
+	param("symbolsPerCard", a.SymbolsPerCard, b.SymbolsPerCard)
+	param("lambda", max(a.Lambda, 1), max(b.Lambda, 1))
+	param("logo", a.Logo, b.Logo)
+	param("roundCards", a.RoundCards, b.RoundCards)
+	param("hexCards", a.HexCards, b.HexCards)
+	param("cardSize", fmt.Sprintf("%gx%g mm", a.CardWidth, a.CardHeight), fmt.Sprintf("%gx%g mm", b.CardWidth, b.CardHeight))
+	param("bleed", a.Bleed, b.Bleed)
+
+	renames := symbolRenames(a, b)
+	if len(renames) > 0 {
+		d.RenamedSymbols = renames
+	}
+	a = renameSymbols(a, renames, nil)
+
+	old, new := deckSymbolSet(a), deckSymbolSet(b)
+	for _, s := range sortedKeys(new) {
+		if !old[s] {
+			d.AddedSymbols = append(d.AddedSymbols, s)
+		}
+	}
+	for _, s := range sortedKeys(old) {
+		if !new[s] {
+			d.RemovedSymbols = append(d.RemovedSymbols, s)
+		}
+	}
+
+	for i := range min(len(a.Cards), len(b.Cards)) {
+		ca, cb := a.Cards[i], b.Cards[i]
+		c := cardDiff{Card: i + 1}
+		for _, s := range cb.Symbols {
+			if !slices.Contains(ca.Symbols, s) {
+				c.Added = append(c.Added, s)
+			}
+		}
+		for _, s := range ca.Symbols {
+			if !slices.Contains(cb.Symbols, s) {
+				c.Removed = append(c.Removed, s)
+			}
+		}
+		c.LayoutChanged = len(c.Added) == 0 && len(c.Removed) == 0 && !slices.Equal(ca.Placements, cb.Placements)
+		if len(c.Added) > 0 || len(c.Removed) > 0 || c.LayoutChanged {
+			d.Cards = append(d.Cards, c)
+		}
+	}
+	d.AddedCards = max(len(b.Cards)-len(a.Cards), 0)
+	d.RemovedCards = max(len(a.Cards)-len(b.Cards), 0)
+	return d
+}
+
+// deckSymbolSet returns the files of the symbols of a deck.
+func deckSymbolSet(m *Manifest) map[string]bool {
+	set := make(map[string]bool)
+	for _, card := range m.Cards {
+		for _, s := range card.Symbols {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// symbolRenames finds the symbols of a that are on exactly the same cards
+// in b under another file which a does not use. In a full deck no two
+// symbols share more than one card, so the cards identify a symbol; symbols
+// whose cards are ambiguous are left out.
+func symbolRenames(a, b *Manifest) map[string]string {
+	n := min(len(a.Cards), len(b.Cards))
+	cardsOf := func(m *Manifest) map[string]string {
+		cards := make(map[string][]string)
+		for _, card := range m.Cards[:n] {
+			for _, s := range card.Symbols {
+				cards[s] = append(cards[s], strconv.Itoa(card.Index))
+			}
+		}
+		keys := make(map[string]string, len(cards))
+		for s, c := range cards {
+			keys[s] = strings.Join(c, ",")
+		}
+		return keys
+	}
+	bySet := func(keys map[string]string) map[string][]string {
+		symbols := make(map[string][]string)
+		for s, k := range keys {
+			symbols[k] = append(symbols[k], s)
+		}
+		return symbols
+	}
+
+	aKeys, bKeys := cardsOf(a), cardsOf(b)
+	aSets, bSets := bySet(aKeys), bySet(bKeys)
+	renames := make(map[string]string)
+	for s, k := range aKeys {
+		if _, kept := bKeys[s]; kept || len(aSets[k]) != 1 || len(bSets[k]) != 1 {
+			continue
+		}
+		if t := bSets[k][0]; aKeys[t] == "" {
+			renames[s] = t
+		}
+	}
+	return renames
+}
+
+// renameSymbols returns a copy of m with its symbols renamed. Display names
+// are taken from names if they have one there, or else kept.
+func renameSymbols(m *Manifest, renames map[string]string, names map[string]string) *Manifest {
+	out := *m
+	rename := func(s string) string {
+		if t, ok := renames[s]; ok {
+			return t
+		}
+		return s
+	}
+
+	out.Cards = make([]ManifestCard, len(m.Cards))
+	for i, card := range m.Cards {
+		card.Symbols = slices.Clone(card.Symbols)
+		for j := range card.Symbols {
+			card.Symbols[j] = rename(card.Symbols[j])
+		}
+		card.Placements = slices.Clone(card.Placements)
+		for j := range card.Placements {
+			card.Placements[j].File = rename(card.Placements[j].File)
+		}
+		out.Cards[i] = card
+	}
+	out.Logo = rename(m.Logo)
+
+	if m.SymbolNames != nil {
+		out.SymbolNames = make(map[string]string, len(m.SymbolNames))
+		for s, name := range m.SymbolNames {
+			out.SymbolNames[rename(s)] = name
+		}
+	}
+	for _, new := range renames {
+		if name, ok := names[new]; ok {
+			if out.SymbolNames == nil {
+				out.SymbolNames = make(map[string]string)
+			}
+			out.SymbolNames[new] = name
+		}
+	}
+	if m.SymbolCodes != nil {
+		out.SymbolCodes = make(map[string]int, len(m.SymbolCodes))
+		for s, code := range m.SymbolCodes {
+			out.SymbolCodes[rename(s)] = code
+		}
+	}
+	return &out
+}
+
+// print writes the differences in a human-readable form.
+func (d deckDiff) print(w io.Writer) {
+	if d.empty() {
+		fmt.Fprintln(w, "No differences.")
+		return
+	}
+	for _, p := range d.Params {
+		fmt.Fprintf(w, "%s: %s -> %s\n", p.Name, p.Old, p.New)
+	}
+	for _, old := range sortedKeys(d.RenamedSymbols) {
+		fmt.Fprintf(w, "renamed: %s -> %s\n", old, d.RenamedSymbols[old])
+	}
+	for _, s := range d.AddedSymbols {
+		fmt.Fprintf(w, "added symbol: %s\n", s)
+	}
+	for _, s := range d.RemovedSymbols {
+		fmt.Fprintf(w, "removed symbol: %s\n", s)
+	}
+	for _, c := range d.Cards {
+		var changes []string
+		for _, s := range c.Added {
+			changes = append(changes, "+"+s)
+		}
+		for _, s := range c.Removed {
+			changes = append(changes, "-"+s)
+		}
+		if c.LayoutChanged {
+			changes = append(changes, "layout changed")
+		}
+		fmt.Fprintf(w, "card %d: %s\n", c.Card, strings.Join(changes, " "))
+	}
+	if d.AddedCards > 0 {
+		fmt.Fprintf(w, "%d cards added\n", d.AddedCards)
+	}
+	if d.RemovedCards > 0 {
+		fmt.Fprintf(w, "%d cards removed\n", d.RemovedCards)
+	}
+}
+
+// runDiff implements the diff command, which shows how a deck changed
+// between two generations.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dobble diff [flags] <old.json> <new.json>")
+		fs.PrintDefaults()
+	}
+	asJSON := fs.Bool("json", false, "print the differences as JSON")
+	var logs logConfig
+	logs.register(fs)
+	fs.Parse(args)
+	if err := logs.apply(fs); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected two manifest files")
+	}
+	a, err := loadManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := loadManifest(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	d := diffManifests(a, b)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	}
+	d.print(os.Stdout)
+	return nil
+}
+
+// runMerge implements the merge command. It applies symbol renames to a
+// saved deck and keeps its layouts, so renamed image files or new display
+// names do not require a new deck. Renames are detected from a later
+// generation of the deck or given with --rename.
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dobble merge [flags] <base.json> [renamed.json]")
+		fs.PrintDefaults()
+	}
+	output := fs.String("output", "", "path of the merged manifest (default: overwrite the base manifest)")
+	renames := make(map[string]string)
+	fs.Func("rename", "rename a symbol, as old=new; may be repeated", func(v string) error {
+		old, new, ok := strings.Cut(v, "=")
+		if !ok || old == "" || new == "" {
+			return fmt.Errorf("expected old=new, got %q", v)
+		}
+		renames[old] = new
+		return nil
+	})
+	var logs logConfig
+	logs.register(fs)
+	fs.Parse(args)
+	if err := logs.apply(fs); err != nil {
+		return err
+	}
+
+	if fs.NArg() < 1 || fs.NArg() > 2 || fs.NArg() == 1 && len(renames) == 0 {
+		fs.Usage()
+		return fmt.Errorf("expected a base manifest and a renamed manifest or --rename")
+	}
+	base, err := loadManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	symbols := deckSymbolSet(base)
+	for old := range renames {
+		if !symbols[old] {
+			return fmt.Errorf("symbol %q is not in the deck", old)
+		}
+	}
+
+	var names map[string]string
+	if fs.NArg() == 2 {
+		other, err := loadManifest(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		for old, new := range symbolRenames(base, other) {
+			if _, given := renames[old]; !given {
+				renames[old] = new
+			}
+		}
+		names = other.SymbolNames
+	}
+
+	// Renames apply at once, so a symbol may take the name of another one
+	// only if that one is renamed too.
+	for _, old := range sortedKeys(renames) {
+		new := renames[old]
+		if _, moved := renames[new]; symbols[new] && !moved {
+			return fmt.Errorf("cannot rename %q to %q: the deck already has that symbol", old, new)
+		}
+		slog.Info("Renaming symbol", "old", old, "new", new)
+	}
+	merged := renameSymbols(base, renames, names)
+	if violations := validateDeck(merged.symbolCards(), merged.Lambda); len(violations) > 0 {
+		return fmt.Errorf("merged deck is not a valid Dobble deck: %s", violations[0])
+	}
+
+	path := *output
+	if path == "" {
+		path = fs.Arg(0)
+	}
+	if err := merged.save(path); err != nil {
+		return err
+	}
+	slog.Info("Merged manifest written", "path", path, "renamed", len(renames))
+	return nil
+}
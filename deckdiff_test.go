@@ -0,0 +1,35 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMergeRenames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deck.json")
+	m := &Manifest{SymbolsPerCard: 3}
+	for i, card := range [][]string{
+		{"a", "b", "c"}, {"a", "d", "e"}, {"a", "f", "g"},
+		{"b", "d", "f"}, {"b", "e", "g"}, {"c", "d", "g"}, {"c", "e", "f"},
+	} {
+		m.Cards = append(m.Cards, ManifestCard{Index: i, Symbols: card})
+	}
+	if err := m.save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runMerge([]string{"--rename", "a=b", path}); err == nil {
+		t.Error("merge renamed a symbol to one already in the deck")
+	}
+	if err := runMerge([]string{"--rename", "a=b", "--rename", "b=a", "--rename", "c=z", path}); err != nil {
+		t.Fatal(err)
+	}
+	merged, err := loadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := merged.Cards[0].Symbols; !slices.Equal(got, []string{"b", "a", "z"}) {
+		t.Errorf("first card after merge = %q, want [b a z]", got)
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// maxDesignSymbols bounds the symbols per card of the designs offered for
+// --lambda, like the valid values listed for classic decks.
+const maxDesignSymbols = 32
+
+// blockDesign is a symmetric (v, k, λ) design: v cards of k symbols each,
+// drawn from v symbols, where every two cards share exactly λ symbols.
+// Classic decks are the designs with λ = 1.
+type blockDesign struct {
+	name   string
+	v      int
+	k      int
+	lambda int
+	build  func() [][]int // cards as 1-based symbol indices
+}
+
+// lambdaDesigns lists the designs this generator can construct for λ, by
+// symbols per card. They come from a few infinite families plus the
+// biplane on 16 points.
+func lambdaDesigns(lambda int) []blockDesign {
+	var designs []blockDesign
+	add := func(d blockDesign) {
+		if d.k <= maxDesignSymbols && !slices.ContainsFunc(designs, func(e blockDesign) bool { return e.k == d.k }) {
+			designs = append(designs, d)
+		}
+	}
+
+	// The planes of PG(3, q) meet in lines of q+1 points.
+	if q := lambda - 1; isPrimePower(q) {
+		add(blockDesign{
+			name: fmt.Sprintf("planes of PG(3, %d)", q),
+			v:    q*q*q + q*q + q + 1, k: q*q + q + 1, lambda: lambda,
+			build: func() [][]int { return projectiveHyperplanes(q, 3) },
+		})
+	}
+	// The complements of the lines of PG(2, q) share q²-q points.
+	for q := 2; q*q-q <= lambda; q++ {
+		if q*q-q == lambda && isPrimePower(q) {
+			add(blockDesign{
+				name: fmt.Sprintf("complement of PG(2, %d)", q),
+				v:    q*q + q + 1, k: q * q, lambda: lambda,
+				build: func() [][]int { return complementDesign(projectiveHyperplanes(q, 2), q*q+q+1) },
+			})
+		}
+	}
+	// Quadratic residues modulo a prime p ≡ 3 (mod 4) form a difference
+	// set with λ = (p-3)/4, their complement one with λ = (p+1)/4.
+	if p := 4*lambda + 3; isPrime(p) {
+		add(blockDesign{
+			name: fmt.Sprintf("quadratic residues mod %d", p),
+			v:    p, k: (p - 1) / 2, lambda: lambda,
+			build: func() [][]int { return developDifferenceSet([]int{p}, powerResidues(p, 2)) },
+		})
+	}
+	if p := 4*lambda - 1; isPrime(p) {
+		add(blockDesign{
+			name: fmt.Sprintf("quadratic non-residues and zero mod %d", p),
+			v:    p, k: (p + 1) / 2, lambda: lambda,
+			build: func() [][]int {
+				return complementDesign(developDifferenceSet([]int{p}, powerResidues(p, 2)), p)
+			},
+		})
+	}
+	// Fourth powers modulo a prime p = 4x²+1 with odd x form a difference
+	// set with λ = (p-5)/16.
+	if p := 16*lambda + 5; isPrime(p) {
+		if x := intSqrt((p - 1) / 4); x*x == (p-1)/4 && x%2 == 1 {
+			add(blockDesign{
+				name: fmt.Sprintf("fourth powers mod %d", p),
+				v:    p, k: (p - 1) / 4, lambda: lambda,
+				build: func() [][]int { return developDifferenceSet([]int{p}, powerResidues(p, 4)) },
+			})
+		}
+	}
+	// The biplane on 16 points, from a difference set in Z4 × Z4.
+	if lambda == 2 {
+		add(blockDesign{
+			name: "difference set in Z4 × Z4",
+			v:    16, k: 6, lambda: 2,
+			build: func() [][]int { return developDifferenceSet([]int{4, 4}, []int{1, 2, 3, 4, 8, 12}) },
+		})
+	}
+
+	slices.SortFunc(designs, func(a, b blockDesign) int { return a.k - b.k })
+	return designs
+}
+
+// findDesign returns the design for the given symbols per card and λ.
+func findDesign(symbols, lambda int) (blockDesign, error) {
+	designs := lambdaDesigns(lambda)
+	for _, d := range designs {
+		if d.k == symbols {
+			return d, nil
+		}
+	}
+	if len(designs) == 0 {
+		return blockDesign{}, fmt.Errorf("no design is known where every two cards share %d symbols", lambda)
+	}
+	return blockDesign{}, fmt.Errorf("%d symbols per card cannot form a deck where every two cards share %d symbols; valid values: %s",
+		symbols, lambda, formatCounts(designSymbolCounts(designs)))
+}
+
+// designSymbolCounts lists the symbols per card of designs.
+func designSymbolCounts(designs []blockDesign) []int {
+	counts := make([]int, len(designs))
+	for i, d := range designs {
+		counts[i] = d.k
+	}
+	return counts
+}
+
+// check verifies that cards form the design: v cards of k distinct symbols
+// out of v, every two sharing exactly λ. It guards the constructions, which
+// are easy to get subtly wrong.
+func (d blockDesign) check(cards [][]int) error {
+	if len(cards) != d.v {
+		return fmt.Errorf("%s: %d cards instead of %d", d.name, len(cards), d.v)
+	}
+	sets := make([]map[int]bool, len(cards))
+	for i, card := range cards {
+		sets[i] = make(map[int]bool, len(card))
+		for _, s := range card {
+			if s < 1 || s > d.v {
+				return fmt.Errorf("%s: card %d has symbol %d out of range", d.name, i, s)
+			}
+			sets[i][s] = true
+		}
+		if len(card) != d.k || len(sets[i]) != d.k {
+			return fmt.Errorf("%s: card %d has %d distinct symbols instead of %d", d.name, i, len(sets[i]), d.k)
+		}
+	}
+	for i := range cards {
+		for j := i + 1; j < len(cards); j++ {
+			shared := 0
+			for s := range sets[i] {
+				if sets[j][s] {
+					shared++
+				}
+			}
+			if shared != d.lambda {
+				return fmt.Errorf("%s: cards %d and %d share %d symbols instead of %d", d.name, i, j, shared, d.lambda)
+			}
+		}
+	}
+	return nil
+}
+
+// projectiveHyperplanes returns the hyperplanes of PG(dim, q) as cards of
+// its points. Points and hyperplanes are the nonzero vectors of GF(q)^(dim+1)
+// whose first nonzero coordinate is 1; a point lies on a hyperplane when
+// their dot product is zero.
+func projectiveHyperplanes(q, dim int) [][]int {
+	f, err := newGaloisField(q)
+	if err != nil {
+		panic(err) // callers check q first
+	}
+
+	var points [][]int
+	for v := 1; v < pow(q, dim+1); v++ {
+		coords := make([]int, dim+1)
+		for i, c := range digits(v, q) {
+			coords[dim-i] = c
+		}
+		if coords[slices.IndexFunc(coords, func(c int) bool { return c != 0 })] == 1 {
+			points = append(points, coords)
+		}
+	}
+
+	cards := make([][]int, len(points))
+	for i, h := range points {
+		for j, p := range points {
+			dot := 0
+			for c := range h {
+				dot = f.add[dot][f.mul[h[c]][p[c]]]
+			}
+			if dot == 0 {
+				cards[i] = append(cards[i], j+1)
+			}
+		}
+	}
+	return cards
+}
+
+// developDifferenceSet returns the translates of a difference set in the
+// group Z_m1 × Z_m2 × ..., whose elements are encoded in mixed radix with
+// the last modulus varying fastest.
+func developDifferenceSet(moduli, set []int) [][]int {
+	order := 1
+	for _, m := range moduli {
+		order *= m
+	}
+	add := func(a, b int) int {
+		sum, place := 0, 1
+		for i := len(moduli) - 1; i >= 0; i-- {
+			m := moduli[i]
+			sum += (a%m + b%m) % m * place
+			a, b, place = a/m, b/m, place*m
+		}
+		return sum
+	}
+
+	cards := make([][]int, order)
+	for g := range order {
+		for _, d := range set {
+			cards[g] = append(cards[g], add(d, g)+1)
+		}
+	}
+	return cards
+}
+
+// complementDesign replaces every card by the symbols of 1..v it lacks.
+func complementDesign(cards [][]int, v int) [][]int {
+	out := make([][]int, len(cards))
+	for i, card := range cards {
+		for s := 1; s <= v; s++ {
+			if !slices.Contains(card, s) {
+				out[i] = append(out[i], s)
+			}
+		}
+	}
+	return out
+}
+
+// powerResidues returns the distinct nonzero e-th powers modulo p.
+func powerResidues(p, e int) []int {
+	var residues []int
+	for x := 1; x < p; x++ {
+		r := 1
+		for range e {
+			r = r * x % p
+		}
+		if !slices.Contains(residues, r) {
+			residues = append(residues, r)
+		}
+	}
+	slices.Sort(residues)
+	return residues
+}
+
+func isPrime(n int) bool {
+	_, k, ok := primePower(n)
+	return ok && k == 1
+}
+
+func isPrimePower(n int) bool {
+	_, _, ok := primePower(n)
+	return ok
+}
+
+func intSqrt(n int) int {
+	x := 0
+	for (x+1)*(x+1) <= n {
+		x++
+	}
+	return x
+}
+
+// deckSize returns the number of symbols, and of cards, of the full deck
+// with the given symbols per card where every two cards share λ symbols.
+func deckSize(symbols, lambda int) (int, error) {
+	if lambda > 1 {
+		d, err := findDesign(symbols, lambda)
+		return d.v, err
+	}
+	if _, _, ok := primePower(symbols - 1); !ok {
+		return 0, fmt.Errorf("%d symbols per card cannot form a valid deck; valid values up to 32: %s",
+			symbols, formatCounts(validSymbolCounts(32)))
+	}
+	n := symbols - 1
+	return n*n + n + 1, nil
+}
+
+// symbolCounts lists the valid symbols per card for λ.
+func symbolCounts(lambda int) []int {
+	if lambda > 1 {
+		return designSymbolCounts(lambdaDesigns(lambda))
+	}
+	return validSymbolCounts(32)
+}
+
+// suggestSymbols returns the largest symbols per card whose full deck the
+// available images suffice for, with the number of cards of that deck.
+func suggestSymbols(available, lambda int) (symbols, cards int, ok bool) {
+	for _, k := range symbolCounts(lambda) {
+		if v, err := deckSize(k, lambda); err == nil && v <= available {
+			symbols, cards, ok = k, v, true
+		}
+	}
+	return symbols, cards, ok
+}
+
+// lambda returns the number of symbols every two cards of the deck share.
+func (cg *CardGenerator) lambda() int {
+	if cg.Design != nil {
+		return cg.Design.lambda
+	}
+	return 1
+}
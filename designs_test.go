@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLambdaDesigns(t *testing.T) {
+	families := map[string]bool{
+		"planes of PG(3":                  false,
+		"complement of PG(2":              false,
+		"quadratic residues":              false,
+		"quadratic non-residues and zero": false,
+		"fourth powers":                   false,
+		"difference set in Z4 × Z4":       false,
+	}
+	for lambda := 1; lambda <= 6; lambda++ {
+		for _, d := range lambdaDesigns(lambda) {
+			if d.lambda != lambda {
+				t.Errorf("%s listed for λ = %d has λ = %d", d.name, lambda, d.lambda)
+			}
+			if err := d.check(d.build()); err != nil {
+				t.Error(err)
+			}
+			for prefix := range families {
+				if strings.HasPrefix(d.name, prefix) {
+					families[prefix] = true
+				}
+			}
+		}
+	}
+	for prefix, seen := range families {
+		if !seen {
+			t.Errorf("no design of the family %q checked", prefix)
+		}
+	}
+}
+
+func TestFindDesign(t *testing.T) {
+	tests := []struct {
+		symbols, lambda, cards int
+	}{
+		{4, 2, 7},
+		{5, 2, 11},
+		{6, 2, 16},
+		{9, 2, 37},
+		{7, 3, 15},
+	}
+	for _, tt := range tests {
+		d, err := findDesign(tt.symbols, tt.lambda)
+		if err != nil {
+			t.Errorf("findDesign(%d, %d): %v", tt.symbols, tt.lambda, err)
+			continue
+		}
+		if d.v != tt.cards {
+			t.Errorf("findDesign(%d, %d) has %d cards, want %d", tt.symbols, tt.lambda, d.v, tt.cards)
+		}
+	}
+
+	if _, err := findDesign(8, 2); err == nil {
+		t.Error("findDesign(8, 2) found a design")
+	}
+}
+
+func TestDesignCheckRejects(t *testing.T) {
+	d := blockDesign{name: "fano", v: 7, k: 3, lambda: 1}
+	cards, err := (&CardGenerator{ImagesPerCard: 3}).generateCardIndices(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.check(cards); err != nil {
+		t.Fatal(err)
+	}
+
+	broken := make([][]int, len(cards))
+	copy(broken, cards)
+	broken[0] = []int{cards[0][0], cards[0][1], cards[0][1]}
+	if d.check(broken) == nil {
+		t.Error("check accepted a card with a repeated symbol")
+	}
+	if d.check(cards[1:]) == nil {
+		t.Error("check accepted a deck with a card missing")
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/disintegration/imaging"
+)
+
+// symbolContrast is how well a symbol stands out from the card background.
+type symbolContrast struct {
+	File  string  `json:"file"`
+	Ratio float64 `json:"ratio"` // WCAG contrast ratio of its average visible color, 1 to 21
+}
+
+// replacement suggests a spare image for a symbol that is hard to spot.
+type replacement struct {
+	File string `json:"file"`
+	With string `json:"with"`
+}
+
+// difficultyReport lists what makes a deck hard to play: symbol pairs that
+// look alike and symbols that hardly stand out from the card.
+type difficultyReport struct {
+	Symbols      int              `json:"symbols"`
+	Rating       string           `json:"rating"` // easy, medium or hard
+	Similar      []similarPair    `json:"similar,omitempty"`
+	LowContrast  []symbolContrast `json:"lowContrast,omitempty"`
+	Replacements []replacement    `json:"replacements,omitempty"`
+}
+
+// relativeLuminance is the WCAG luminance of an sRGB color, 0 to 1.
+func relativeLuminance(c color.NRGBA) float64 {
+	channel := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B)
+}
+
+// contrastRatio is the WCAG contrast ratio of two luminances.
+func contrastRatio(a, b float64) float64 {
+	return (math.Max(a, b) + 0.05) / (math.Min(a, b) + 0.05)
+}
+
+// symbolContrastRatio returns the contrast between the average visible
+// color of a symbol and the background. Vector symbols are drawn black.
+func symbolContrastRatio(src *symbolSource, background color.NRGBA) float64 {
+	bg := relativeLuminance(background)
+	if src.svg != nil {
+		return contrastRatio(0, bg)
+	}
+
+	// A thumbnail is enough for the average color.
+	img := src.img
+	if b := img.Bounds(); max(b.Dx(), b.Dy()) > 128 {
+		img = imaging.Fit(img, 128, 128, imaging.Box)
+	}
+	return imageContrast(img, bg)
+}
+
+// analyzeDifficulty checks every pair of deck symbols for similarity and
+// every symbol for contrast against the background. Spares, images not in
+// the deck, are suggested as replacements for the symbols involved.
+func analyzeDifficulty(paths, spares []string, background color.NRGBA, maxDistance int, minContrast float64) (difficultyReport, error) {
+	report := difficultyReport{Symbols: len(paths)}
+
+	all := append(slices.Clone(paths), spares...)
+	hashes := make(map[string]perceptualHash, len(all))
+	contrast := make(map[string]float64, len(all))
+	cache := newSymbolCache()
+	for _, path := range all {
+		_, src, err := cache.decode(path)
+		if err != nil {
+			if slices.Contains(spares, path) {
+				continue
+			}
+			return report, err
+		}
+		hashes[path] = hashSymbol(src)
+		contrast[path] = symbolContrastRatio(src, background)
+	}
+
+	report.Similar = findSimilarSymbols(paths, hashes, maxDistance)
+	for _, path := range paths {
+		if r := contrast[path]; r < minContrast {
+			report.LowContrast = append(report.LowContrast, symbolContrast{File: path, Ratio: math.Round(r*100) / 100})
+		}
+	}
+	slices.SortFunc(report.LowContrast, func(a, b symbolContrast) int { return cmp.Compare(a.Ratio, b.Ratio) })
+
+	// One symbol of each similar pair and every low-contrast symbol should
+	// go. Each gets the unused spare most unlike the rest of the deck.
+	var replace []string
+	for _, p := range report.Similar {
+		if !slices.Contains(replace, p.B) && !slices.Contains(replace, p.A) {
+			replace = append(replace, p.B)
+		}
+	}
+	for _, c := range report.LowContrast {
+		if !slices.Contains(replace, c.File) {
+			replace = append(replace, c.File)
+		}
+	}
+	kept := slices.DeleteFunc(slices.Clone(paths), func(p string) bool { return slices.Contains(replace, p) })
+	for _, file := range replace {
+		best, bestDistance := "", maxDistance
+		for _, spare := range spares {
+			if _, ok := hashes[spare]; !ok || contrast[spare] < minContrast || slices.Contains(kept, spare) {
+				continue
+			}
+			d := 64
+			for _, k := range kept {
+				d = min(d, hashes[spare].distance(hashes[k]))
+			}
+			if d > bestDistance {
+				best, bestDistance = spare, d
+			}
+		}
+		if best != "" {
+			report.Replacements = append(report.Replacements, replacement{File: file, With: best})
+			kept = append(kept, best)
+		}
+	}
+
+	switch issues := len(replace); {
+	case issues == 0:
+		report.Rating = "easy"
+	case issues*10 <= len(paths):
+		report.Rating = "medium"
+	default:
+		report.Rating = "hard"
+	}
+	return report, nil
+}
+
+// print writes the report in a human-readable form.
+func (r difficultyReport) print(w io.Writer, names map[string]string) {
+	label := func(file string) string {
+		if name := names[file]; name != "" {
+			return name + " (" + filepath.Base(file) + ")"
+		}
+		return file
+	}
+
+	fmt.Fprintf(w, "Symbols:     %d\n", r.Symbols)
+	fmt.Fprintf(w, "Difficulty:  %s\n", r.Rating)
+	if len(r.Similar) > 0 {
+		fmt.Fprintln(w, "\nSimilar symbols (differing hash bits out of 64):")
+		for _, p := range r.Similar {
+			fmt.Fprintf(w, "  %2d  %s ~ %s\n", p.Distance, label(p.A), label(p.B))
+		}
+	}
+	if len(r.LowContrast) > 0 {
+		fmt.Fprintln(w, "\nLow contrast against the card (ratio):")
+		for _, c := range r.LowContrast {
+			fmt.Fprintf(w, "  %5.2f  %s\n", c.Ratio, label(c.File))
+		}
+	}
+	if len(r.Replacements) > 0 {
+		fmt.Fprintln(w, "\nSuggested replacements:")
+		for _, s := range r.Replacements {
+			fmt.Fprintf(w, "  %s -> %s\n", label(s.File), s.With)
+		}
+	}
+}
+
+// runDifficulty implements the difficulty command. It reports symbols of a
+// saved deck that players could confuse or overlook, for accessible and
+// kid-friendly decks.
+func runDifficulty(args []string) error {
+	fs := flag.NewFlagSet("difficulty", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dobble difficulty [flags] <manifest.json>")
+		fs.PrintDefaults()
+	}
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	background := fs.String("background", "#ffffff", "card background color as #rrggbb")
+	similar := fs.Int("similar", 12, "report symbol pairs whose perceptual hashes differ in at most this many of 64 bits")
+	minContrast := fs.Float64("min-contrast", 2, "report symbols with a lower WCAG contrast ratio against the background")
+	spareDir := fs.String("spares", "", "directory of images to suggest as replacements (default: the directories of the deck's symbols)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one manifest file")
+	}
+	bg, err := parseHexColor(*background)
+	if err != nil {
+		return err
+	}
+
+	m, err := loadManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	paths := deckSymbols(m)
+
+	var dirs []string
+	if *spareDir != "" {
+		dirs = []string{*spareDir}
+	} else {
+		for _, p := range paths {
+			if dir := filepath.Dir(p); !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	var spares []string
+	for _, dir := range dirs {
+		found, err := discoverImages(dir, imageFilter{})
+		if err != nil {
+			continue
+		}
+		for _, f := range found {
+			if !slices.Contains(paths, f) && !slices.Contains(spares, f) {
+				spares = append(spares, f)
+			}
+		}
+	}
+
+	report, err := analyzeDifficulty(paths, spares, color.NRGBA{R: bg.R, G: bg.G, B: bg.B, A: 0xff}, *similar, *minContrast)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	report.print(os.Stdout, m.SymbolNames)
+	return nil
+}
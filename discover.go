@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// imageFilter selects the files picked up from the image directory.
+// Patterns use path.Match syntax and are matched against the slash-separated
+// path relative to the directory.
+type imageFilter struct {
+	Recursive bool
+	Include   []string
+	Exclude   []string
+}
+
+// checkPattern reports malformed glob patterns up front instead of silently
+// matching nothing.
+func checkPattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// match reports whether the image at the relative path rel passes the
+// filter. Without include patterns every image is included.
+func (f imageFilter) match(rel string) bool {
+	for _, p := range f.Exclude {
+		if ok, _ := path.Match(p, rel); ok {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, p := range f.Include {
+		if ok, _ := path.Match(p, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isHidden reports whether a file or directory name is hidden by Unix
+// convention.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// discoverImages lists the supported image files in dir, which may also be
+// a zip archive. Hidden files and directories are skipped.
+func discoverImages(dir string, filter imageFilter) ([]string, error) {
+	if isZip(dir) {
+		return discoverZipImages(dir, filter)
+	}
+
+	var images []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && (!filter.Recursive || isHidden(d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(d.Name()))
+		if isHidden(d.Name()) || !supportedImageExts[ext] {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if filter.match(filepath.ToSlash(rel)) {
+			images = append(images, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image directory: %w", err)
+	}
+
+	return images, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// Rough figures for estimating the size of the PDF: layouts cover about half
+// of a card with symbols, and embedded PNG renditions compress to about two
+// bytes per pixel, JPEG ones to about a third of a byte.
+const (
+	estimatedCoverage     = 0.5
+	estimatedPNGPerPixel  = 2
+	estimatedJPEGPerPixel = 0.3
+	estimatedPageBytes    = 2 << 10
+)
+
+// deckPlan is the deck math for a set of options, computed without loading
+// or rendering any images.
+type deckPlan struct {
+	SymbolsPerCard  int
+	RequiredSymbols int
+	Cards           int
+	Copies          int
+	CardsPerPage    int
+	Pages           int
+	EstimatedBytes  int64
+}
+
+// planDeckMath computes what generating a deck with opts would produce.
+func planDeckMath(opts options) (deckPlan, error) {
+	full, err := deckSize(opts.deckSymbols(), opts.lambda)
+	if err != nil {
+		return deckPlan{}, err
+	}
+	if opts.totalCards < 0 {
+		return deckPlan{}, fmt.Errorf("total cards must not be negative, got %d", opts.totalCards)
+	}
+
+	if opts.perPage > 0 {
+		if err := opts.fitCards(opts.perPage); err != nil {
+			return deckPlan{}, err
+		}
+	}
+
+	_, cols, rows, err := pageGrid(opts)
+	if err != nil {
+		return deckPlan{}, err
+	}
+
+	p := deckPlan{
+		SymbolsPerCard:  opts.imagesPerCard,
+		RequiredSymbols: full + opts.logoSymbols(),
+		Cards:           full,
+		Copies:          max(opts.copies, 1),
+		CardsPerPage:    cols * rows,
+	}
+	if opts.totalCards > 0 {
+		p.Cards = min(opts.totalCards, full)
+	}
+
+	// Every copy starts on a new page, and every front page is followed by
+	// its back page when backs are printed.
+	p.Pages = p.Copies * ((p.Cards + p.CardsPerPage - 1) / p.CardsPerPage)
+	if opts.backs {
+		p.Pages *= 2
+	}
+
+	// Renditions are embedded once and reused by later copies.
+	sidePx := math.Sqrt(opts.layoutShape().usableArea()*estimatedCoverage/float64(p.SymbolsPerCard)) / mmPerInch * opts.imageDPI
+	perPixel := float64(estimatedPNGPerPixel)
+	if opts.jpegQuality > 0 {
+		perPixel = estimatedJPEGPerPixel
+	}
+	images := float64(p.Cards*p.SymbolsPerCard) * sidePx * sidePx * perPixel
+	p.EstimatedBytes = int64(images) + int64(p.Pages)*estimatedPageBytes
+
+	return p, nil
+}
+
+// print writes the plan in a human-readable form.
+func (p deckPlan) print(w io.Writer) {
+	fmt.Fprintf(w, "Symbols per card:  %d\n", p.SymbolsPerCard)
+	fmt.Fprintf(w, "Required symbols:  %d\n", p.RequiredSymbols)
+	fmt.Fprintf(w, "Cards:             %d\n", p.Cards)
+	if p.Copies > 1 {
+		fmt.Fprintf(w, "Copies:            %d\n", p.Copies)
+	}
+	fmt.Fprintf(w, "Cards per page:    %d\n", p.CardsPerPage)
+	fmt.Fprintf(w, "Pages:             %d\n", p.Pages)
+	fmt.Fprintf(w, "Estimated size:    %.1f MB\n", float64(p.EstimatedBytes)/(1<<20))
+}
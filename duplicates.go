@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log/slog"
+	"math/bits"
+	"slices"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// hashGrid is the edge length of the grayscale thumbnail perceptual hashes
+// are computed from; comparing horizontal neighbors in all but the last row
+// gives 64 bits.
+const hashGrid = 9
+
+// perceptualHash is a difference hash of a symbol for each of its four
+// rotations, since symbols are printed rotated.
+type perceptualHash [4]uint64
+
+// hashSymbol computes the perceptual hash of a decoded symbol. The image is
+// composited onto white first, so transparent and white backgrounds hash
+// alike.
+func hashSymbol(src *symbolSource) perceptualHash {
+	img := src.img
+	if src.svg != nil {
+		img = rasterizeSVG(src.svg, 64)
+	}
+	thumb := imaging.Resize(img, hashGrid, hashGrid, imaging.Box)
+
+	var grid [hashGrid][hashGrid]float64
+	for y := range hashGrid {
+		for x := range hashGrid {
+			c := color.NRGBAModel.Convert(thumb.At(x, y)).(color.NRGBA)
+			a := float64(c.A) / 255
+			l := (0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)) / 255
+			grid[y][x] = l*a + (1 - a)
+		}
+	}
+
+	var h perceptualHash
+	for r := range h {
+		var bitsSet uint64
+		for y := range hashGrid - 1 {
+			for x := range hashGrid - 1 {
+				bitsSet <<= 1
+				if grid[y][x] > grid[y][x+1] {
+					bitsSet |= 1
+				}
+			}
+		}
+		h[r] = bitsSet
+		grid = rotateGrid(grid)
+	}
+	return h
+}
+
+// rotateGrid turns the thumbnail by 90 degrees.
+func rotateGrid(g [hashGrid][hashGrid]float64) [hashGrid][hashGrid]float64 {
+	var out [hashGrid][hashGrid]float64
+	for y := range hashGrid {
+		for x := range hashGrid {
+			out[x][hashGrid-1-y] = g[y][x]
+		}
+	}
+	return out
+}
+
+// distance returns the number of differing bits between the hashes at the
+// closest pair of rotations, from 0 (alike) to 64.
+func (h perceptualHash) distance(other perceptualHash) int {
+	d := 64
+	for _, v := range h {
+		d = min(d, bits.OnesCount64(v^other[0]))
+	}
+	return d
+}
+
+// similarPair is two symbols whose perceptual hashes are close.
+type similarPair struct {
+	A        string `json:"a"`
+	B        string `json:"b"`
+	Distance int    `json:"distance"`
+}
+
+// hashSymbols decodes the symbol files one at a time and hashes them.
+func hashSymbols(paths []string) (map[string]perceptualHash, error) {
+	cache := newSymbolCache()
+	hashes := make(map[string]perceptualHash, len(paths))
+	for _, path := range paths {
+		_, src, err := cache.decode(path)
+		if err != nil {
+			return nil, err
+		}
+		hashes[path] = hashSymbol(src)
+	}
+	return hashes, nil
+}
+
+// findSimilarSymbols returns every pair of symbols at most maxDistance
+// apart, closest first.
+func findSimilarSymbols(paths []string, hashes map[string]perceptualHash, maxDistance int) []similarPair {
+	var pairs []similarPair
+	for i, a := range paths {
+		for _, b := range paths[i+1:] {
+			if d := hashes[a].distance(hashes[b]); d <= maxDistance {
+				pairs = append(pairs, similarPair{A: a, B: b, Distance: d})
+			}
+		}
+	}
+	slices.SortStableFunc(pairs, func(p, q similarPair) int { return p.Distance - q.Distance })
+	return pairs
+}
+
+// deckSymbols returns the distinct symbol files of a deck in order of first
+// appearance.
+func deckSymbols(m *Manifest) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, card := range m.Cards {
+		for _, p := range card.Placements {
+			if !seen[p.File] {
+				seen[p.File] = true
+				paths = append(paths, p.File)
+			}
+		}
+	}
+	return paths
+}
+
+// checkDuplicates warns about symbols of the deck that look nearly the same,
+// since players could not tell which one two cards share. In strict mode it
+// fails instead.
+func checkDuplicates(m *Manifest, maxDistance int, strict bool) error {
+	if maxDistance <= 0 {
+		return nil
+	}
+
+	paths := deckSymbols(m)
+	hashes, err := hashSymbols(paths)
+	if err != nil {
+		return err
+	}
+	pairs := findSimilarSymbols(paths, hashes, maxDistance)
+	for _, p := range pairs {
+		slog.Warn("Symbols look nearly identical", "a", p.A, "b", p.B, "distance", p.Distance)
+	}
+	if strict && len(pairs) > 0 {
+		names := make([]string, len(pairs))
+		for i, p := range pairs {
+			names[i] = p.A + " ~ " + p.B
+		}
+		return fmt.Errorf("%d pairs of symbols look nearly identical: %s", len(pairs), strings.Join(names, ", "))
+	}
+	return nil
+}
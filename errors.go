@@ -0,0 +1,48 @@
+package main
+
+import "errors"
+
+// Errors callers can test for with errors.Is to react to a failure without
+// parsing its message. Each has its own exit code.
+var (
+	// ErrInvalidParameters means the deck parameters cannot form a deck.
+	ErrInvalidParameters = errors.New("invalid input")
+	// ErrNotEnoughImages means there are fewer symbol images than the deck
+	// needs.
+	ErrNotEnoughImages = errors.New("not enough images")
+	// ErrImageDecode means a symbol image cannot be read.
+	ErrImageDecode = errors.New("failed to decode image")
+	// ErrPDFWrite means the PDF could not be generated or written.
+	ErrPDFWrite = errors.New("PDF generation failed")
+	// ErrCancelled means the run was interrupted by SIGINT or SIGTERM.
+	ErrCancelled = errors.New("cancelled")
+)
+
+// Exit codes of the CLI. Invalid flags exit with 2 like the flag package.
+const (
+	exitFailure         = 1
+	exitUsage           = 2
+	exitNotEnoughImages = 3
+	exitImageDecode     = 4
+	exitPDFWrite        = 5
+	exitCancelled       = 130 // 128 + SIGINT, like a shell reports it
+)
+
+// exitCode returns the exit code for an error returned by a command. A
+// broken image makes the PDF fail too, so it is checked first.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, ErrCancelled):
+		return exitCancelled
+	case errors.Is(err, ErrInvalidParameters):
+		return exitUsage
+	case errors.Is(err, ErrNotEnoughImages):
+		return exitNotEnoughImages
+	case errors.Is(err, ErrImageDecode):
+		return exitImageDecode
+	case errors.Is(err, ErrPDFWrite):
+		return exitPDFWrite
+	default:
+		return exitFailure
+	}
+}
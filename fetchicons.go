@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// iconPack is an openly licensed icon set fetch-icons can download. Its
+// metadata lists every icon with the categories it can be filtered by.
+type iconPack struct {
+	metadataURL string
+	imageURL    string // format string taking the icon's hex code
+	license     string
+}
+
+// openMojiBase is where OpenMoji publishes its metadata and images.
+const openMojiBase = "https://raw.githubusercontent.com/hfg-gmuend/openmoji/master/"
+
+const openMojiLicense = `Emoji artwork by OpenMoji (https://openmoji.org), the open-source emoji
+and icon project, licensed under CC BY-SA 4.0
+(https://creativecommons.org/licenses/by-sa/4.0/).
+Decks made with these symbols must credit OpenMoji and share their
+artwork under the same license.
+`
+
+var iconPacks = map[string]iconPack{
+	"openmoji":       {openMojiBase + "data/openmoji.json", openMojiBase + "color/618x618/%s.png", openMojiLicense},
+	"openmoji-black": {openMojiBase + "data/openmoji.json", openMojiBase + "black/618x618/%s.png", openMojiLicense},
+}
+
+// iconPackEntry is an icon of the pack metadata.
+type iconPackEntry struct {
+	Hexcode    string `json:"hexcode"`
+	Group      string `json:"group"`
+	Subgroups  string `json:"subgroups"`
+	Annotation string `json:"annotation"`
+	Skintone   string `json:"skintone"`
+}
+
+// iconAttributionFile is written next to the icons with the license terms of
+// the pack.
+const iconAttributionFile = "ATTRIBUTION.txt"
+
+// runFetchIcons implements the fetch-icons command. It downloads icons of an
+// openly licensed pack into the image directory, so a first deck needs no
+// images of one's own.
+func runFetchIcons(args []string) error {
+	fs := flag.NewFlagSet("fetch-icons", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dobble fetch-icons [flags]")
+		fs.PrintDefaults()
+	}
+	var packNames []string
+	for name := range iconPacks {
+		packNames = append(packNames, name)
+	}
+	slices.Sort(packNames)
+	packName := fs.String("pack", "openmoji", "icon pack: "+strings.Join(packNames, ", "))
+	dir := fs.String("img-dir", imgDir, "directory to save the icons in")
+	categories := fs.String("category", "", "comma-separated categories or subcategories to pick icons from, e.g. animals-nature,food-fruit (default: all)")
+	count := fs.Int("count", 57, "number of icons to download; 57 make a full deck with 8 symbols per card")
+	list := fs.Bool("list", false, "list the categories of the pack instead of downloading")
+	fs.Parse(args)
+
+	pack, ok := iconPacks[*packName]
+	if !ok || *count < 1 || fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unknown icon pack %q or invalid count", *packName)
+	}
+
+	client := &http.Client{Timeout: remoteTimeout}
+	entries, err := fetchIconIndex(client, pack.metadataURL)
+	if err != nil {
+		return err
+	}
+	if *list {
+		printIconCategories(os.Stdout, entries)
+		return nil
+	}
+
+	var wanted []string
+	if *categories != "" {
+		for _, c := range strings.Split(*categories, ",") {
+			wanted = append(wanted, strings.TrimSpace(c))
+		}
+	}
+	picked := pickIcons(entries, wanted, *count)
+	if len(picked) == 0 {
+		return fmt.Errorf("no icons in categories %q; see fetch-icons --list", *categories)
+	}
+	if len(picked) < *count {
+		slog.Warn("Fewer icons available than requested", "requested", *count, "available", len(picked))
+	}
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create image directory: %w", err)
+	}
+	used := make(map[string]bool)
+	for i, e := range picked {
+		name := iconFileName(e)
+		if used[name] {
+			name = strings.TrimSuffix(name, ".png") + "-" + strings.ToLower(e.Hexcode) + ".png"
+		}
+		used[name] = true
+		dest := filepath.Join(*dir, name)
+		if _, err := os.Stat(dest); err == nil {
+			continue
+		}
+		if _, err := download(client, fmt.Sprintf(pack.imageURL, e.Hexcode), func(*http.Response) (string, error) { return dest, nil }); err != nil {
+			return fmt.Errorf("failed to download icon %s: %w", e.Hexcode, err)
+		}
+		slog.Debug("Downloaded icon", "icon", e.Annotation, "path", dest, "done", i+1, "total", len(picked))
+	}
+	if err := os.WriteFile(filepath.Join(*dir, iconAttributionFile), []byte(pack.license), 0o644); err != nil {
+		return fmt.Errorf("failed to write attribution: %w", err)
+	}
+
+	slog.Info("Icons ready", "pack", *packName, "count", len(picked), "dir", *dir)
+	return nil
+}
+
+// fetchIconIndex downloads and decodes the metadata of a pack.
+func fetchIconIndex(client *http.Client, url string) ([]iconPackEntry, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download icon index: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download icon index: unexpected status %s", resp.Status)
+	}
+
+	var entries []iconPackEntry
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDownloadSize)).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to parse icon index: %w", err)
+	}
+	return entries, nil
+}
+
+// pickIcons returns up to count icons in the given categories, matched
+// against group and subgroup. Skin tone variants and components are left
+// out, since they would look like duplicates on a card.
+func pickIcons(entries []iconPackEntry, categories []string, count int) []iconPackEntry {
+	var picked []iconPackEntry
+	for _, e := range entries {
+		if len(picked) == count {
+			break
+		}
+		if e.Skintone != "" || e.Group == "component" {
+			continue
+		}
+		if len(categories) > 0 && !slices.Contains(categories, e.Group) && !slices.Contains(categories, e.Subgroups) {
+			continue
+		}
+		picked = append(picked, e)
+	}
+	return picked
+}
+
+// printIconCategories lists the groups of a pack with their subgroups and
+// icon counts.
+func printIconCategories(w io.Writer, entries []iconPackEntry) {
+	counts := make(map[string]int)
+	var groups []string
+	subgroups := make(map[string][]string)
+	for _, e := range entries {
+		if e.Skintone != "" || e.Group == "component" {
+			continue
+		}
+		if counts[e.Group] == 0 {
+			groups = append(groups, e.Group)
+		}
+		if counts[e.Subgroups] == 0 {
+			subgroups[e.Group] = append(subgroups[e.Group], e.Subgroups)
+		}
+		counts[e.Group]++
+		counts[e.Subgroups]++
+	}
+	for _, g := range groups {
+		fmt.Fprintf(w, "%s (%d)\n", g, counts[g])
+		for _, s := range subgroups[g] {
+			fmt.Fprintf(w, "  %s (%d)\n", s, counts[s])
+		}
+	}
+}
+
+// iconFileName names an icon file after its annotation, so labels and
+// symbol lists show readable names.
+func iconFileName(e iconPackEntry) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '-'
+	}, e.Annotation)
+	name = strings.Trim(name, "-")
+	for strings.Contains(name, "--") {
+		name = strings.ReplaceAll(name, "--", "-")
+	}
+	if name == "" {
+		name = e.Hexcode
+	}
+	return name + ".png"
+}
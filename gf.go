@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// galoisField holds the addition and multiplication tables of GF(p^k).
+// Elements are the integers 0..order-1, read as polynomials over GF(p) whose
+// coefficients are the base-p digits.
+type galoisField struct {
+	order int
+	add   [][]int
+	mul   [][]int
+}
+
+// primePower reports whether q = p^k for a prime p and k >= 1.
+func primePower(q int) (p, k int, ok bool) {
+	if q < 2 {
+		return 0, 0, false
+	}
+	for p = 2; p*p <= q; p++ {
+		if q%p == 0 {
+			break
+		}
+	}
+	if q%p != 0 {
+		return q, 1, true
+	}
+	for k = 0; q%p == 0; k++ {
+		q /= p
+	}
+	return p, k, q == 1
+}
+
+// validSymbolCounts lists every symbols-per-card value up to max for which a
+// complete deck can be constructed.
+func validSymbolCounts(max int) []int {
+	var counts []int
+	for s := 3; s <= max; s++ {
+		if _, _, ok := primePower(s - 1); ok {
+			counts = append(counts, s)
+		}
+	}
+	return counts
+}
+
+func formatCounts(counts []int) string {
+	parts := make([]string, len(counts))
+	for i, c := range counts {
+		parts[i] = strconv.Itoa(c)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func newGaloisField(q int) (*galoisField, error) {
+	p, k, ok := primePower(q)
+	if !ok {
+		return nil, fmt.Errorf("no finite field of order %d", q)
+	}
+
+	f := &galoisField{order: q, add: square(q), mul: square(q)}
+
+	for a := 0; a < q; a++ {
+		for b := 0; b < q; b++ {
+			f.add[a][b] = polyAdd(a, b, p)
+		}
+	}
+
+	// Try monic polynomials of degree k until one yields a field, i.e. a
+	// multiplication without zero divisors.
+	for modulus := pow(p, k); modulus < 2*pow(p, k); modulus++ {
+		if f.fillMul(p, k, modulus) {
+			return f, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no irreducible polynomial of degree %d over GF(%d)", k, p)
+}
+
+func (f *galoisField) fillMul(p, k, modulus int) bool {
+	for a := 0; a < f.order; a++ {
+		for b := 0; b < f.order; b++ {
+			f.mul[a][b] = polyMulMod(a, b, p, k, modulus)
+			if a != 0 && b != 0 && f.mul[a][b] == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func square(n int) [][]int {
+	m := make([][]int, n)
+	for i := range m {
+		m[i] = make([]int, n)
+	}
+	return m
+}
+
+func pow(b, e int) int {
+	r := 1
+	for ; e > 0; e-- {
+		r *= b
+	}
+	return r
+}
+
+func digits(a, p int) []int {
+	var d []int
+	for ; a > 0; a /= p {
+		d = append(d, a%p)
+	}
+	return d
+}
+
+func undigits(d []int, p int) int {
+	a := 0
+	for i := len(d) - 1; i >= 0; i-- {
+		a = a*p + d[i]
+	}
+	return a
+}
+
+func polyAdd(a, b, p int) int {
+	r, m := 0, 1
+	for ; a > 0 || b > 0; a, b, m = a/p, b/p, m*p {
+		r += ((a%p + b%p) % p) * m
+	}
+	return r
+}
+
+// polyMulMod multiplies a and b and reduces the product modulo the monic
+// degree-k polynomial encoded by modulus.
+func polyMulMod(a, b, p, k, modulus int) int {
+	da, db := digits(a, p), digits(b, p)
+	if len(da) == 0 || len(db) == 0 {
+		return 0
+	}
+
+	prod := make([]int, len(da)+len(db)-1)
+	for i, x := range da {
+		for j, y := range db {
+			prod[i+j] = (prod[i+j] + x*y) % p
+		}
+	}
+
+	mod := digits(modulus, p)
+	for deg := len(prod) - 1; deg >= k; deg-- {
+		c := prod[deg]
+		if c == 0 {
+			continue
+		}
+		for i, m := range mod {
+			prod[deg-k+i] = ((prod[deg-k+i]-c*m)%p + p) % p
+		}
+	}
+
+	if len(prod) > k {
+		prod = prod[:k]
+	}
+	return undigits(prod, p)
+}
@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestPrimePower(t *testing.T) {
+	tests := []struct {
+		q, p, k int
+		ok      bool
+	}{
+		{2, 2, 1, true},
+		{7, 7, 1, true},
+		{8, 2, 3, true},
+		{9, 3, 2, true},
+		{25, 5, 2, true},
+		{1, 0, 0, false},
+		{6, 0, 0, false},
+		{12, 0, 0, false},
+	}
+	for _, tt := range tests {
+		p, k, ok := primePower(tt.q)
+		if ok != tt.ok || ok && (p != tt.p || k != tt.k) {
+			t.Errorf("primePower(%d) = %d, %d, %v, want %d, %d, %v", tt.q, p, k, ok, tt.p, tt.k, tt.ok)
+		}
+	}
+}
+
+func TestGaloisFieldAxioms(t *testing.T) {
+	for _, q := range []int{2, 3, 4, 5, 7, 8, 9, 16} {
+		f, err := newGaloisField(q)
+		if err != nil {
+			t.Fatalf("GF(%d): %v", q, err)
+		}
+		for a := 0; a < q; a++ {
+			if f.add[a][0] != a || f.mul[a][1] != a {
+				t.Fatalf("GF(%d): 0 or 1 is not the identity for %d", q, a)
+			}
+			hasNeg, hasInv := false, a == 0
+			for b := 0; b < q; b++ {
+				if f.add[a][b] != f.add[b][a] || f.mul[a][b] != f.mul[b][a] {
+					t.Fatalf("GF(%d): %d and %d do not commute", q, a, b)
+				}
+				hasNeg = hasNeg || f.add[a][b] == 0
+				hasInv = hasInv || f.mul[a][b] == 1
+				for c := 0; c < q; c++ {
+					if f.add[f.add[a][b]][c] != f.add[a][f.add[b][c]] || f.mul[f.mul[a][b]][c] != f.mul[a][f.mul[b][c]] {
+						t.Fatalf("GF(%d): (%d, %d, %d) not associative", q, a, b, c)
+					}
+					if f.mul[a][f.add[b][c]] != f.add[f.mul[a][b]][f.mul[a][c]] {
+						t.Fatalf("GF(%d): %d·(%d+%d) not distributive", q, a, b, c)
+					}
+				}
+			}
+			if !hasNeg || !hasInv {
+				t.Fatalf("GF(%d): %d has no additive or multiplicative inverse", q, a)
+			}
+		}
+	}
+
+	if _, err := newGaloisField(6); err == nil {
+		t.Error("newGaloisField(6) succeeded")
+	}
+}
+
+func TestProjectivePlanes(t *testing.T) {
+	for _, n := range []int{2, 3, 4, 5, 7, 8, 9} {
+		cg := &CardGenerator{ImagesPerCard: n + 1}
+		cards, err := cg.generateCardIndices(n)
+		if err != nil {
+			t.Fatalf("order %d: %v", n, err)
+		}
+		d := blockDesign{name: "projective plane", v: cg.calculateRequiredImages(), k: n + 1, lambda: 1}
+		if err := d.check(cards); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// glyphFormats lists the values accepted by --glyph-format.
+var glyphFormats = []string{"png", "svg"}
+
+// glyphEntry is a line of a glyph list: a codepoint of the icon font and an
+// optional display name.
+type glyphEntry struct {
+	Rune rune
+	Name string
+}
+
+// readGlyphList reads one "codepoint[,name]" record per line. Codepoints are
+// given in hex, as U+F015, 0xf015 or f015, or as the character itself.
+// Lines starting with # are comments.
+func readGlyphList(path string) ([]glyphEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open glyph list: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var entries []glyphEntry
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read glyph list: %w", err)
+		}
+
+		code := strings.TrimSpace(record[0])
+		if code == "" {
+			continue
+		}
+		cp, err := parseCodepoint(code)
+		if err != nil {
+			return nil, fmt.Errorf("glyph list entry %d: %w", line, err)
+		}
+		entry := glyphEntry{Rune: cp}
+		if len(record) > 1 {
+			entry.Name = strings.TrimSpace(record[1])
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseCodepoint reads a hex codepoint or a single character.
+func parseCodepoint(s string) (rune, error) {
+	if utf8.RuneCountInString(s) == 1 {
+		r, _ := utf8.DecodeRuneInString(s)
+		return r, nil
+	}
+	hex := s
+	for _, prefix := range []string{"U+", "u+", "0x", "0X"} {
+		hex = strings.TrimPrefix(hex, prefix)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || n > utf8.MaxRune {
+		return 0, fmt.Errorf("invalid codepoint %q", s)
+	}
+	return rune(n), nil
+}
+
+// renderGlyphSymbols renders the glyphs of the icon font at fontPath listed
+// in listPath to PNG or SVG files in dir. It returns the file paths and the
+// display names keyed by path: the name from the list, or the codepoint.
+func renderGlyphSymbols(fontPath, listPath, format, dir string) ([]string, map[string]string, error) {
+	entries, err := readGlyphList(listPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(fontPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read glyph font: %w", err)
+	}
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse glyph font: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create symbol directory: %w", err)
+	}
+
+	var buf sfnt.Buffer
+	files := make([]string, len(entries))
+	names := make(map[string]string)
+	for i, e := range entries {
+		idx, err := f.GlyphIndex(&buf, e.Rune)
+		if err != nil || idx == 0 {
+			return nil, nil, fmt.Errorf("glyph font has no glyph for U+%04X", e.Rune)
+		}
+		segs, err := f.LoadGlyph(&buf, idx, fixed.I(textSymbolPx), nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load glyph U+%04X: %w", e.Rune, err)
+		}
+		b := glyphBounds(segs)
+		if b.Empty() {
+			return nil, nil, fmt.Errorf("glyph U+%04X is empty", e.Rune)
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("glyph_%03d.%s", i+1, format))
+		if format == "svg" {
+			err = writeGlyphSVG(path, segs, b)
+		} else {
+			err = writePNG(path, rasterizeGlyph(segs, b), generatedSymbolDPI)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		files[i] = path
+		names[path] = e.Name
+		if e.Name == "" {
+			names[path] = fmt.Sprintf("U+%04X", e.Rune)
+		}
+	}
+
+	return files, names, nil
+}
+
+// glyphBounds returns the pixel rectangle covering the glyph outline and its
+// control points. Glyph coordinates grow downward, like image coordinates.
+func glyphBounds(segs sfnt.Segments) image.Rectangle {
+	var b fixed.Rectangle26_6
+	first := true
+	for _, seg := range segs {
+		n := 1
+		switch seg.Op {
+		case sfnt.SegmentOpQuadTo:
+			n = 2
+		case sfnt.SegmentOpCubeTo:
+			n = 3
+		}
+		for _, p := range seg.Args[:n] {
+			if first {
+				b.Min, b.Max, first = p, p, false
+				continue
+			}
+			b.Min.X, b.Min.Y = min(b.Min.X, p.X), min(b.Min.Y, p.Y)
+			b.Max.X, b.Max.Y = max(b.Max.X, p.X), max(b.Max.Y, p.Y)
+		}
+	}
+	return image.Rect(b.Min.X.Floor(), b.Min.Y.Floor(), b.Max.X.Ceil(), b.Max.Y.Ceil())
+}
+
+// glyphPoint converts a glyph coordinate to pixels relative to the corner of
+// its bounds.
+func glyphPoint(p fixed.Point26_6, b image.Rectangle) (float32, float32) {
+	return float32(p.X)/64 - float32(b.Min.X), float32(p.Y)/64 - float32(b.Min.Y)
+}
+
+// rasterizeGlyph fills the glyph outline in black on a transparent image
+// cropped to its bounds, like renderText.
+func rasterizeGlyph(segs sfnt.Segments, b image.Rectangle) *image.NRGBA {
+	r := vector.NewRasterizer(b.Dx(), b.Dy())
+	for i, seg := range segs {
+		ax, ay := glyphPoint(seg.Args[0], b)
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			if i > 0 {
+				r.ClosePath()
+			}
+			r.MoveTo(ax, ay)
+		case sfnt.SegmentOpLineTo:
+			r.LineTo(ax, ay)
+		case sfnt.SegmentOpQuadTo:
+			bx, by := glyphPoint(seg.Args[1], b)
+			r.QuadTo(ax, ay, bx, by)
+		case sfnt.SegmentOpCubeTo:
+			bx, by := glyphPoint(seg.Args[1], b)
+			cx, cy := glyphPoint(seg.Args[2], b)
+			r.CubeTo(ax, ay, bx, by, cx, cy)
+		}
+	}
+	r.ClosePath()
+
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	r.Draw(dst, dst.Bounds(), image.NewUniform(color.Black), image.Point{})
+	return dst
+}
+
+// writeGlyphSVG saves the glyph outline as an SVG path, which the PDF embeds
+// as vectors like any other SVG symbol.
+func writeGlyphSVG(path string, segs sfnt.Segments, b image.Rectangle) error {
+	var d strings.Builder
+	for i, seg := range segs {
+		ax, ay := glyphPoint(seg.Args[0], b)
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			if i > 0 {
+				d.WriteString("Z ")
+			}
+			fmt.Fprintf(&d, "M%.2f %.2f ", ax, ay)
+		case sfnt.SegmentOpLineTo:
+			fmt.Fprintf(&d, "L%.2f %.2f ", ax, ay)
+		case sfnt.SegmentOpQuadTo:
+			bx, by := glyphPoint(seg.Args[1], b)
+			fmt.Fprintf(&d, "Q%.2f %.2f %.2f %.2f ", ax, ay, bx, by)
+		case sfnt.SegmentOpCubeTo:
+			bx, by := glyphPoint(seg.Args[1], b)
+			cx, cy := glyphPoint(seg.Args[2], b)
+			fmt.Fprintf(&d, "C%.2f %.2f %.2f %.2f %.2f %.2f ", ax, ay, bx, by, cx, cy)
+		}
+	}
+	d.WriteString("Z")
+
+	svg := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d"><path d="%s"/></svg>`+"\n",
+		b.Dx(), b.Dy(), b.Dx(), b.Dy(), d.String())
+	if err := os.WriteFile(path, []byte(svg), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// deckGraph is the incidence structure of a deck: a bipartite graph with a
+// node per card and per symbol, and an edge wherever a symbol is on a card.
+// For a classic deck it is the incidence graph of a projective plane.
+type deckGraph struct {
+	cards   []string // card labels, by card
+	symbols []string // symbol labels, in order of first appearance
+	edges   [][2]int // card and symbol indices
+}
+
+func newDeckGraph(m *Manifest) deckGraph {
+	var g deckGraph
+	index := make(map[string]int)
+	for i, card := range m.Cards {
+		g.cards = append(g.cards, fmt.Sprintf("Card %d", i+1))
+		for _, s := range card.Symbols {
+			j, ok := index[s]
+			if !ok {
+				j = len(g.symbols)
+				index[s] = j
+				g.symbols = append(g.symbols, graphSymbolLabel(m, s))
+			}
+			g.edges = append(g.edges, [2]int{i, j})
+		}
+	}
+	return g
+}
+
+// graphSymbolLabel names a symbol by its display name or else its file name
+// without extension.
+func graphSymbolLabel(m *Manifest, file string) string {
+	if name := m.SymbolNames[file]; name != "" {
+		return name
+	}
+	base := filepath.Base(file)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// writeDOT writes the graph in the Graphviz DOT language, cards as boxes
+// and symbols as ellipses.
+func (g deckGraph) writeDOT(w io.Writer) error {
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	var b strings.Builder
+	b.WriteString("graph deck {\n\tlayout=neato;\n\toverlap=false;\n")
+	for i, label := range g.cards {
+		fmt.Fprintf(&b, "\tc%d [label=\"%s\", shape=box];\n", i+1, quote.Replace(label))
+	}
+	for j, label := range g.symbols {
+		fmt.Fprintf(&b, "\ts%d [label=\"%s\", shape=ellipse];\n", j+1, quote.Replace(label))
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&b, "\tc%d -- s%d;\n", e[0]+1, e[1]+1)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeGraphML writes the graph as GraphML, with the label and kind (card
+// or symbol) of every node as data.
+func (g deckGraph) writeGraphML(w io.Writer) error {
+	esc := func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	b.WriteString(`  <key id="label" for="node" attr.name="label" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="kind" for="node" attr.name="kind" attr.type="string"/>` + "\n")
+	b.WriteString(`  <graph id="deck" edgedefault="undirected">` + "\n")
+	node := func(id, label, kind string) {
+		fmt.Fprintf(&b, "    <node id=\"%s\"><data key=\"label\">%s</data><data key=\"kind\">%s</data></node>\n", id, esc(label), kind)
+	}
+	for i, label := range g.cards {
+		node(fmt.Sprintf("c%d", i+1), label, "card")
+	}
+	for j, label := range g.symbols {
+		node(fmt.Sprintf("s%d", j+1), label, "symbol")
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&b, "    <edge source=\"c%d\" target=\"s%d\"/>\n", e[0]+1, e[1]+1)
+	}
+	b.WriteString("  </graph>\n</graphml>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// runGraph implements the graph command, which exports the card–symbol
+// structure of a saved deck for Graphviz, Gephi or yEd.
+func runGraph(args []string) error {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dobble graph [flags] <manifest.json>")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "", "graph format: dot or graphml (default: from the --output extension, else dot)")
+	output := fs.String("output", "", "file to write the graph to (default: stdout)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one manifest file")
+	}
+	if *format == "" {
+		*format = "dot"
+		if strings.EqualFold(filepath.Ext(*output), ".graphml") {
+			*format = "graphml"
+		}
+	}
+	m, err := loadManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var write func(io.Writer) error
+	switch graph := newDeckGraph(m); *format {
+	case "dot":
+		write = graph.writeDOT
+	case "graphml":
+		write = graph.writeGraphML
+	default:
+		return fmt.Errorf("unknown graph format %q; use dot or graphml", *format)
+	}
+	if *output == "" {
+		return write(os.Stdout)
+	}
+	if err := writeAtomic(*output, write); err != nil {
+		return fmt.Errorf("failed to write %s: %w", *output, err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+)
+
+// htmlCardDPI is the resolution of the card images in the HTML gallery,
+// enough to read the symbols on screen.
+const htmlCardDPI = 150
+
+// htmlCard is one card of the gallery.
+type htmlCard struct {
+	Number  int
+	Image   string
+	Symbols []string
+}
+
+// exportHTML writes an index.html gallery showing every card with the list
+// of its symbols, for proofreading a deck before printing.
+func exportHTML(m *Manifest, opts options) error {
+	if err := os.MkdirAll(opts.htmlDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create HTML directory: %w", err)
+	}
+
+	cache := newSymbolCache()
+	prog := newProgress("html", len(m.Cards), opts)
+	defer prog.finish()
+
+	cards := make([]htmlCard, len(m.Cards))
+	for i, card := range m.Cards {
+		img, err := rasterizeCard(card, opts, cache, htmlCardDPI)
+		if err != nil {
+			return fmt.Errorf("failed to render card %d: %w", card.Index, err)
+		}
+		name := cardFileName(card.Index, "png")
+		if err := writePNG(filepath.Join(opts.htmlDir, name), img, htmlCardDPI); err != nil {
+			return err
+		}
+
+		cards[i] = htmlCard{Number: card.Index + 1, Image: name}
+		for _, s := range card.Placements {
+			cards[i].Symbols = append(cards[i].Symbols, symbolLabel(m.SymbolNames, s.File))
+		}
+		if err := prog.step(len(card.Placements)); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(filepath.Join(opts.htmlDir, "index.html"))
+	if err != nil {
+		return fmt.Errorf("failed to create gallery: %w", err)
+	}
+	defer f.Close()
+
+	data := struct {
+		Name  string
+		Seed  int64
+		Cards []htmlCard
+	}{opts.deckName, m.Seed, cards}
+	if err := galleryTemplate.Execute(f, data); err != nil {
+		return fmt.Errorf("failed to write gallery: %w", err)
+	}
+	return f.Close()
+}
+
+var galleryTemplate = template.Must(template.New("gallery").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 2rem; }
+main { display: grid; grid-template-columns: repeat(auto-fill, minmax(14rem, 1fr)); gap: 1.5rem; }
+figure { margin: 0; }
+img { width: 100%; }
+figcaption h2 { font-size: 1rem; margin: .5rem 0 .25rem; }
+ul { margin: 0; padding-left: 1.2rem; font-size: .9rem; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p>{{len .Cards}} cards, seed {{.Seed}}</p>
+<main>
+{{range .Cards}}<figure>
+<img src="{{.Image}}" alt="Card {{.Number}}" loading="lazy">
+<figcaption><h2>Card {{.Number}}</h2><ul>{{range .Symbols}}<li>{{.}}</li>{{end}}</ul></figcaption>
+</figure>
+{{end}}</main>
+</body>
+</html>
+`))
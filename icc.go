@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// sRGB primaries and white point, adapted to the D50 illuminant of the
+// profile connection space.
+var (
+	iccD50   = [3]float64{0.9642, 1.0, 0.8249}
+	iccRed   = [3]float64{0.4360747, 0.2225045, 0.0139322}
+	iccGreen = [3]float64{0.3850649, 0.7168786, 0.0971045}
+	iccBlue  = [3]float64{0.1430804, 0.0606169, 0.7141733}
+)
+
+const srgbCurvePoints = 1024
+
+// srgbProfile builds a minimal ICC v2 display profile for sRGB: the matrix
+// of the primaries and the sRGB tone curve. It is enough to identify the
+// color space of the document for print workflows without shipping a
+// profile file.
+func srgbProfile() []byte {
+	xyz := func(v [3]float64) []byte {
+		b := append([]byte("XYZ "), 0, 0, 0, 0)
+		for _, c := range v {
+			b = binary.BigEndian.AppendUint32(b, uint32(int32(math.Round(c*65536))))
+		}
+		return b
+	}
+
+	curve := append([]byte("curv"), 0, 0, 0, 0)
+	curve = binary.BigEndian.AppendUint32(curve, srgbCurvePoints)
+	for i := 0; i < srgbCurvePoints; i++ {
+		v := srgbToLinear(float64(i) / (srgbCurvePoints - 1))
+		curve = binary.BigEndian.AppendUint16(curve, uint16(math.Round(v*65535)))
+	}
+
+	desc := append([]byte("desc"), 0, 0, 0, 0)
+	name := "sRGB IEC61966-2.1"
+	desc = binary.BigEndian.AppendUint32(desc, uint32(len(name)+1))
+	desc = append(desc, name...)
+	desc = append(desc, 0)
+	desc = append(desc, make([]byte, 4+4+2+1+67)...) // empty Unicode and ScriptCode descriptions
+
+	cprt := append([]byte("text"), 0, 0, 0, 0)
+	cprt = append(cprt, "No copyright, use freely"...)
+	cprt = append(cprt, 0)
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", desc}, {"cprt", cprt}, {"wtpt", xyz(iccD50)},
+		{"rXYZ", xyz(iccRed)}, {"gXYZ", xyz(iccGreen)}, {"bXYZ", xyz(iccBlue)},
+		{"rTRC", curve}, {"gTRC", curve}, {"bTRC", curve},
+	}
+
+	// Tag data follows the header and tag table, 4-byte aligned. Identical
+	// tags, like the three tone curves, share one copy.
+	var data bytes.Buffer
+	offset := 128 + 4 + 12*len(tags)
+	table := binary.BigEndian.AppendUint32(nil, uint32(len(tags)))
+	offsets := make([]int, len(tags))
+	for i, t := range tags {
+		at := -1
+		for j := range tags[:i] {
+			if bytes.Equal(tags[j].data, t.data) {
+				at = offsets[j]
+				break
+			}
+		}
+		if at < 0 {
+			at = offset + data.Len()
+			data.Write(t.data)
+			for data.Len()%4 != 0 {
+				data.WriteByte(0)
+			}
+		}
+		offsets[i] = at
+		table = append(table, t.sig...)
+		table = binary.BigEndian.AppendUint32(table, uint32(at))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(t.data)))
+	}
+
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(offset+data.Len()))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000) // version 2.1
+	copy(header[12:], "mntr")
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	binary.BigEndian.PutUint16(header[24:], 2024) // creation date, fixed for reproducible output
+	binary.BigEndian.PutUint16(header[26:], 1)
+	binary.BigEndian.PutUint16(header[28:], 1)
+	copy(header[36:], "acsp")
+	for i, c := range iccD50 {
+		binary.BigEndian.PutUint32(header[68+4*i:], uint32(int32(math.Round(c*65536))))
+	}
+
+	profile := append(header, table...)
+	return append(profile, data.Bytes()...)
+}
+
+// srgbToLinear applies the sRGB tone curve to a channel value in [0, 1].
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// iccLinearSteps is the resolution of the table mapping linear light back
+// to device values of the profile.
+const iccLinearSteps = 4096
+
+// iccTransform converts sRGB colors to the RGB space of a matrix/TRC ICC
+// profile, the kind display profiles and many RGB printer profiles are.
+// Colors are mapped relative colorimetrically: each white maps to the other,
+// colors outside the gamut are clipped.
+type iccTransform struct {
+	matrix   [3][3]float64            // linear sRGB to linear profile RGB
+	toDevice [3][iccLinearSteps]uint8 // per channel, linear values to device values
+}
+
+// newICCTransform prepares the conversion to profile. Profiles of other
+// kinds, like the CMYK lookup tables of press profiles, are an error.
+func newICCTransform(profile []byte) (*iccTransform, error) {
+	if len(profile) < 132 || string(profile[16:20]) != "RGB " || string(profile[20:24]) != "XYZ " {
+		return nil, errors.New("only RGB profiles with an XYZ connection space can be converted to")
+	}
+	tags, err := iccTags(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	var dst [3][3]float64
+	t := &iccTransform{}
+	for ch, name := range []string{"r", "g", "b"} {
+		xyz, err := iccXYZ(tags[name+"XYZ"])
+		if err != nil {
+			return nil, fmt.Errorf("%sXYZ: %w", name, err)
+		}
+		for i := range xyz {
+			dst[i][ch] = xyz[i]
+		}
+		curve, err := iccCurve(tags[name+"TRC"])
+		if err != nil {
+			return nil, fmt.Errorf("%sTRC: %w", name, err)
+		}
+		t.toDevice[ch] = invertCurve(curve)
+	}
+
+	inv, ok := invert3(dst)
+	if !ok {
+		return nil, errors.New("the primaries of the profile are degenerate")
+	}
+	src := [3][3]float64{
+		{iccRed[0], iccGreen[0], iccBlue[0]},
+		{iccRed[1], iccGreen[1], iccBlue[1]},
+		{iccRed[2], iccGreen[2], iccBlue[2]},
+	}
+	t.matrix = mul3(inv, src)
+	return t, nil
+}
+
+// convertColor converts a single sRGB color, keeping its alpha.
+func (t *iccTransform) convertColor(c color.NRGBA) color.NRGBA {
+	lin := [3]float64{srgbToLinear(float64(c.R) / 255), srgbToLinear(float64(c.G) / 255), srgbToLinear(float64(c.B) / 255)}
+	var out [3]uint8
+	for ch, row := range t.matrix {
+		v := row[0]*lin[0] + row[1]*lin[1] + row[2]*lin[2]
+		i := int(math.Round(min(max(v, 0), 1) * (iccLinearSteps - 1)))
+		out[ch] = t.toDevice[ch][i]
+	}
+	return color.NRGBA{R: out[0], G: out[1], B: out[2], A: c.A}
+}
+
+// convert converts an sRGB image. Every distinct color is converted once,
+// since symbols mostly consist of few colors.
+func (t *iccTransform) convert(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+
+	seen := make(map[[3]uint8][3]uint8)
+	for i := 0; i < len(dst.Pix); i += 4 {
+		if dst.Pix[i+3] == 0 {
+			continue
+		}
+		rgb := [3]uint8{dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2]}
+		out, ok := seen[rgb]
+		if !ok {
+			c := t.convertColor(color.NRGBA{R: rgb[0], G: rgb[1], B: rgb[2]})
+			out = [3]uint8{c.R, c.G, c.B}
+			seen[rgb] = out
+		}
+		copy(dst.Pix[i:i+3], out[:])
+	}
+	return dst
+}
+
+// iccTags returns the data of the tags of an ICC profile by signature.
+func iccTags(profile []byte) (map[string][]byte, error) {
+	count := int(binary.BigEndian.Uint32(profile[128:]))
+	if 132+12*count > len(profile) {
+		return nil, errors.New("truncated tag table")
+	}
+	tags := make(map[string][]byte, count)
+	for i := range count {
+		entry := profile[132+12*i:]
+		offset, size := int(binary.BigEndian.Uint32(entry[4:])), int(binary.BigEndian.Uint32(entry[8:]))
+		if offset < 0 || size < 0 || offset+size > len(profile) {
+			return nil, fmt.Errorf("tag %q lies outside the profile", entry[:4])
+		}
+		tags[string(entry[:4])] = profile[offset : offset+size]
+	}
+	return tags, nil
+}
+
+// iccFixed decodes an s15Fixed16Number.
+func iccFixed(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// iccXYZ decodes an XYZType tag holding a single color.
+func iccXYZ(data []byte) ([3]float64, error) {
+	if len(data) < 20 || string(data[:4]) != "XYZ " {
+		return [3]float64{}, errors.New("missing or not an XYZ tag")
+	}
+	return [3]float64{iccFixed(data[8:]), iccFixed(data[12:]), iccFixed(data[16:])}, nil
+}
+
+// iccCurve decodes a tone curve tag, a curveType table or gamma or a
+// parametricCurveType, as the function from device values to linear light.
+func iccCurve(data []byte) (func(float64) float64, error) {
+	if len(data) < 12 {
+		return nil, errors.New("missing or truncated tone curve")
+	}
+	switch string(data[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(data[8:]))
+		if len(data) < 12+2*n {
+			return nil, errors.New("truncated tone curve")
+		}
+		switch n {
+		case 0:
+			return func(x float64) float64 { return x }, nil
+		case 1:
+			gamma := float64(binary.BigEndian.Uint16(data[12:])) / 256
+			return func(x float64) float64 { return math.Pow(x, gamma) }, nil
+		}
+		table := make([]float64, n)
+		for i := range table {
+			table[i] = float64(binary.BigEndian.Uint16(data[12+2*i:])) / 65535
+		}
+		return func(x float64) float64 {
+			pos := x * float64(n-1)
+			i := min(int(pos), n-2)
+			return table[i] + (table[i+1]-table[i])*(pos-float64(i))
+		}, nil
+
+	case "para":
+		kind := binary.BigEndian.Uint16(data[8:])
+		counts := []int{1, 3, 4, 5, 7}
+		if int(kind) >= len(counts) || len(data) < 12+4*counts[kind] {
+			return nil, fmt.Errorf("unsupported parametric curve type %d", kind)
+		}
+		p := make([]float64, 7)
+		for i := range counts[kind] {
+			p[i] = iccFixed(data[12+4*i:])
+		}
+		g, a, b, c, d, e, f := p[0], p[1], p[2], p[3], p[4], p[5], p[6]
+		switch kind {
+		case 0:
+			return func(x float64) float64 { return math.Pow(x, g) }, nil
+		case 1, 2:
+			return func(x float64) float64 {
+				if a*x+b < 0 {
+					return c
+				}
+				return math.Pow(a*x+b, g) + c
+			}, nil
+		default: // 3 and 4; e and f are zero for 3
+			return func(x float64) float64 {
+				if x < d {
+					return c*x + f
+				}
+				return math.Pow(a*x+b, g) + e
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported tone curve type %q", data[:4])
+}
+
+// invertCurve tabulates the device value whose linear light is closest to
+// every step of linear light, for a rising tone curve.
+func invertCurve(curve func(float64) float64) [iccLinearSteps]uint8 {
+	var levels [256]float64
+	for i := range levels {
+		levels[i] = curve(float64(i) / 255)
+	}
+	var table [iccLinearSteps]uint8
+	for i := range table {
+		v := float64(i) / (iccLinearSteps - 1)
+		d := sort.SearchFloat64s(levels[:], v)
+		if d == len(levels) || d > 0 && v-levels[d-1] < levels[d]-v {
+			d--
+		}
+		table[i] = uint8(d)
+	}
+	return table
+}
+
+func mul3(a, b [3][3]float64) [3][3]float64 {
+	var m [3][3]float64
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				m[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return m
+}
+
+func invert3(m [3][3]float64) ([3][3]float64, bool) {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	if math.Abs(det) < 1e-12 {
+		return [3][3]float64{}, false
+	}
+	var inv [3][3]float64
+	for i := range 3 {
+		for j := range 3 {
+			// The cofactor of m[j][i], from the rows and columns after them.
+			r1, r2 := (j+1)%3, (j+2)%3
+			c1, c2 := (i+1)%3, (i+2)%3
+			inv[i][j] = (m[r1][c1]*m[r2][c2] - m[r1][c2]*m[r2][c1]) / det
+		}
+	}
+	return inv, true
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+)
+
+// brokenImage is a symbol image that cannot be read or decoded.
+type brokenImage struct {
+	Path string
+	Err  error
+}
+
+func (b brokenImage) Error() string {
+	return fmt.Sprintf("%s: %v", b.Path, b.Err)
+}
+
+func (b brokenImage) Unwrap() error { return b.Err }
+
+// findBrokenImages decodes every image and returns all that fail.
+func findBrokenImages(paths []string) []brokenImage {
+	var broken []brokenImage
+	for _, path := range paths {
+		data, err := readSymbolFile(path)
+		if err == nil {
+			_, err = decodeSymbol(path, data)
+		}
+		if err != nil {
+			broken = append(broken, brokenImage{Path: path, Err: err})
+		}
+	}
+	return broken
+}
+
+// checkImages makes sure every image the deck will use can be decoded, so a
+// corrupt file is reported before rendering starts rather than halfway
+// through the PDF. All broken images are reported at once. With
+// SubstituteBroken they are replaced by spare images from the selection, or
+// by numbered placeholders when there are no spares left.
+func (cg *CardGenerator) checkImages() error {
+	if cg.Logo != "" {
+		if broken := findBrokenImages([]string{cg.Logo}); len(broken) > 0 {
+			return fmt.Errorf("the logo cannot be used: %w", broken[0])
+		}
+	}
+
+	required := min(cg.calculateRequiredImages(), len(cg.ImageFiles))
+	broken := findBrokenImages(cg.ImageFiles[:required])
+	if len(broken) == 0 {
+		return nil
+	}
+
+	if !cg.SubstituteBroken {
+		errs := make([]error, len(broken))
+		for i, b := range broken {
+			errs[i] = b
+		}
+		return fmt.Errorf("%d of %d images cannot be used: %w", len(broken), required, errors.Join(errs...))
+	}
+
+	brokenErr := make(map[string]error, len(broken))
+	for _, b := range broken {
+		brokenErr[b.Path] = b.Err
+	}
+
+	// Spare images are those beyond the ones the deck needs; they are only
+	// used once they decode.
+	var spares []string
+	for _, path := range cg.ImageFiles[required:] {
+		if len(spares) == len(broken) {
+			break
+		}
+		if len(findBrokenImages([]string{path})) == 0 {
+			spares = append(spares, path)
+		}
+	}
+	if missing := len(broken) - len(spares); missing > 0 {
+		dir := filepath.Join(cg.GeneratedDir, "substitutes")
+		placeholders, err := generatePlaceholders(dir, missing)
+		if err != nil {
+			return err
+		}
+		spares = append(spares, placeholders...)
+	}
+
+	for i, path := range cg.ImageFiles[:required] {
+		if err, ok := brokenErr[path]; ok {
+			slog.Warn("Substituting unusable image", "path", path, "error", err, "substitute", spares[0])
+			cg.ImageFiles[i], spares = spares[0], spares[1:]
+		}
+	}
+
+	return nil
+}
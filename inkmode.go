@@ -0,0 +1,72 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// outlineThreshold is the edge strength, as a fraction of the strongest
+// possible edge, below which pixels stay blank in ink-saver mode. It keeps
+// textures and JPEG noise from turning into specks.
+const outlineThreshold = 0.12
+
+// applyInkMode converts a rendered symbol for --grayscale or --ink-saver
+// output. Other images are returned unchanged.
+func (o options) applyInkMode(img image.Image) image.Image {
+	switch {
+	case o.inkSaver:
+		return outline(img)
+	case o.grayscale:
+		return imaging.Grayscale(img)
+	}
+	return img
+}
+
+// outline turns img into black line art: a Sobel filter finds the edges of
+// the image as it would print on white paper, so both color changes and the
+// silhouette of transparent images become lines. Everything else is
+// transparent and costs no ink.
+func outline(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// Luminance of the image composited onto white, in 0..1.
+	lum := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			l := (0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)) / 255
+			a := float64(c.A) / 255
+			lum[y*w+x] = l*a + (1 - a)
+		}
+	}
+
+	// Outside the image is paper, so shapes touching the border get closed
+	// outlines.
+	at := func(x, y int) float64 {
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return 1
+		}
+		return lum[y*w+x]
+	}
+
+	// The strongest Sobel response, from black to white, is 4·√2.
+	const maxEdge = 4 * math.Sqrt2
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			gx := at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1)
+			gy := at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1)
+			edge := math.Hypot(gx, gy) / maxEdge
+			if edge < outlineThreshold {
+				continue
+			}
+			alpha := clamp01((edge - outlineThreshold) / (0.5 - outlineThreshold))
+			dst.SetNRGBA(x, y, color.NRGBA{A: uint8(alpha * 255)})
+		}
+	}
+	return dst
+}
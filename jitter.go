@@ -0,0 +1,62 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+// jitterKinds lists the values accepted by --jitter.
+var jitterKinds = []string{"hue", "brightness", "mirror"}
+
+// Largest shifts at a --jitter-strength of 1.
+const (
+	maxHueJitter        = 90.0 // degrees
+	maxBrightnessJitter = 50.0 // percent
+)
+
+// jitterer varies the look of every symbol occurrence slightly, like the
+// hand-drawn variations of printed decks. It draws from its own generator,
+// so jitter leaves the layout of the deck unchanged.
+type jitterer struct {
+	rng      *rand.Rand
+	kinds    []string
+	strength float64
+}
+
+func newJitterer(kinds []string, strength float64, seed int64) *jitterer {
+	return &jitterer{rng: rand.New(rand.NewSource(seed + 1)), kinds: kinds, strength: strength}
+}
+
+// jitter adds random shifts of the enabled kinds to look.
+func (j *jitterer) jitter(look *SymbolLook) {
+	for _, kind := range j.kinds {
+		switch kind {
+		case "hue":
+			look.Hue = math.Round((j.rng.Float64()*2-1)*j.strength*maxHueJitter*10) / 10
+		case "brightness":
+			look.Brightness = math.Round((j.rng.Float64()*2-1)*j.strength*maxBrightnessJitter*10) / 10
+		case "mirror":
+			look.Mirror = j.rng.Intn(2) == 1
+		}
+	}
+}
+
+// adjust shifts the hue and brightness of a color. The hue is rotated
+// around the gray axis, so black, white and grays stay as they are.
+func (l SymbolLook) adjust(c color.NRGBA) color.NRGBA {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	if l.Hue != 0 {
+		cos, sin := math.Cos(l.Hue*math.Pi/180), math.Sin(l.Hue*math.Pi/180)
+		k := (1 - cos) / 3
+		s := math.Sqrt(1.0/3) * sin
+		r, g, b = r*(cos+k)+g*(k-s)+b*(k+s),
+			r*(k+s)+g*(cos+k)+b*(k-s),
+			r*(k-s)+g*(k+s)+b*(cos+k)
+	}
+	shift := l.Brightness / 100 * 255
+	channel := func(v float64) uint8 {
+		return uint8(math.Max(0, math.Min(255, v+shift)) + 0.5)
+	}
+	return color.NRGBA{R: channel(r), G: channel(g), B: channel(b), A: c.A}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	defaultLabelSize = 7.0       // font size of symbol labels in pt
+	labelLineHeight  = 1.3       // height of the label band relative to the font size
+	mmPerPt          = 25.4 / 72 // font sizes are given in points
+	labelFontFamily  = "label"
+)
+
+// symbolLabel returns the display name of a symbol file: its name from the
+// symbol list, or the file name without extension.
+func symbolLabel(names map[string]string, file string) string {
+	if name := names[file]; name != "" {
+		return name
+	}
+	base := filepath.Base(file)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// labelSplit divides the box of a labeled symbol into the image above and
+// the label band below, in mm, with room for one line per label language.
+// The band never takes more than a third of the box.
+func (o options) labelSplit(size float64) (imgSize, labelHeight float64) {
+	lines := float64(max(len(o.labelLangs), 1))
+	labelHeight = math.Min(lines*o.labelSize*mmPerPt*labelLineHeight, size/3)
+	return size - labelHeight, labelHeight
+}
+
+// labelFont returns the TrueType data of the label font: the file given
+// with --label-font, or the bundled Go font.
+func (o options) labelFont() ([]byte, error) {
+	if o.labelFontFile == "" {
+		return goregular.TTF, nil
+	}
+	data, err := os.ReadFile(o.labelFontFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read label font: %w", err)
+	}
+	return data, nil
+}
+
+// addLabelFont registers the label font with the PDF. Labels come from file
+// names and symbol lists, so a Unicode font is used instead of the core
+// fonts.
+func (r *renderer) addLabelFont() error {
+	data, err := r.opts.labelFont()
+	if err != nil {
+		return err
+	}
+	r.pdf.AddUTF8FontFromBytes(labelFontFamily, "", data)
+	if err := r.pdf.Error(); err != nil {
+		return fmt.Errorf("failed to load label font: %w", err)
+	}
+	return nil
+}
+
+// drawLabeledSymbol draws the symbol upright with its label below and
+// rotates both together, so the label reads along with the symbol.
+func (r *renderer) drawLabeledSymbol(x, y float64, s ManifestSymbol) error {
+	imgSize, labelHeight := r.opts.labelSplit(s.Size)
+	x, y = x+s.X, y+s.Y
+
+	r.pdf.TransformBegin()
+	defer r.pdf.TransformEnd()
+	r.pdf.TransformRotate(float64(s.Rotation), x+s.Size/2, y+s.Size/2)
+
+	if err := r.processImage(s.File, x+(s.Size-imgSize)/2, y, imgSize, 0, s.SymbolLook); err != nil {
+		return err
+	}
+
+	// Labels too long for the box are set in a smaller size.
+	lines := r.opts.labelLines(s.File)
+	lineHeight := labelHeight / float64(len(lines))
+	r.pdf.SetTextColor(0, 0, 0)
+	for i, label := range lines {
+		size := math.Min(r.opts.labelSize, lineHeight/labelLineHeight/mmPerPt)
+		r.pdf.SetFont(labelFontFamily, "", size)
+		if w := r.pdf.GetStringWidth(label); w > s.Size {
+			size *= s.Size / w
+			r.pdf.SetFontSize(size)
+		}
+		baseline := y + imgSize + float64(i)*lineHeight + (lineHeight+size*mmPerPt*0.7)/2
+		r.pdf.Text(x+(s.Size-r.pdf.GetStringWidth(label))/2, baseline, label)
+	}
+	return nil
+}
+
+// rasterLabeledSymbol draws the symbol upright at sizePx with its label in
+// the band below and rotates both together, like drawLabeledSymbol.
+func rasterLabeledSymbol(src *symbolSource, s ManifestSymbol, opts options, face font.Face, dpi float64) image.Image {
+	imgSize, labelHeight := opts.labelSplit(s.Size)
+	size, imgPx, labelPx := mmToPx(s.Size, dpi), mmToPx(imgSize, dpi), mmToPx(labelHeight, dpi)
+	unit := image.NewNRGBA(image.Rect(0, 0, size, size))
+
+	sym := s.apply(src.render(imgPx, 0, opts.resampleFilter()), src)
+	sym = opts.applyInkMode(opts.applyAccessibility(sym, s.SymbolLook, src))
+	if s.Mirror {
+		sym = imaging.FlipH(sym)
+	}
+	sb := sym.Bounds()
+	at := image.Pt((size-sb.Dx())/2, (imgPx-sb.Dy())/2)
+	draw.Draw(unit, sb.Sub(sb.Min).Add(at), sym, sb.Min, draw.Over)
+
+	lines := opts.labelLines(s.File)
+	linePx := labelPx / len(lines)
+	for i, text := range lines {
+		label := renderLabel(face, text, linePx)
+		if lb := label.Bounds(); lb.Dx() > size || lb.Dy() > linePx {
+			label = imaging.Fit(label, size, linePx, imaging.Lanczos)
+		}
+		lb := label.Bounds()
+		at = image.Pt((size-lb.Dx())/2, size-labelPx+i*linePx+(linePx-lb.Dy())/2)
+		draw.Draw(unit, lb.Sub(lb.Min).Add(at), label, lb.Min, draw.Over)
+	}
+
+	return imaging.Rotate(unit, float64(s.Rotation), color.Transparent)
+}
+
+// renderLabel draws text in black on a transparent band as wide as the text
+// and heightPx high, or as high as the font needs if that is more.
+func renderLabel(face font.Face, text string, heightPx int) *image.NRGBA {
+	m := face.Metrics()
+	heightPx = max(heightPx, (m.Ascent + m.Descent).Ceil(), 1)
+	width := max(font.MeasureString(face, text).Ceil(), 1)
+	img := image.NewNRGBA(image.Rect(0, 0, width, heightPx))
+
+	baseline := (fixed.I(heightPx) + m.Ascent - m.Descent) / 2
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.Black,
+		Face: face,
+		Dot:  fixed.Point26_6{Y: baseline},
+	}
+	d.DrawString(text)
+	return img
+}
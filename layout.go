@@ -0,0 +1,494 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+)
+
+const (
+	cardPadding     = 5.0  // default minimum distance between a symbol and the card edge
+	layoutDensity   = 0.5  // share of the usable card area the first attempt tries to fill
+	layoutAttempts  = 200  // random positions tried per symbol before shrinking
+	layoutShrinkBy  = 0.95 // factor applied to the symbol size after a failed pass
+	layoutMaxPasses = 100
+	layoutMinGrid   = 0.75 // smallest random-layout size, relative to the grid fallback
+	layoutEpsilon   = 1e-9 // tolerance for boxes touching the card edge
+	jitterFill      = 0.85 // symbol size relative to its cell in a jittered grid
+	layoutMinCell   = 0.25 // smallest symbol size relative to its grid cell, whatever the spacing
+)
+
+// placement is the box a symbol is drawn into, relative to the card's
+// top-left corner. Symbols are only rotated in 90° steps, so the box stays
+// axis-aligned.
+type placement struct {
+	X, Y, Size float64
+}
+
+// cardShape describes the printable outline of a card and how densely
+// symbols may be packed on it.
+type cardShape struct {
+	Round   bool
+	Hex     bool    // pointy-top hexagon filling the card box
+	Corner  float64 // corner radius of rectangular cards
+	Width   float64
+	Height  float64
+	Padding float64 // minimum distance between a symbol and the card edge
+	Spacing float64 // minimum distance between two symbols
+	Overlap float64 // share of the smaller box two symbols may overlap by, 0 to 1
+
+	// Keepout is an area left free of symbols, the QR code of --qr-codes
+	// front. There is none if its Size is 0.
+	Keepout placement
+}
+
+// contains reports whether the box lies completely inside the card, keeping
+// Padding distance from the edge. Cards are convex, so checking the corners
+// of the box is enough.
+func (c cardShape) contains(p placement) bool {
+	if c.blocked(p.X, p.Y, p.Size, p.Size) {
+		return false
+	}
+	for _, corner := range [][2]float64{
+		{p.X, p.Y}, {p.X + p.Size, p.Y}, {p.X, p.Y + p.Size}, {p.X + p.Size, p.Y + p.Size},
+	} {
+		if c.distance(corner[0], corner[1]) > -c.Padding+layoutEpsilon {
+			return false
+		}
+	}
+	return true
+}
+
+// blocked reports whether the w×h rectangle at (x, y) comes closer than
+// Spacing to the Keepout area.
+func (c cardShape) blocked(x, y, w, h float64) bool {
+	k, gap := c.Keepout, c.Spacing/2
+	return k.Size > 0 &&
+		x < k.X+k.Size+gap && k.X-gap < x+w &&
+		y < k.Y+k.Size+gap && k.Y-gap < y+h
+}
+
+// usableArea returns the area inside the padding. For hexagonal cards it is
+// approximate.
+func (c cardShape) usableArea() float64 {
+	w, h := c.Width-2*c.Padding, c.Height-2*c.Padding
+	switch {
+	case c.Round:
+		r := math.Min(c.Width, c.Height)/2 - c.Padding
+		return math.Pi * r * r
+	case c.Hex:
+		return w * h * 3 / 4
+	default:
+		r := math.Max(c.Corner-c.Padding, 0)
+		return w*h - (4-math.Pi)*r*r
+	}
+}
+
+// collides reports whether two boxes are closer than Spacing or overlap by
+// more than the Overlap tolerance.
+func (c cardShape) collides(p, o placement) bool {
+	gap := c.Spacing / 2
+	w := math.Min(p.X+p.Size, o.X+o.Size) - math.Max(p.X, o.X) + 2*gap
+	h := math.Min(p.Y+p.Size, o.Y+o.Size) - math.Max(p.Y, o.Y) + 2*gap
+	if w <= layoutEpsilon || h <= layoutEpsilon {
+		return false
+	}
+	if c.Overlap <= 0 {
+		return true
+	}
+	smaller := math.Min(p.Size, o.Size)
+	return w*h > c.Overlap*smaller*smaller
+}
+
+// collidesAny reports whether p collides with any of the other boxes.
+func (c cardShape) collidesAny(p placement, others []placement) bool {
+	for _, o := range others {
+		if c.collides(p, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// Layout is a strategy for arranging the symbols of a card. Place returns
+// count non-overlapping boxes inside the card, or nil if the strategy cannot
+// arrange that many symbols on this card. Boxes may differ in size, as with
+// anchoredLayout, whose first box is larger than the others; planSymbols
+// scales each symbol relative to its own box.
+type Layout interface {
+	Place(rng *rand.Rand, shape cardShape, count int) []placement
+}
+
+// layoutFunc adapts a plain function to the Layout interface.
+type layoutFunc func(rng *rand.Rand, shape cardShape, count int) []placement
+
+func (f layoutFunc) Place(rng *rand.Rand, shape cardShape, count int) []placement {
+	return f(rng, shape, count)
+}
+
+// layouts holds the strategies selectable with --layout.
+var layouts = map[string]Layout{
+	"auto":    layoutFunc(layoutSymbols),
+	"packed":  layoutFunc(jitterLayout),
+	"chaotic": layoutFunc(chaoticLayout),
+	"grid": layoutFunc(func(_ *rand.Rand, shape cardShape, count int) []placement {
+		return gridLayout(shape, count)
+	}),
+}
+
+// registerLayout makes a strategy selectable by name. It is meant to be
+// called from init functions, so a new layout only needs its own file.
+func registerLayout(name string, l Layout) {
+	if _, ok := layouts[name]; ok {
+		panic("layout registered twice: " + name)
+	}
+	layouts[name] = l
+}
+
+// layoutNames returns the registered layout names in alphabetical order.
+func layoutNames() []string {
+	names := make([]string, 0, len(layouts))
+	for name := range layouts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// layoutSymbols is the default layout. It places count equally sized symbols
+// on the card so that no two boxes overlap and all of them stay inside the
+// card. Rectangular cards use a jittered grid, which spreads the symbols over
+// the whole card. On round cards random positions are tried first, shrinking
+// the symbols after every failed pass; if the random search only succeeds
+// with symbols much smaller than a plain grid would allow, the grid is used
+// instead, which always fits.
+func layoutSymbols(rng *rand.Rand, shape cardShape, count int) []placement {
+	if count == 0 {
+		return nil
+	}
+	if !shape.Round {
+		return jitterLayout(rng, shape, count)
+	}
+	return chaoticLayout(rng, shape, count)
+}
+
+// chaoticLayout scatters the symbols at random positions, shrinking them
+// until they fit, with the grid as the fallback.
+func chaoticLayout(rng *rand.Rand, shape cardShape, count int) []placement {
+	if count == 0 {
+		return nil
+	}
+
+	grid := gridLayout(shape, count)
+	size := math.Sqrt(shape.usableArea() * layoutDensity / float64(count))
+
+	for pass := 0; pass < layoutMaxPasses && size >= grid[0].Size*layoutMinGrid; pass++ {
+		if placements, ok := randomLayout(rng, shape, count, size); ok {
+			return placements
+		}
+		size *= layoutShrinkBy
+	}
+
+	return grid
+}
+
+func randomLayout(rng *rand.Rand, shape cardShape, count int, size float64) ([]placement, bool) {
+	placements := make([]placement, 0, count)
+
+	for len(placements) < count {
+		placed := false
+		for attempt := 0; attempt < layoutAttempts && !placed; attempt++ {
+			candidate := placement{
+				X:    rng.Float64() * (shape.Width - size),
+				Y:    rng.Float64() * (shape.Height - size),
+				Size: size,
+			}
+			if !shape.contains(candidate) || shape.collidesAny(candidate, placements) {
+				continue
+			}
+			placements = append(placements, candidate)
+			placed = true
+		}
+		if !placed {
+			return nil, false
+		}
+	}
+
+	return placements, true
+}
+
+// jitterLayout divides the usable area of the card into a grid
+// with at least count cells, stretched to cover the whole area, and puts
+// each symbol at a random position inside its own randomly chosen cell.
+// Symbols are smaller than their cells by jitterFill, which leaves the room
+// for the jitter; cells left over stay empty, so the gaps move around too.
+func jitterLayout(rng *rand.Rand, shape cardShape, count int) []placement {
+	if count == 0 {
+		return nil
+	}
+	left, top, width, height := gridArea(shape)
+
+	// Cells touching the keepout stay empty, so the grid grows until
+	// enough others are left.
+	var cols, rows int
+	var bestSize, cellW, cellH float64
+	var free []int
+	for n := count; len(free) < count; n++ {
+		cols, bestSize = bestGrid(width, height, n)
+		rows = (n + cols - 1) / cols
+		cellW, cellH = width/float64(cols), height/float64(rows)
+		free = free[:0]
+		for cell := range cols * rows {
+			if !shape.blocked(left+float64(cell%cols)*cellW, top+float64(cell/cols)*cellH, cellW, cellH) {
+				free = append(free, cell)
+			}
+		}
+	}
+	size := math.Max(math.Min(bestSize*jitterFill, bestSize-shape.Spacing), bestSize*layoutMinCell)
+	gap := math.Min(shape.Spacing, bestSize-size) / 2 // kept free on each side of a cell
+
+	placements := make([]placement, count)
+	for i, j := range rng.Perm(len(free))[:count] {
+		cell := free[j]
+		placements[i] = placement{
+			X:    left + float64(cell%cols)*cellW + gap + rng.Float64()*math.Max(cellW-size-2*gap, 0),
+			Y:    top + float64(cell/cols)*cellH + gap + rng.Float64()*math.Max(cellH-size-2*gap, 0),
+			Size: size,
+		}
+	}
+	return placements
+}
+
+// gridLayout arranges the symbols in the largest grid that fits the usable
+// area. Round cards use the square inscribed in the padded circle.
+func gridLayout(shape cardShape, count int) []placement {
+	left, top, width, height := gridArea(shape)
+
+	// Cells touching the keepout are skipped, with the grid grown until
+	// enough others are left.
+	var placements []placement
+	for n := count; len(placements) < count; n++ {
+		bestCols, bestSize := bestGrid(width, height, n)
+		rows := (n + bestCols - 1) / bestCols
+		offsetX := left + (width-float64(bestCols)*bestSize)/2
+		offsetY := top + (height-float64(rows)*bestSize)/2
+
+		// Symbols are centered in their cells, leaving Spacing between them.
+		size := math.Max(bestSize-shape.Spacing, bestSize*layoutMinCell)
+		inset := (bestSize - size) / 2
+		placements = placements[:0]
+		for i := 0; i < bestCols*rows && len(placements) < count; i++ {
+			x, y := offsetX+float64(i%bestCols)*bestSize, offsetY+float64(i/bestCols)*bestSize
+			if !shape.blocked(x, y, bestSize, bestSize) {
+				placements = append(placements, placement{X: x + inset, Y: y + inset, Size: size})
+			}
+		}
+	}
+	return placements
+}
+
+// gridArea returns the rectangle grids are laid out in: the largest
+// rectangle inside the padding that stays clear of round corners, or on
+// round cards the square inscribed in the padded circle. Hexagonal cards use
+// the largest rectangle inside the padded hexagon.
+func gridArea(shape cardShape) (left, top, width, height float64) {
+	switch {
+	case shape.Round:
+		radius := math.Min(shape.Width, shape.Height)/2 - shape.Padding
+		width = radius * math.Sqrt2
+		return shape.Width/2 - width/2, shape.Height/2 - width/2, width, width
+	case shape.Hex:
+		// The slanted edges satisfy k·|x| + |y| = H around the center.
+		halfW, halfH := shape.Width/2, shape.Height/2
+		k := halfH / (2 * halfW)
+		limit := halfH - shape.Padding*math.Hypot(1, k)
+		a := math.Min(halfW-shape.Padding, limit/(2*k))
+		b := limit - k*a
+		return halfW - a, halfH - b, 2 * a, 2 * b
+	default:
+		inset := shape.Padding + math.Max(shape.Corner-shape.Padding, 0)*(1-1/math.Sqrt2)
+		return inset, inset, shape.Width - 2*inset, shape.Height - 2*inset
+	}
+}
+
+// bestGrid returns the number of columns of the grid with the largest square
+// cells that holds count cells in a width×height area, and the cell size.
+func bestGrid(width, height float64, count int) (int, float64) {
+	bestCols, bestSize := 1, 0.0
+	for cols := 1; cols <= count; cols++ {
+		rows := (count + cols - 1) / cols
+		size := math.Min(width/float64(cols), height/float64(rows))
+		if size > bestSize {
+			bestCols, bestSize = cols, size
+		}
+	}
+	return bestCols, bestSize
+}
+
+// planDeck lays out every card: it positions the symbols and picks a size
+// tier and random rotation for each. The result is recorded in a manifest that all
+// output formats render from.
+func planDeck(cards [][]string, opts options, rng *rand.Rand) *Manifest {
+	w, h := opts.cardSize()
+	shape := opts.layoutShape()
+
+	m := &Manifest{
+		Seed:           opts.seed,
+		DeckID:         opts.deckID,
+		SymbolsPerCard: opts.imagesPerCard,
+		RoundCards:     opts.roundCards,
+		HexCards:       opts.hexCards,
+		CornerRadius:   opts.cornerRadius,
+		CardWidth:      w,
+		CardHeight:     h,
+		Bleed:          opts.bleed,
+		PageSize:       opts.pageSize,
+		Orientation:    opts.orientation,
+		Output:         opts.output,
+		SymbolNames:    opts.symbolNames,
+		Cards:          make([]ManifestCard, len(cards)),
+	}
+
+	if opts.lambda > 1 {
+		m.Lambda = opts.lambda
+	}
+	m.Logo = opts.logo
+
+	tiers := &tierAssigner{rng: rng, next: make(map[string]int), min: opts.minSymbolScale}
+	tints := newTintAssigner(opts.tintMode, opts.tintPalette, opts.seed)
+	jitter := newJitterer(opts.jitter, opts.jitterStrength, opts.seed)
+	anchorScale := 0.0
+	if opts.anchor {
+		anchorScale = opts.anchorScale
+	}
+	for i, card := range cards {
+		if opts.anchor {
+			anchor := rng.Intn(len(card))
+			if opts.logo != "" {
+				anchor = slices.Index(card, opts.logo)
+			}
+			card = anchorFirst(card, anchor)
+		}
+		m.Cards[i] = ManifestCard{
+			Index:      i,
+			Symbols:    card,
+			Placements: planSymbols(rng, shape, card, tiers, layouts[opts.layout], opts.layoutAttempts, opts.constraints, anchorScale),
+		}
+		for j := range m.Cards[i].Placements {
+			s := &m.Cards[i].Placements[j]
+			s.Tint = opts.constraints.tint(s.File, tints.tint(s.File))
+			jitter.jitter(&s.SymbolLook)
+		}
+	}
+
+	return m
+}
+
+// sizeTiers are the sizes a symbol is drawn at, relative to the box the
+// layout reserved for it: large, medium and small.
+var sizeTiers = []float64{1.0, 0.8, 0.6}
+
+// tierAssigner hands out size tiers per symbol occurrence. Each symbol
+// starts at a random tier and then cycles through all of them, so a symbol
+// appearing on three or more cards is seen at every size, like in the
+// original game.
+type tierAssigner struct {
+	rng  *rand.Rand
+	next map[string]int
+	min  float64 // smaller tiers are raised to this scale
+}
+
+// tierQuota returns how many symbols of a card of n get each tier: at least
+// one large, some small once there are three, and the rest medium, like the
+// spread of sizes on official cards.
+func tierQuota(n int) [3]int {
+	large, small := max(1, n/4), n/4
+	if n >= 3 {
+		small = max(1, small)
+	}
+	return [3]int{large, n - large - small, small}
+}
+
+// cardScales picks the scales of the symbols of a card. Each symbol asks
+// for its next tier, but every card gets the mix of tierQuota, so symbols
+// whose tier is used up on the card take the closest tier left and continue
+// their cycle from there.
+func (t *tierAssigner) cardScales(card []string) []float64 {
+	quota := tierQuota(len(card))
+	tiers := make([]int, len(card))
+	var waiting []int
+	for i, file := range card {
+		tier, ok := t.next[file]
+		if !ok {
+			tier = t.rng.Intn(len(sizeTiers))
+		}
+		tiers[i] = tier % len(sizeTiers)
+		if quota[tiers[i]] > 0 {
+			quota[tiers[i]]--
+		} else {
+			waiting = append(waiting, i)
+		}
+	}
+	dist := func(a, b int) int { return max(a-b, b-a) }
+	for _, i := range waiting {
+		best := -1
+		for tier, left := range quota {
+			if left > 0 && (best < 0 || dist(tier, tiers[i]) < dist(best, tiers[i])) {
+				best = tier
+			}
+		}
+		quota[best]--
+		tiers[i] = best
+	}
+
+	scales := make([]float64, len(card))
+	for i, file := range card {
+		t.next[file] = tiers[i] + 1
+		scales[i] = max(sizeTiers[tiers[i]], t.min)
+	}
+	return scales
+}
+
+// planSymbols arranges the symbols of a card with the best of attempts
+// candidates from the given layout, falling back to the default layout if it
+// cannot place them. With an anchorScale above 0, the first symbol is put at
+// the center at full size instead. Constraints override the random rotation
+// and size.
+func planSymbols(rng *rand.Rand, shape cardShape, card []string, tiers *tierAssigner, l Layout, attempts int, constraints symbolConstraints, anchorScale float64) []ManifestSymbol {
+	var layout []placement
+	switch {
+	case anchorScale > 0:
+		layout = anchoredLayout(rng, shape, len(card), anchorScale)
+	case l != nil:
+		layout = bestLayout(rng, shape, l, len(card), attempts)
+	}
+	anchored := anchorScale > 0 && len(layout) == len(card)
+	if len(layout) != len(card) {
+		layout = layoutSymbols(rng, shape, len(card))
+	}
+	symbols := make([]ManifestSymbol, len(layout))
+	var scales []float64
+	if anchored {
+		scales = append([]float64{1}, tiers.cardScales(card[1:])...)
+	} else {
+		scales = tiers.cardScales(card)
+	}
+
+	for i, p := range layout {
+		// Random values are drawn for constrained symbols too, so adding a
+		// constraint leaves the rest of the deck unchanged.
+		rotation, scale := constraints.apply(card[i], rng.Intn(4)*90, scales[i])
+		imgSize := p.Size * scale
+
+		// Keep the shrunken symbol centered in the box reserved by the layout.
+		symbols[i] = ManifestSymbol{
+			File:     card[i],
+			X:        p.X + (p.Size-imgSize)/2,
+			Y:        p.Y + (p.Size-imgSize)/2,
+			Size:     imgSize,
+			Rotation: rotation,
+		}
+	}
+
+	return symbols
+}
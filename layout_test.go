@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestLayouts(t *testing.T) {
+	shapes := map[string]cardShape{
+		"rect":    {Width: 55, Height: 85, Padding: cardPadding},
+		"rounded": {Width: 55, Height: 85, Corner: 8, Padding: cardPadding, Spacing: 1},
+		"round":   {Round: true, Width: 80, Height: 80, Padding: cardPadding},
+		"hex":     {Hex: true, Width: 70, Height: 80, Padding: cardPadding, Overlap: 0.1},
+		"keepout": {Width: 55, Height: 85, Padding: cardPadding, Keepout: placement{X: 40, Y: 70, Size: 10}},
+	}
+
+	for _, name := range layoutNames() {
+		for shapeName, shape := range shapes {
+			for _, count := range []int{3, 6, 8, 12} {
+				rng := rand.New(rand.NewSource(1))
+				got := layouts[name].Place(rng, shape, count)
+				if got == nil && name != "auto" && name != "grid" {
+					continue // the strategy cannot arrange that many here
+				}
+				if len(got) != count {
+					t.Errorf("%s on %s: %d of %d symbols placed", name, shapeName, len(got), count)
+					continue
+				}
+				for i, p := range got {
+					if p.Size <= 0 || !shape.contains(p) {
+						t.Errorf("%s on %s with %d symbols: box %d %+v not on the card", name, shapeName, count, i, p)
+					}
+					if shape.collidesAny(p, got[i+1:]) {
+						t.Errorf("%s on %s with %d symbols: box %d %+v collides", name, shapeName, count, i, p)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLayoutsReproducible(t *testing.T) {
+	shape := cardShape{Round: true, Width: 80, Height: 80, Padding: cardPadding}
+	for _, name := range layoutNames() {
+		a := layouts[name].Place(rand.New(rand.NewSource(7)), shape, 8)
+		b := layouts[name].Place(rand.New(rand.NewSource(7)), shape, 8)
+		if len(a) != len(b) {
+			t.Fatalf("%s placed %d and %d symbols with the same seed", name, len(a), len(b))
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				t.Errorf("%s placed box %d at %+v and %+v with the same seed", name, i, a[i], b[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Penalties used by scoreLayout. Overlaps and symbols sticking out of the
+// card make a card unusable, so they outweigh any gain in symbol size.
+const (
+	overlapPenalty = 10.0 // per card area of overlap
+	edgePenalty    = 1.0  // per symbol outside the card
+	balancePenalty = 0.5  // per card radius of offset of the symbols' centroid
+)
+
+// scoreLayout rates a layout; higher is better. It rewards the share of the
+// card covered by symbols and penalizes overlap area, symbols crossing the
+// card edge and lopsided cards whose symbols bunch up on one side.
+func scoreLayout(shape cardShape, placements []placement) float64 {
+	if len(placements) == 0 {
+		return math.Inf(-1)
+	}
+
+	area := shape.usableArea()
+	var covered, overlap, cx, cy float64
+	var outside int
+	for i, p := range placements {
+		a := p.Size * p.Size
+		covered += a
+		cx += (p.X + p.Size/2) * a
+		cy += (p.Y + p.Size/2) * a
+		if !shape.contains(p) {
+			outside++
+		}
+		for _, o := range placements[:i] {
+			w := math.Min(p.X+p.Size, o.X+o.Size) - math.Max(p.X, o.X)
+			h := math.Min(p.Y+p.Size, o.Y+o.Size) - math.Max(p.Y, o.Y)
+			if w > 0 && h > 0 {
+				overlap += w * h
+			}
+		}
+	}
+
+	// Distance of the area-weighted centroid from the card center, relative
+	// to half the smaller card side.
+	cx, cy = cx/covered, cy/covered
+	offset := math.Hypot(cx-shape.Width/2, cy-shape.Height/2) / (math.Min(shape.Width, shape.Height) / 2)
+
+	return covered/area -
+		overlapPenalty*overlap/area -
+		edgePenalty*float64(outside) -
+		balancePenalty*offset
+}
+
+// bestLayout asks the layout for attempts candidates and returns the one
+// with the best score. Candidates with the wrong number of symbols are
+// ignored; if there is none, it returns nil.
+func bestLayout(rng *rand.Rand, shape cardShape, l Layout, count, attempts int) []placement {
+	var best []placement
+	bestScore := math.Inf(-1)
+	for i := 0; i < max(attempts, 1); i++ {
+		candidate := l.Place(rng, shape, count)
+		if len(candidate) != count {
+			continue
+		}
+		if score := scoreLayout(shape, candidate); best == nil || score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logConfig holds the logging flags shared by all commands.
+type logConfig struct {
+	level   string
+	format  string
+	quiet   bool
+	verbose bool
+	stderr  bool // keep stdout free for machine-readable output
+}
+
+func (c *logConfig) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.level, "log-level", "info", "minimum log level: debug, info, warn or error")
+	fs.StringVar(&c.format, "log-format", "text", "log format: text or json")
+	fs.BoolVar(&c.quiet, "quiet", false, "only log errors (to stderr) and print the paths of the outputs")
+	fs.BoolVar(&c.verbose, "verbose", false, "log debug messages, same as --log-level debug")
+}
+
+// apply installs the configured logger as the default. It leaves the current
+// logger alone if none of the logging flags were given and stdout may be
+// logged to, so a batch job keeps the settings of the batch run.
+func (c logConfig) apply(fs *flag.FlagSet) error {
+	set := c.stderr
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "log-level", "log-format", "quiet", "verbose":
+			set = true
+		}
+	})
+	if !set {
+		return nil
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.level)); err != nil {
+		return fmt.Errorf("invalid log level %q", c.level)
+	}
+	var out io.Writer = os.Stdout
+	if c.stderr {
+		out = os.Stderr
+	}
+	switch {
+	case c.quiet:
+		level = slog.LevelError
+		out = os.Stderr
+	case c.verbose:
+		level = slog.LevelDebug
+	}
+
+	logger, err := newLogger(out, level, c.format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+func newLogger(w io.Writer, level slog.Level, format string) (*slog.Logger, error) {
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(format) {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, handlerOpts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, handlerOpts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q (want text or json)", format)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import "path/filepath"
+
+// logoSymbols returns the number of symbols per card that are not drawn
+// from the images: 1 for the --logo, else 0.
+func (o options) logoSymbols() int {
+	if o.logo != "" {
+		return 1
+	}
+	return 0
+}
+
+// logoSymbols is the counterpart of options.logoSymbols for the generator.
+func (cg *CardGenerator) logoSymbols() int {
+	if cg.Logo != "" {
+		return 1
+	}
+	return 0
+}
+
+// deckSymbols returns the symbols per card the deck is constructed with,
+// which leaves out the logo.
+func (o options) deckSymbols() int {
+	return o.imagesPerCard - o.logoSymbols()
+}
+
+// logoHint explains in errors about the symbols per card that the logo
+// takes one of them.
+func (o options) logoHint() string {
+	if o.logo == "" {
+		return ""
+	}
+	return "; the --logo takes one more symbol per card"
+}
+
+// sameFile reports whether two paths name the same file, so the logo is
+// not also used as a symbol when it lies in the image directory.
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
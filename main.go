@@ -1,325 +1,1448 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
-	"image/png"
+	"image/draw"
+	_ "image/jpeg"
 	"log/slog"
+	"maps"
 	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/huh"
-	"github.com/disintegration/imaging"
-	"github.com/go-pdf/fpdf"
+	_ "golang.org/x/image/webp"
 )
 
 const (
 	imgDir         = "./img"
-	cardWidth      = 55.0
+	cardWidth      = 55.0 // default card size in mm
 	cardHeight     = 85.0
-	margin         = 5.0
-	dpiScale       = 3.779528 // 96 DPI
+	defaultMargin  = 5.0 // page margin and gutter between cards in mm
+	imageDPI       = 96  // default resolution of raster symbols in the PDF
 	outputFileName = "dobble_cards.pdf"
-	minScaleFactor = 0.7
-	maxScaleFactor = 1.0
 )
 
+// supportedImageExts lists the file extensions picked up from the image
+// directory. Matching is case-insensitive.
+var supportedImageExts = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".svg":  true,
+	".webp": true,
+	".avif": true, // decoded with an external converter, see avif.go
+}
+
 type CardGenerator struct {
-	TotalCards    int
-	ImagesPerCard int
-	ImageFiles    []string
-	RoundCards    bool
+	TotalCards       int
+	ImagesPerCard    int
+	ImageFiles       []string
+	RoundCards       bool
+	ImgDir           string
+	Filter           imageFilter
+	FillSymbols      bool   // generate symbols when there are too few images
+	Placeholders     bool   // use numbered placeholders instead of images
+	KeepOrder        bool   // assign ImageFiles in order instead of shuffling
+	SubstituteBroken bool   // replace undecodable images instead of failing
+	GeneratedDir     string // where generated symbols are written
+	Partial          bool   // leave out cards with missing images instead of failing
+	Rand             *rand.Rand
+
+	// Design is the deck construction for --lambda above 1; nil builds the
+	// projective plane where cards share one symbol.
+	Design *blockDesign
+	// Logo is added to every card once the deck is built. ImagesPerCard
+	// does not count it.
+	Logo string
 }
 
-func main() {
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	slog.SetDefault(logger)
+type options struct {
+	interactive      bool
+	log              logConfig
+	deck             string // deck preset, pre-fills the interactive form
+	profile          string // saved settings the flags start from, pre-fills the form
+	saveProfile      string
+	settings         []string // effective flags, saved after a successful run
+	totalCards       int
+	imagesPerCard    int
+	lambda           int // symbols every two cards share
+	partial          bool
+	roundCards       bool
+	hexCards         bool
+	cornerRadius     float64
+	layout           string
+	layoutAttempts   int
+	anchor           bool
+	anchorScale      float64
+	logo             string
+	padding          float64
+	spacing          float64
+	overlap          float64
+	imgDir           string
+	filter           imageFilter
+	output           string
+	svgRaster        bool
+	svgDPI           float64
+	imageDPI         float64
+	jpegQuality      int
+	jobs             int
+	resample         string
+	bench            bool
+	pprofDir         string
+	tempDir          string
+	keepTemp         bool
+	watch            bool
+	watchAddr        string
+	seed             int64
+	manifest         string
+	backs            bool
+	back             cardBack
+	cutLines         cutLines
+	cardNumbers      string
+	qrCodes          string
+	qrSize           float64 // mm, including the quiet zone
+	cover            bool
+	rules            bool
+	deckName         string
+	tuckBox          bool
+	tuckBoxFile      string
+	cardThickness    float64
+	printReady       bool
+	iccProfile       string
+	grayscale        bool
+	inkSaver         bool
+	deckID           string
+	copies           int
+	copyBackColors   []color.RGBA // back color per copy, cycled
+	pageSize         string
+	orientation      string
+	cardWidth        float64
+	cardHeight       float64
+	diameter         float64
+	bleed            float64
+	cropMarks        bool
+	safeZone         float64
+	images           []string // explicit symbol files; empty means all of imgDir
+	textSymbols      string
+	textStyle        string
+	glyphFont        string
+	glyphList        string
+	glyphFormat      string
+	font             string
+	generatedDir     string
+	fillSymbols      bool
+	substituteBroken bool
+	minDPI           float64
+	strict           bool
+	dryRun           bool
+	stats            string
+	usageCSV         string
+	resultJSON       bool
+	perPage          int
+	pageMargin       float64
+	gutter           float64
+	force            bool
+	placeholders     bool
+	urls             []string
+	urlList          string
+	symbolList       string
+	symbolNames      map[string]string // display names keyed by image path
+	formats          []string
+	pngDir           string
+	pngDPI           float64
+	svgDir           string
+	svgEmbed         bool
+	ttsDir           string
+	ttsCardPx        int
+	ttsBaseURL       string
+	htmlDir          string
+	constraintsFile  string
+	constraints      symbolConstraints
+	labels           bool
+	labelFontFile    string
+	labelSize        float64
+	translationsFile string
+	labelLangs       []string // languages of the label lines, from --label-lang
+	translations     translations
+	tintMode         string
+	tintPalette      []string // #rrggbb colors handed out by --tint
+	jitter           []string // kinds of per-occurrence variation, from --jitter
+	jitterStrength   float64
+	duplicateDist    int
+	highContrast     bool
+	minContrast      float64
+	colorShapes      bool
+	largePrint       bool
+	minSymbolScale   float64 // smallest size tier, relative to the box reserved by the layout
+	tactile          bool
+	symbolCodes      map[string]int // braille numbers keyed by image path
+
+	// ctx is cancelled on SIGINT or SIGTERM; nil outside generate.
+	ctx context.Context
+}
 
-	cg, err := getInputAndInitialize()
-	if err != nil {
-		logger.Error("Initialization failed", "error", err)
-		os.Exit(1)
+// outputPath returns the file or directory the given format is written to.
+func (o options) outputPath(format string) string {
+	switch format {
+	case "png":
+		return o.pngDir
+	case "svg":
+		return o.svgDir
+	case "tts":
+		return o.ttsDir
+	case "html":
+		return o.htmlDir
+	default:
+		return o.output
 	}
+}
+
+// outputFormats lists the values accepted by --formats.
+var outputFormats = []string{"pdf", "png", "svg", "tts", "html"}
+
+// wants reports whether the given output format was requested.
+func (o options) wants(format string) bool {
+	return slices.Contains(o.formats, format)
+}
+
+// cardPreset is a named card format. Diameter is only set for presets that
+// are meant to be cut as circles.
+type cardPreset struct {
+	Width, Height float64
+	Diameter      float64
+}
+
+var cardPresets = map[string]cardPreset{
+	"classic": {Width: cardWidth, Height: cardHeight},
+	"poker":   {Width: 63, Height: 88},
+	"mini":    {Width: 44, Height: 68},
+	"tin":     {Width: 80, Height: 80, Diameter: 80},
+	"a5":      {Width: 148, Height: 210},
+}
 
-	cards := cg.generateCards()
-	logger.Info("Cards generated", "count", len(cards))
+// deckPreset is a named deck variant matching a commercial edition.
+type deckPreset struct {
+	Symbols int // symbols per card
+	Cards   int
+}
+
+var deckPresets = map[string]deckPreset{
+	"classic": {Symbols: 8, Cards: 55},
+	"kids":    {Symbols: 6, Cards: 31},
+	"mini":    {Symbols: 4, Cards: 13},
+}
+
+// command is a subcommand of the CLI.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"generate", "generate a deck (default; --interactive shows the form)", runGenerate},
+	{"validate", "check that a deck manifest satisfies the Dobble property", runValidate},
+	{"serve", "start the web UI and JSON API", runServe},
+	{"batch", "generate several decks described in a JSON job file", runBatch},
+	{"stats", "report symbol usage and balance of a deck manifest", runStats},
+	{"diff", "show which cards and symbols changed between two deck manifests", runDiff},
+	{"graph", "export the card-symbol structure of a deck manifest as DOT or GraphML", runGraph},
+	{"merge", "apply symbol renames to a deck manifest without changing its layouts", runMerge},
+	{"reprint", "render selected cards of a saved deck again", runReprint},
+	{"preview", "show cards of a saved deck in the terminal", runPreview},
+	{"difficulty", "report symbols of a saved deck that look alike or lack contrast", runDifficulty},
+	{"fetch-icons", "download an openly licensed icon set into the image directory", runFetchIcons},
+}
 
-	if err := generatePDF(cards, cg.RoundCards); err != nil {
-		logger.Error("PDF generation failed", "error", err)
-		os.Exit(1)
+func main() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	// Without a command the tool keeps its original behavior: no arguments
+	// show the form, bare flags generate non-interactively. Flags set in
+	// the environment count as given, for headless use.
+	args := os.Args[1:]
+	switch {
+	case len(args) == 0 && hasEnvConfig():
+		args = []string{"generate"}
+	case len(args) == 0:
+		args = []string{"generate", "--interactive"}
+	case strings.HasPrefix(args[0], "-"):
+		args = append([]string{"generate"}, args...)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			if err := cmd.run(args[1:]); err != nil {
+				slog.Error("Command failed", "command", cmd.name, "error", err)
+				os.Exit(exitCode(err))
+			}
+			return
+		}
 	}
 
-	logger.Info("PDF successfully generated")
+	if args[0] != "help" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	}
+	usage()
+	os.Exit(2)
 }
 
-func getInputAndInitialize() (*CardGenerator, error) {
-	var totalCardsStr, imagesPerCardStr string
-	var roundCards bool
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: dobble <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'dobble <command> -h' for the flags of a command.")
+	fmt.Fprintln(os.Stderr, "Flags of generate can also be set in the environment, e.g. DOBBLE_IMG_DIR for --img-dir.")
+	fmt.Fprintln(os.Stderr, "\nExit codes: 1 failure, 2 invalid parameters, 3 not enough images, 4 broken image, 5 PDF failure, 130 interrupted.")
+}
 
-	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewInput().Title("Enter the total number of cards:").Value(&totalCardsStr),
-			huh.NewInput().Title("Enter the number of images per card:").Value(&imagesPerCardStr),
-			huh.NewConfirm().
-				Title("Do you want round cards?").
-				Value(&roundCards),
-		),
-	)
+// runGenerate implements the generate command.
+func runGenerate(args []string) error {
+	opts, err := parseFlags(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if opts.dryRun {
+		plan, err := planDeckMath(opts)
+		if err != nil {
+			return err
+		}
+		plan.print(os.Stdout)
+		return nil
+	}
 
-	if err := form.Run(); err != nil {
-		return nil, fmt.Errorf("form input failed: %w", err)
+	if opts.pprofDir != "" {
+		stop, err := startProfiling(opts.pprofDir)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	if opts.bench {
+		bench.enabled.Store(true)
+		start := time.Now()
+		defer func() { bench.report(os.Stderr, time.Since(start)) }()
 	}
 
-	totalCards, err1 := strconv.Atoi(totalCardsStr)
-	imagesPerCard, err2 := strconv.Atoi(imagesPerCardStr)
-	if err := errors.Join(err1, err2); err != nil {
-		return nil, fmt.Errorf("invalid input: %w", err)
+	if opts.seed == 0 {
+		opts.seed = time.Now().UnixNano()
+	}
+	if opts.deckID == "" {
+		opts.deckID = defaultDeckID(opts.seed)
 	}
+	slog.Info("Using seed", "seed", opts.seed, "deckID", opts.deckID)
 
-	cg := &CardGenerator{
-		TotalCards:    totalCards,
-		ImagesPerCard: imagesPerCard,
-		RoundCards:    roundCards,
+	if opts.watch {
+		return watch(opts)
 	}
 
-	if err := cg.loadImageFiles(); err != nil {
+	ctx, stop := cancelOnSignal()
+	defer stop()
+	opts.ctx = ctx
+
+	var recorder *warningRecorder
+	if opts.resultJSON {
+		recorder = newWarningRecorder(slog.Default().Handler())
+		slog.SetDefault(slog.New(recorder))
+	}
+	m, err := generate(&opts)
+	if err == nil {
+		if err := saveSettings(opts); err != nil {
+			slog.Warn("Failed to save the settings", "error", err)
+		}
+	}
+	if recorder != nil {
+		if err := newRunResult(m, opts, recorder.recorded(), err).print(os.Stdout); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// generate builds the deck and writes every requested output. Output paths
+// in opts are expanded in place.
+func generate(opts *options) (*Manifest, error) {
+	rng := rand.New(rand.NewSource(opts.seed))
+
+	if err := prepareSymbols(opts); err != nil {
+		return nil, err
+	}
+	if err := opts.readConstraints(); err != nil {
+		return nil, err
+	}
+	if err := opts.readTranslations(); err != nil {
 		return nil, err
 	}
 
-	return cg, nil
-}
+	var cg *CardGenerator
+	var err error
+	if opts.interactive {
+		cg, err = getInputAndInitialize(*opts, rng)
+	} else {
+		cg, err = newCardGenerator(*opts, rng)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("initialization failed: %w", err)
+	}
 
-func (cg *CardGenerator) generateCards() [][]string {
-	n := cg.ImagesPerCard - 1
-	totalCards := n*n + n + 1
+	if err := opts.expandOutputPaths(outputFields(cg, opts.seed, time.Now())); err != nil {
+		return nil, err
+	}
+	if opts.wants("pdf") {
+		if err := checkOverwrite(opts.output, opts.force); err != nil {
+			return nil, err
+		}
+	}
 
-	if len(cg.ImageFiles) < totalCards {
-		slog.Error("Not enough images for the given parameters",
-			"required", totalCards,
-			"available", len(cg.ImageFiles))
-		return nil
+	manifest, err := buildDeck(cg, opts, rng)
+	if err != nil {
+		return nil, fmt.Errorf("card generation failed: %w", err)
 	}
 
-	cards := cg.generateCardIndices(n)
-	imageCards := cg.convertToImageCards(cards)
-	cg.shuffleCards(imageCards)
+	if err := checkResolution(manifest, opts.minDPI, opts.strict); err != nil {
+		return nil, err
+	}
+	if err := checkDuplicates(manifest, opts.duplicateDist, opts.strict); err != nil {
+		return nil, err
+	}
 
-	return cg.limitCards(imageCards)
+	if opts.context().Err() != nil {
+		return nil, fmt.Errorf("%w before any output was written", ErrCancelled)
+	}
+	if err := writeOutputs(manifest, *opts); err != nil {
+		return nil, err
+	}
+
+	if opts.stats != "" {
+		stats := computeStats(manifest)
+		if err := stats.save(opts.stats); err != nil {
+			return nil, err
+		}
+		if !opts.log.quiet && !opts.resultJSON {
+			stats.print(os.Stdout)
+		}
+		slog.Info("Statistics written", "path", opts.stats)
+	}
+	if opts.usageCSV != "" {
+		if err := writeAtomic(opts.usageCSV, computeStats(manifest).writeCSV); err != nil {
+			return nil, fmt.Errorf("failed to write symbol usage: %w", err)
+		}
+		slog.Info("Symbol usage written", "path", opts.usageCSV)
+	}
+
+	return manifest, nil
 }
 
-func (cg *CardGenerator) generateCardIndices(n int) [][]int {
-	cards := make([][]int, 0, n*n+n+1)
+// prepareSymbols turns symbol sources other than the image directory into
+// local image files and sets them as the explicit symbol selection.
+func prepareSymbols(opts *options) error {
+	if opts.textSymbols != "" {
+		files, err := renderTextSymbols(opts.textSymbols, opts.font, opts.textStyle, opts.generatedDir)
+		if err != nil {
+			return fmt.Errorf("text symbols failed: %w", err)
+		}
+		slog.Info("Text symbols rendered", "count", len(files), "dir", opts.generatedDir)
+		opts.images = files
+	}
 
-	for i := 0; i < n+1; i++ {
-		card := make([]int, cg.ImagesPerCard)
-		card[0] = 1
-		for j := 0; j < n; j++ {
-			card[j+1] = (j + 1) + (i * n) + 1
+	if opts.symbolList != "" {
+		files, names, err := loadSymbolList(opts.symbolList, *opts)
+		if err != nil {
+			return fmt.Errorf("symbol list failed: %w", err)
 		}
-		cards = append(cards, card)
+		slog.Info("Symbol list loaded", "count", len(files), "path", opts.symbolList)
+		opts.images = append(opts.images, files...)
+		opts.symbolNames = names
 	}
 
-	for i := 0; i < n; i++ {
-		for j := 0; j < n; j++ {
-			card := make([]int, cg.ImagesPerCard)
-			card[0] = i + 2
-			for k := 0; k < n; k++ {
-				card[k+1] = (n + 1 + n*k + (i*k+j)%n) + 1
+	if opts.glyphFont != "" {
+		files, names, err := renderGlyphSymbols(opts.glyphFont, opts.glyphList, opts.glyphFormat, opts.generatedDir)
+		if err != nil {
+			return fmt.Errorf("glyph symbols failed: %w", err)
+		}
+		slog.Info("Glyph symbols rendered", "count", len(files), "dir", opts.generatedDir)
+		opts.images = append(opts.images, files...)
+		if opts.symbolNames == nil {
+			opts.symbolNames = make(map[string]string)
+		}
+		maps.Copy(opts.symbolNames, names)
+	}
+
+	urls := opts.urls
+	if opts.urlList != "" {
+		listed, err := readSymbolList(opts.urlList)
+		if err != nil {
+			return err
+		}
+		urls = append(urls, listed...)
+	}
+	if len(urls) > 0 {
+		for _, u := range urls {
+			if !isRemote(u) {
+				return fmt.Errorf("not an http(s) URL: %q", u)
 			}
-			cards = append(cards, card)
 		}
+		dir := filepath.Join(opts.generatedDir, "remote")
+		files, err := fetchRemoteSymbols(urls, dir)
+		if err != nil {
+			return fmt.Errorf("remote symbols failed: %w", err)
+		}
+		slog.Info("Remote symbols ready", "count", len(files), "dir", dir)
+		opts.images = append(opts.images, files...)
 	}
 
-	return cards
+	return nil
 }
 
-func (cg *CardGenerator) convertToImageCards(cards [][]int) [][]string {
-	imageCards := make([][]string, len(cards))
-	for i, card := range cards {
-		imageCards[i] = make([]string, len(card))
-		for j, symbolIndex := range card {
-			imageCards[i][j] = cg.ImageFiles[symbolIndex-1]
+// buildDeck generates the cards and lays them out. The generator's
+// parameters are copied back into opts, since the form may have changed them.
+func buildDeck(cg *CardGenerator, opts *options, rng *rand.Rand) (*Manifest, error) {
+	cards, err := cg.generateCards()
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Cards generated", "count", len(cards))
+
+	if violations := validateDeck(cards, opts.lambda); len(violations) > 0 {
+		slog.Warn("Generated deck is not a valid Dobble deck",
+			"violations", len(violations),
+			"first", violations[0].String())
+	}
+	if cg.Logo != "" {
+		for i := range cards {
+			cards[i] = append(cards[i], cg.Logo)
 		}
 	}
-	return imageCards
-}
 
-func (cg *CardGenerator) shuffleCards(cards [][]string) {
-	rand.Shuffle(len(cards), func(i, j int) {
-		cards[i], cards[j] = cards[j], cards[i]
-	})
+	opts.totalCards = cg.TotalCards
+	opts.imagesPerCard = cg.ImagesPerCard + opts.logoSymbols()
+	opts.roundCards = cg.RoundCards
 
-	for i := range cards {
-		rand.Shuffle(len(cards[i]), func(j, k int) {
-			cards[i][j], cards[i][k] = cards[i][k], cards[i][j]
-		})
+	if opts.perPage > 0 {
+		if err := opts.fitCards(opts.perPage); err != nil {
+			return nil, err
+		}
 	}
+
+	m := planDeck(cards, *opts, rng)
+	if opts.tactile {
+		m.SymbolCodes = numberSymbols(m)
+		opts.symbolCodes = m.SymbolCodes
+	}
+	return m, nil
 }
 
-func (cg *CardGenerator) limitCards(cards [][]string) [][]string {
-	if cg.TotalCards < len(cards) {
-		return cards[:cg.TotalCards]
+// manifestPath returns the path the manifest is saved to: --manifest, or
+// deck.json next to the PDF.
+func (o options) manifestPath() string {
+	if o.manifest != "" {
+		return o.manifest
 	}
-	return cards
+	return filepath.Join(filepath.Dir(o.output), defaultManifestName)
 }
 
-func (cg *CardGenerator) loadImageFiles() error {
-	files, err := os.ReadDir(imgDir)
-	if err != nil {
-		return fmt.Errorf("failed to read image directory: %w", err)
+// writeOutputs renders the planned deck in every requested format and saves
+// the manifest.
+func writeOutputs(manifest *Manifest, opts options) error {
+	if opts.wants("pdf") {
+		if err := generatePDF(manifest, opts); err != nil {
+			return fmt.Errorf("%w: %w", ErrPDFWrite, err)
+		}
+		slog.Info("PDF successfully generated", "output", opts.output)
 	}
 
-	for _, file := range files {
-		if !file.IsDir() && filepath.Ext(file.Name()) == ".png" {
-			cg.ImageFiles = append(cg.ImageFiles, filepath.Join(imgDir, file.Name()))
+	if opts.wants("png") {
+		if err := exportPNGs(manifest, opts); err != nil {
+			return fmt.Errorf("PNG export failed: %w", err)
 		}
+		slog.Info("PNG cards written", "dir", opts.pngDir)
 	}
 
-	requiredImages := cg.calculateRequiredImages()
+	if opts.wants("svg") {
+		if err := exportSVGs(manifest, opts); err != nil {
+			return fmt.Errorf("SVG export failed: %w", err)
+		}
+		slog.Info("SVG cards written", "dir", opts.svgDir)
+	}
 
-	if len(cg.ImageFiles) < requiredImages {
-		return fmt.Errorf("not enough images in the img folder: required %d, found %d", requiredImages, len(cg.ImageFiles))
+	if opts.wants("tts") {
+		if err := exportTTS(manifest, opts); err != nil {
+			return fmt.Errorf("TTS export failed: %w", err)
+		}
+		slog.Info("Tabletop Simulator deck written", "dir", opts.ttsDir)
 	}
 
-	rand.Shuffle(len(cg.ImageFiles), func(i, j int) {
-		cg.ImageFiles[i], cg.ImageFiles[j] = cg.ImageFiles[j], cg.ImageFiles[i]
-	})
+	if opts.wants("html") {
+		if err := exportHTML(manifest, opts); err != nil {
+			return fmt.Errorf("HTML export failed: %w", err)
+		}
+		slog.Info("HTML gallery written", "path", filepath.Join(opts.htmlDir, "index.html"))
+	}
+
+	if opts.tuckBoxFile != "" {
+		if err := writeTuckBoxPDF(manifest, opts); err != nil {
+			return fmt.Errorf("tuck box export failed: %w", err)
+		}
+		slog.Info("Tuck box template written", "output", opts.tuckBoxFile)
+	}
+
+	manifestPath := opts.manifestPath()
+	if err := manifest.save(manifestPath); err != nil {
+		return fmt.Errorf("manifest export failed: %w", err)
+	}
+	slog.Info("Manifest written", "path", manifestPath)
+
+	if opts.log.quiet && !opts.resultJSON {
+		for _, format := range opts.formats {
+			fmt.Println(opts.outputPath(format))
+		}
+	}
 
 	return nil
 }
 
-func (cg *CardGenerator) calculateRequiredImages() int {
-	n := cg.ImagesPerCard - 1
-	return n*n + n + 1
+// parseFlags reads the flags of the generate command. Invalid flags are
+// reported as ErrInvalidParameters, after printing the usage.
+func parseFlags(args []string) (options, error) {
+	return parseGenerateFlags(args, false)
+}
+
+// defaultOptions returns the generate options with every flag at its
+// default, for commands that render decks without the generate flags. Saved
+// settings and the environment are ignored, and logging is left as the
+// command set it up.
+func defaultOptions() options {
+	opts, _ := parseGenerateFlags(nil, true) // the defaults are valid
+	return opts
 }
 
-func generatePDF(cards [][]string, roundCards bool) error {
-	pdf := fpdf.New("P", "mm", "A4", "")
-	pdf.SetAutoPageBreak(true, 10)
+func parseGenerateFlags(args []string, defaultsOnly bool) (options, error) {
+	var opts options
+	var raw generateFlagValues
 
-	pageWidth, pageHeight, _ := pdf.PageSize(1)
-	cardSize := math.Min(cardWidth, cardHeight)
-	cardsPerRow := int((pageWidth - 2*margin) / (cardSize + margin))
-	cardsPerCol := int((pageHeight - 2*margin) / (cardSize + margin))
-	cardsPerPage := cardsPerRow * cardsPerCol
+	fs := newGenerateFlagSet(&opts, &raw)
+	opts.log.register(fs)
 
-	for i, card := range cards {
-		if i%cardsPerPage == 0 {
-			pdf.AddPage()
+	// The flag package prints parse errors with the usage itself.
+	if err := opts.parseSettings(fs, args, defaultsOnly); err != nil {
+		return opts, fmt.Errorf("%w: %w", ErrInvalidParameters, err)
+	}
+	raw.given = givenFlags(args, !defaultsOnly)
+	if err := opts.validateFlags(fs, raw); err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return opts, fmt.Errorf("%w: %w", ErrInvalidParameters, err)
+	}
+	return opts, nil
+}
+
+// newGenerateFlagSet registers the deck flags of the generate command,
+// without the logging flags, on a new flag set.
+func newGenerateFlagSet(opts *options, raw *generateFlagValues) *flag.FlagSet {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	fs.BoolVar(&opts.interactive, "interactive", false, "ask for the deck parameters in an interactive form (pre-filled with the last settings)")
+	fs.StringVar(&opts.profile, "profile", "", "start from the settings saved with --save-profile under this name, or \"last\" for the last successful run; other flags override them")
+	fs.StringVar(&opts.saveProfile, "save-profile", "", "after a successful run, save its settings as a profile with this name")
+	fs.StringVar(&raw.deck, "deck", "", "deck preset: classic (8 symbols, 55 cards), kids (6 symbols, 31 cards) or mini (4 symbols, 13 cards)")
+	fs.IntVar(&opts.totalCards, "cards", 0, "total number of cards to generate (0 generates the full deck)")
+	fs.IntVar(&opts.imagesPerCard, "symbols", 8, "number of symbols per card")
+	fs.IntVar(&opts.lambda, "lambda", 1, "number of symbols every two cards share; 2 or 3 give harder variants with their own valid --symbols values")
+	fs.BoolVar(&opts.roundCards, "round", false, "generate round cards")
+	fs.StringVar(&raw.shape, "shape", "", "card shape: "+strings.Join(cardShapeNames, ", ")+" (default: rect, or round for --round and the tin preset)")
+	fs.Float64Var(&opts.cornerRadius, "corner-radius", 0, "corner radius of rounded cards in mm (default 3 with --shape rounded)")
+	fs.StringVar(&opts.layout, "layout", "auto", "symbol arrangement: "+strings.Join(layoutNames(), ", "))
+	fs.IntVar(&opts.layoutAttempts, "layout-attempts", 5, "candidate layouts generated per card; the best-scoring one is used")
+	fs.BoolVar(&opts.anchor, "anchor", false, "place one symbol of every card at its center, larger than the others, which are arranged in a ring around it (replaces --layout)")
+	fs.Float64Var(&opts.anchorScale, "anchor-scale", 1.5, "size of the --anchor symbol relative to the others")
+	fs.StringVar(&opts.logo, "logo", "", "image shown on every card, e.g. a company logo, on top of a deck built from the other symbols, so any two cards still share exactly one of those; counts as one of --symbols and is the --anchor symbol")
+	fs.Float64Var(&opts.padding, "padding", cardPadding, "minimum distance in mm between symbols and the card edge")
+	fs.Float64Var(&opts.spacing, "spacing", 0, "minimum distance in mm between two symbols")
+	fs.Float64Var(&opts.overlap, "overlap", 0, "how much two symbols may overlap, in percent of the smaller one")
+	fs.StringVar(&opts.imgDir, "img-dir", imgDir, "directory or .zip archive containing the symbol images")
+	fs.BoolVar(&opts.filter.Recursive, "recursive", false, "also pick up images in subdirectories of the image directory")
+	fs.Func("include", "only use images whose path relative to the image directory matches this glob, e.g. \"animals/*.png\"; may be repeated", func(p string) error {
+		opts.filter.Include = append(opts.filter.Include, p)
+		return checkPattern(p)
+	})
+	fs.Func("exclude", "skip images whose relative path matches this glob; may be repeated", func(p string) error {
+		opts.filter.Exclude = append(opts.filter.Exclude, p)
+		return checkPattern(p)
+	})
+	fs.StringVar(&opts.output, "output", outputFileName, "path of the generated PDF; may contain {date}, {time}, {symbols}, {cards} and {seed}")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "print the deck math (symbols, cards, pages, estimated size) without generating anything")
+	fs.StringVar(&opts.stats, "stats", "", "write deck statistics as JSON to this file and print a summary")
+	fs.StringVar(&opts.usageCSV, "usage-csv", "", "write a CSV of every symbol with the cards it appears on and their count to this file")
+	fs.BoolVar(&opts.resultJSON, "result-json", false, "when done, print a JSON summary of the run (outputs, pages, cards, seed, warnings) to stdout; logs go to stderr")
+	fs.BoolVar(&opts.force, "force", false, "overwrite an existing PDF")
+	fs.StringVar(&opts.textSymbols, "text-symbols", "", "file with one emoji or word per line to use as symbols instead of images")
+	fs.StringVar(&opts.font, "font", "", "TrueType/OpenType font for --text-symbols and text: entries of --symbol-list, e.g. Noto Emoji (default: bundled Go font)")
+	fs.StringVar(&opts.glyphFont, "glyph-font", "", "icon font (TTF/OTF), e.g. Font Awesome or Material Symbols, to use glyphs of as symbols instead of images (with --glyphs)")
+	fs.StringVar(&opts.glyphList, "glyphs", "", "file listing the --glyph-font codepoints (U+F015[,name] per line)")
+	fs.StringVar(&opts.glyphFormat, "glyph-format", "png", "render glyphs as high-resolution png or vector svg symbols")
+	fs.StringVar(&opts.textStyle, "text-style", "plain", "frame around text symbols: "+strings.Join(textStyles, ", "))
+	fs.BoolVar(&opts.partial, "partial", false, "when there are too few images for the full deck, generate the largest deck they allow instead of failing")
+	fs.BoolVar(&opts.fillSymbols, "fill-symbols", false, "generate shape symbols when there are too few images")
+	fs.BoolVar(&opts.placeholders, "placeholders", false, "use numbered placeholder symbols instead of images, for testing layouts and print alignment")
+	fs.Func("url", "http(s) URL of a symbol image; may be repeated", func(u string) error {
+		opts.urls = append(opts.urls, u)
+		return nil
+	})
+	fs.StringVar(&opts.urlList, "url-list", "", "file with one symbol image URL per line")
+	fs.StringVar(&opts.symbolList, "symbol-list", "", "CSV or text file listing the symbol images (path[,name] per line) to use in that order; text:WORD entries become text symbols")
+	fs.BoolVar(&opts.labels, "labels", false, "print the name of each symbol below it, from --symbol-list or the file name")
+	fs.StringVar(&opts.labelFontFile, "label-font", "", "TrueType/OpenType font for --labels, e.g. Noto Sans CJK for scripts the bundled Go font lacks (default: bundled Go font)")
+	fs.Float64Var(&opts.labelSize, "label-size", defaultLabelSize, "font size of --labels in pt")
+	fs.StringVar(&opts.translationsFile, "translations", "", "CSV file with the symbol labels in several languages (symbol,lang,lang,... header)")
+	fs.StringVar(&raw.labelLangs, "label-lang", "", "comma-separated languages from --translations to label the symbols in; two print bilingual labels (implies --labels)")
+	fs.StringVar(&opts.tintMode, "tint", "none", "recolor monochrome symbols from --tint-palette, one color per symbol: "+strings.Join(tintModes, ", "))
+	fs.StringVar(&raw.tintPalette, "tint-palette", strings.Join(defaultTintPalette, ","), "comma-separated #rrggbb colors for --tint")
+	fs.StringVar(&raw.jitter, "jitter", "", "comma-separated variations applied to each symbol occurrence: "+strings.Join(jitterKinds, ", ")+" (default: none, every occurrence looks the same)")
+	fs.Float64Var(&opts.jitterStrength, "jitter-strength", 0.1, "amount of hue and brightness --jitter, from 0 to 1")
+	fs.BoolVar(&opts.largePrint, "large-print", false, "oversized variant for low-vision players: A5 cards, one per A4 page (--per-page 2 --orientation landscape for two), larger symbols and labels; the same --seed gives the same cards")
+	fs.BoolVar(&opts.highContrast, "high-contrast", false, "darken symbols below --min-contrast and outline every symbol in dark, for players with low vision")
+	fs.Float64Var(&opts.minContrast, "min-contrast", 3, "WCAG contrast ratio symbols are darkened to against the white card with --high-contrast")
+	fs.BoolVar(&opts.colorShapes, "color-shapes", false, "mark each --tint color with its own shape, so colorblind players can tell the color groups apart")
+	fs.BoolVar(&opts.tactile, "tactile", false, "print each symbol's number in braille below it as solid dots that rise when printed on swell paper, for tactile decks")
+	fs.StringVar(&opts.constraintsFile, "constraints", "", "JSON file fixing the rotation, scale or color of symbols matching a pattern (default: "+constraintsFileName+" in the image directory, if present)")
+	fs.BoolVar(&opts.substituteBroken, "substitute-broken", false, "replace unreadable images with spare images or placeholders instead of failing")
+	fs.Float64Var(&opts.minDPI, "min-dpi", 150, "warn about symbols with a lower resolution at their printed size (0 disables the check)")
+	fs.BoolVar(&opts.strict, "strict", false, "fail instead of warning about low-resolution or nearly identical symbols")
+	fs.IntVar(&opts.duplicateDist, "duplicate-distance", 4, "warn about symbols whose perceptual hashes differ in at most this many of 64 bits, at any rotation (0 disables the check)")
+	fs.StringVar(&opts.generatedDir, "generated-dir", "generated", "directory for generated and downloaded symbol images")
+	fs.BoolVar(&opts.svgRaster, "svg-raster", false, "rasterize SVG symbols instead of embedding them as vectors")
+	fs.Float64Var(&opts.imageDPI, "image-dpi", imageDPI, "resolution of raster symbols embedded in the PDF; lower values make smaller files")
+	fs.IntVar(&opts.jpegQuality, "jpeg-quality", 0, "embed opaque symbols, like photos, as JPEG with this quality (1-100; 0 keeps lossless PNG)")
+	fs.StringVar(&opts.resample, "resample", "lanczos", "filter for scaling raster symbols: "+strings.Join(resampleFilterNames, ", ")+" (from sharpest and slowest to fastest)")
+	fs.IntVar(&opts.jobs, "jobs", runtime.NumCPU(), "number of symbols decoded and rendered in parallel")
+	fs.BoolVar(&opts.watch, "watch", false, "keep running and regenerate the deck whenever the symbol images or input files change")
+	fs.StringVar(&opts.watchAddr, "watch-addr", "", "with --watch, serve a browser preview of the PDF that reloads after every regeneration on this address")
+	fs.BoolVar(&opts.bench, "bench", false, "report the time spent decoding, resizing, rotating, encoding and embedding symbols")
+	fs.StringVar(&opts.pprofDir, "pprof", "", "write CPU and heap profiles of the run to this directory")
+	fs.BoolVar(&opts.keepTemp, "keep-temp", false, "keep the processed symbol images embedded in the PDF and list them, to debug how a symbol renders")
+	fs.StringVar(&opts.tempDir, "temp-dir", "", "directory --keep-temp writes the processed symbol images to (default: a new directory in the system temp directory)")
+	fs.Float64Var(&opts.svgDPI, "svg-dpi", 300, "resolution used when rasterizing SVG symbols")
+	fs.StringVar(&opts.manifest, "manifest", "", "path of the deck manifest (default: deck.json next to the PDF)")
+	fs.Int64Var(&opts.seed, "seed", 0, "seed for all randomness, to reproduce a deck exactly (0 picks a random seed)")
+	fs.BoolVar(&opts.backs, "backs", false, "print card backs on alternating pages for double-sided printing")
+	fs.StringVar(&raw.backColor, "back-color", "#2b4c7e", "card back color as #rrggbb")
+	fs.StringVar(&opts.back.Pattern, "back-pattern", "none", "card back pattern: none, dots, stripes or grid")
+	fs.StringVar(&opts.back.Image, "back-image", "", "image drawn in the center of every card back")
+	fs.StringVar(&opts.back.FlipEdge, "duplex-flip", "long", "edge the printer flips on: long or short")
+	fs.IntVar(&opts.copies, "copies", 1, "number of identical decks to print in the PDF")
+	fs.StringVar(&raw.copyBackColors, "copy-back-colors", "", "comma-separated #rrggbb back colors, one per copy, to tell the sets apart (with --backs)")
+	fs.StringVar(&opts.pageSize, "page-size", "A4", "paper size: A3, A4, A5, Letter or Legal")
+	fs.StringVar(&opts.orientation, "orientation", "portrait", "page orientation: portrait or landscape")
+	fs.StringVar(&raw.preset, "card-preset", "classic", "card format: classic (55x85), poker (63x88), mini (44x68), tin (80 mm circle) or a5 (148x210)")
+	fs.Float64Var(&opts.cardWidth, "card-width", 0, "card width in mm (overrides the preset)")
+	fs.Float64Var(&opts.cardHeight, "card-height", 0, "card height in mm (overrides the preset)")
+	fs.Float64Var(&opts.diameter, "diameter", 0, "diameter of round cards in mm (default: the smaller card side)")
+	fs.Float64Var(&opts.bleed, "bleed", 0, "bleed in mm added around every card")
+	fs.Float64Var(&opts.pageMargin, "page-margin", defaultMargin, "outer page margin in mm")
+	fs.Float64Var(&opts.gutter, "gutter", defaultMargin, "space between cards in mm (0 puts cards edge to edge with shared cut lines)")
+	fs.IntVar(&opts.perPage, "per-page", 0, "scale cards to the largest size that fits this many on a page (0 keeps the card size)")
+	fs.BoolVar(&opts.cropMarks, "crop-marks", false, "draw crop marks at the card corners")
+	fs.StringVar(&opts.cutLines.Style, "cut-lines", "solid", "card outline in the PDF: "+strings.Join(cutLineStyles, ", "))
+	fs.StringVar(&raw.cutColor, "cut-color", "#000000", "cut line color as #rrggbb")
+	fs.Float64Var(&opts.cutLines.Width, "cut-width", 0, "cut line width in mm (default: 0.2, or 0.05 for hairline)")
+	fs.BoolVar(&opts.cover, "cover", false, "start the PDF with a cover page showing the deck name and its numbers")
+	fs.BoolVar(&opts.rules, "rules", false, "add a page explaining how to play before the cards")
+	fs.StringVar(&opts.deckName, "deck-name", "Dobble", "deck name printed on the cover page")
+	fs.BoolVar(&opts.printReady, "print-ready", false, "PDF for print shops: one card per page with trim and bleed boxes, flattened transparency and an ICC output intent, without cut lines or marks")
+	fs.StringVar(&opts.iccProfile, "icc-profile", "", "ICC profile embedded as the output intent, with symbols converted to it if it is an RGB profile (default with --print-ready: built-in sRGB)")
+	fs.BoolVar(&opts.grayscale, "grayscale", false, "convert symbols to grayscale")
+	fs.BoolVar(&opts.inkSaver, "ink-saver", false, "print symbols as black outlines to save ink")
+	fs.BoolVar(&opts.tuckBox, "tuck-box", false, "add a tuck box template sized for the deck as the last page of the PDF")
+	fs.StringVar(&opts.tuckBoxFile, "tuck-box-file", "", "write the tuck box template to this PDF instead of adding it to the deck")
+	fs.Float64Var(&opts.cardThickness, "card-thickness", defaultCardThickness, "thickness of one card in mm, for sizing the tuck box")
+	fs.StringVar(&opts.cardNumbers, "card-numbers", "none", "print the card number and deck ID on each card in the PDF: "+strings.Join(cardLabelSides, ", "))
+	fs.StringVar(&opts.qrCodes, "qr-codes", "none", "print a QR code encoding the deck ID and card number on each card in the PDF, for companion apps: "+strings.Join(cardLabelSides, ", "))
+	fs.Float64Var(&opts.qrSize, "qr-size", 10, "side of the QR codes of --qr-codes in mm, including their quiet zone")
+	fs.StringVar(&opts.deckID, "deck-id", "", "deck identifier printed with --card-numbers and encoded by --qr-codes (default: derived from the seed)")
+	fs.Float64Var(&opts.safeZone, "safe-zone", 0, "draw a dashed safe-zone guide this many mm inside the trim edge")
+	fs.StringVar(&raw.formats, "formats", "pdf", "comma-separated output formats: "+strings.Join(outputFormats, ", "))
+	fs.StringVar(&opts.pngDir, "png-dir", "cards", "directory for per-card PNG files")
+	fs.Float64Var(&opts.pngDPI, "png-dpi", 300, "resolution of per-card PNG files")
+	fs.StringVar(&opts.svgDir, "svg-dir", "cards", "directory for per-card SVG files")
+	fs.BoolVar(&opts.svgEmbed, "svg-embed", true, "embed symbols in card SVGs instead of referencing the files")
+	fs.StringVar(&opts.ttsDir, "tts-dir", "tts", "directory for the Tabletop Simulator deck")
+	fs.IntVar(&opts.ttsCardPx, "tts-card-width", 400, "width of a single card on the TTS sprite sheet in pixels")
+	fs.StringVar(&opts.ttsBaseURL, "tts-url", "", "base URL the TTS images will be hosted at (default: local file URLs)")
+	fs.StringVar(&opts.htmlDir, "html-dir", "gallery", "directory for the HTML gallery of all cards")
+	return fs
+}
+
+// generateFlagValues holds the generate flags that are parsed into options
+// only once all flags are known.
+type generateFlagValues struct {
+	backColor, preset, formats, deck, copyBackColors string
+	cutColor, shape, labelLangs, tintPalette, jitter string
+
+	given map[string]bool // flags set on the command line or in the environment
+}
+
+// parseSettings parses the saved settings, the environment and args into
+// fs, in this order, and sets up logging.
+func (o *options) parseSettings(fs *flag.FlagSet, args []string, defaultsOnly bool) error {
+	// Saved settings are parsed first, then the environment, so the flags
+	// given override both. The form starts from the last settings, if there
+	// are any.
+	var profileArgs []string
+	if name, ok := profileName(args); ok && !defaultsOnly {
+		var err error
+		if profileArgs, err = loadProfile(name); err != nil {
+			return err
+		}
+	} else if interactive, _ := scanFlag(args, "interactive"); interactive == "true" {
+		if profileArgs, _ = loadProfile(lastProfile); profileArgs != nil {
+			o.profile = lastProfile
+		}
+	}
+	if err := fs.Parse(profileArgs); err != nil {
+		return err
+	}
+	if !defaultsOnly {
+		if err := applyEnv(fs); err != nil {
+			return err
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	o.settings = withoutFlags(fs, append(slices.Clone(profileArgs), args...), unsavedFlags...)
+
+	if !defaultsOnly {
+		o.log.stderr = o.resultJSON
+		if err := o.log.apply(fs); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		col := i % cardsPerRow
-		row := (i / cardsPerRow) % cardsPerCol
+// validateFlags checks the parsed flags and fills in the options derived
+// from them.
+func (o *options) validateFlags(fs *flag.FlagSet, raw generateFlagValues) error {
+	if err := o.applyDeckPreset(raw.given, raw.deck); err != nil {
+		return err
+	}
 
-		x := margin + float64(col)*(cardSize+margin)
-		y := margin + float64(row)*(cardSize+margin)
+	for _, f := range strings.Split(raw.formats, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if !slices.Contains(outputFormats, f) {
+			return fmt.Errorf("unknown output format %q", f)
+		}
+		o.formats = append(o.formats, f)
+	}
+
+	if o.padding < 0 || o.spacing < 0 || o.overlap < 0 || o.overlap > 100 {
+		return errors.New("padding and spacing must not be negative, overlap must be between 0 and 100")
+	}
 
-		slog.Info("Processing card", "index", i, "x", x, "y", y)
+	if o.imageDPI <= 0 || o.jobs < 1 || o.jpegQuality < 0 || o.jpegQuality > 100 {
+		return errors.New("image DPI and jobs must be positive, JPEG quality must be between 0 and 100")
+	}
 
-		if roundCards {
-			if err := processRoundCard(pdf, x, y, card); err != nil {
-				return fmt.Errorf("failed to process round card %d: %w", i, err)
-			}
-		} else {
-			if err := processSquareCard(pdf, x, y, card); err != nil {
-				return fmt.Errorf("failed to process square card %d: %w", i, err)
+	if o.lambda < 1 {
+		return errors.New("--lambda must be at least 1")
+	}
+
+	if o.labelSize <= 0 {
+		return errors.New("label size must be positive")
+	}
+
+	if raw.labelLangs != "" {
+		if o.translationsFile == "" {
+			return errors.New("--label-lang requires --translations")
+		}
+		for _, lang := range strings.Split(raw.labelLangs, ",") {
+			o.labelLangs = append(o.labelLangs, strings.TrimSpace(lang))
+		}
+		o.labels = true
+	}
+
+	if o.tactile && o.labels {
+		return errors.New("--tactile cannot be combined with --labels")
+	}
+
+	if (o.glyphFont == "") != (o.glyphList == "") || !slices.Contains(glyphFormats, o.glyphFormat) {
+		return errors.New("--glyph-font and --glyphs must be given together, --glyph-format must be png or svg")
+	}
+
+	if !slices.Contains(tintModes, o.tintMode) {
+		return fmt.Errorf("unknown tint mode %q", o.tintMode)
+	}
+	for _, c := range strings.Split(raw.tintPalette, ",") {
+		c = strings.TrimSpace(c)
+		if _, err := parseHexColor(c); err != nil {
+			return err
+		}
+		o.tintPalette = append(o.tintPalette, c)
+	}
+
+	if o.minContrast < 1 || o.minContrast > 21 {
+		return errors.New("minimum contrast must be between 1 and 21")
+	}
+
+	if raw.jitter != "" {
+		for _, kind := range strings.Split(raw.jitter, ",") {
+			kind = strings.TrimSpace(kind)
+			if !slices.Contains(jitterKinds, kind) {
+				return fmt.Errorf("unknown jitter %q", kind)
 			}
+			o.jitter = append(o.jitter, kind)
 		}
 	}
+	if o.jitterStrength < 0 || o.jitterStrength > 1 {
+		return errors.New("jitter strength must be between 0 and 1")
+	}
 
-	return pdf.OutputFileAndClose(outputFileName)
-}
+	if !slices.Contains(textStyles, o.textStyle) {
+		return fmt.Errorf("unknown text style %q", o.textStyle)
+	}
 
-func processRoundCard(pdf *fpdf.Fpdf, x, y float64, card []string) error {
-	diameter := math.Min(cardWidth, cardHeight)
-	radius := diameter / 2
+	if _, ok := resampleFilters[o.resample]; !ok {
+		return fmt.Errorf("unknown resample filter %q", o.resample)
+	}
+
+	if _, ok := layouts[o.layout]; !ok {
+		return fmt.Errorf("unknown layout %q", o.layout)
+	}
+	if o.anchorScale < 1 {
+		return errors.New("--anchor-scale must be at least 1")
+	}
 
-	pdf.SetDrawColor(0, 0, 0)
-	pdf.Circle(x+radius, y+radius, radius, "D")
+	if o.largePrint {
+		raw.preset = o.applyLargePrint(raw.given, raw.preset)
+	}
 
-	availableRadius := radius - 5
-	optimalImageSize := availableRadius * 2 / math.Sqrt(float64(len(card)))
+	heightSet := o.cardHeight != 0
+	if err := o.applyCardPreset(raw.preset); err != nil {
+		return err
+	}
+	if err := o.applyShape(raw.shape, heightSet); err != nil {
+		return err
+	}
 
-	for i, imgFile := range card {
-		angle := 2 * math.Pi * float64(i) / float64(len(card))
-		distanceFromCenter := availableRadius * 0.6
+	if !slices.Contains(cardLabelSides, o.cardNumbers) {
+		return fmt.Errorf("unknown --card-numbers value %q", o.cardNumbers)
+	}
+	if err := validateDeckID(o.deckID); err != nil {
+		return err
+	}
+	if o.watch && o.interactive || o.watchAddr != "" && !o.watch {
+		return errors.New("--watch cannot be combined with --interactive, --watch-addr requires --watch")
+	}
+	if o.cardNumbers == "back" && !o.backs {
+		return errors.New("--card-numbers back requires --backs")
+	}
+	if !slices.Contains(cardLabelSides, o.qrCodes) {
+		return fmt.Errorf("unknown --qr-codes value %q", o.qrCodes)
+	}
+	if o.qrCodes == "back" && !o.backs {
+		return errors.New("--qr-codes back requires --backs")
+	}
+	if w, h := o.cardSize(); o.qrCodes != "none" && (o.qrSize < 5 || o.qrSize > min(w, h)/2) {
+		return fmt.Errorf("--qr-size must be between 5 mm and half the card size (%g mm)", min(w, h)/2)
+	}
 
-		imgX := x + radius + distanceFromCenter*math.Cos(angle) - optimalImageSize/2
-		imgY := y + radius + distanceFromCenter*math.Sin(angle) - optimalImageSize/2
+	if o.printReady {
+		if o.cover || o.rules || (o.tuckBox && o.tuckBoxFile == "") {
+			return errors.New("--print-ready cannot be combined with --cover, --rules or --tuck-box; use --tuck-box-file for the box")
+		}
+		o.pageMargin, o.gutter = 0, 0
+		o.cropMarks, o.safeZone = false, 0
+		raw.cutColor, o.cutLines.Style = "#000000", "none"
+	}
 
-		if err := processImage(pdf, imgFile, imgX, imgY, optimalImageSize); err != nil {
+	cutRGBA, err := parseHexColor(raw.cutColor)
+	if err == nil {
+		o.cutLines.Color = cutRGBA
+		err = o.cutLines.validate()
+	}
+	if err != nil {
+		return err
+	}
+
+	if o.backs {
+		var err error
+		if o.back.Color, err = parseHexColor(raw.backColor); err == nil {
+			err = o.back.validate()
+		}
+		if err != nil {
 			return err
 		}
 	}
 
+	if raw.copyBackColors != "" {
+		for _, c := range strings.Split(raw.copyBackColors, ",") {
+			rgba, err := parseHexColor(strings.TrimSpace(c))
+			if err != nil {
+				return err
+			}
+			o.copyBackColors = append(o.copyBackColors, rgba)
+		}
+	}
+	if o.copies < 1 {
+		return errors.New("copies must be at least 1")
+	}
+
 	return nil
 }
 
-func processSquareCard(pdf *fpdf.Fpdf, x, y float64, card []string) error {
-	pdf.Rect(x, y, cardWidth, cardHeight, "D")
+// applyDeckPreset sets the symbols per card and card count from the named
+// deck preset, unless they were given explicitly.
+func (o *options) applyDeckPreset(given map[string]bool, name string) error {
+	if name == "" {
+		return nil
+	}
+	p, ok := deckPresets[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown deck preset %q (want classic, kids or mini)", name)
+	}
 
-	availableWidth := cardWidth - 10
-	availableHeight := cardHeight - 10
-	optimalImageSize := math.Min(availableWidth/2, availableHeight/float64(len(card)))
+	if !given["symbols"] {
+		o.imagesPerCard = p.Symbols
+	}
+	if !given["cards"] {
+		o.totalCards = p.Cards
+	}
+	o.deck = name
+	return nil
+}
 
-	for i, imgFile := range card {
-		imgX := x + 5 + rand.Float64()*(availableWidth-optimalImageSize)
-		imgY := y + 5 + float64(i)*(availableHeight/float64(len(card))) + rand.Float64()*(availableHeight/float64(len(card))-optimalImageSize)
+// largePrintLabelScale enlarges labels in large-print decks.
+const largePrintLabelScale = 2
 
-		if err := processImage(pdf, imgFile, imgX, imgY, optimalImageSize); err != nil {
-			return err
-		}
+// largePrintMinScale is the smallest size tier of symbols in large-print
+// decks, so no symbol is drawn much smaller than its neighbors.
+const largePrintMinScale = 0.8
+
+// applyLargePrint sets the defaults of --large-print for the flags that were
+// not given explicitly and returns the card preset to use.
+func (o *options) applyLargePrint(given map[string]bool, preset string) string {
+	if !given["card-preset"] {
+		preset = "a5"
+	}
+	if !given["label-size"] {
+		o.labelSize *= largePrintLabelScale
+	}
+	o.minSymbolScale = largePrintMinScale
+	return preset
+}
+
+// applyCardPreset fills in the card dimensions that were not given
+// explicitly from the named preset. Presets with a diameter imply round cards.
+func (o *options) applyCardPreset(name string) error {
+	p, ok := cardPresets[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown card preset %q (want classic, poker, mini, tin or a5)", name)
+	}
+
+	if o.cardWidth == 0 {
+		o.cardWidth = p.Width
+	}
+	if o.cardHeight == 0 {
+		o.cardHeight = p.Height
+	}
+	if o.diameter == 0 && p.Diameter > 0 {
+		o.diameter = p.Diameter
+		o.roundCards = true
 	}
 
+	if o.cardWidth <= 0 || o.cardHeight <= 0 || o.diameter < 0 || o.bleed < 0 {
+		return fmt.Errorf("card dimensions must be positive")
+	}
+	if o.pageMargin < 0 || o.gutter < 0 {
+		return fmt.Errorf("page margin and gutter must not be negative")
+	}
 	return nil
 }
 
-func processImage(pdf *fpdf.Fpdf, imgFile string, x, y, size float64) error {
-	file, err := os.Open(imgFile)
-	if err != nil {
-		return fmt.Errorf("failed to open image file: %w", err)
+// cardSize returns the outer width and height of a card in mm.
+func (o options) cardSize() (float64, float64) {
+	if o.roundCards {
+		d := o.diameter
+		if d == 0 {
+			d = math.Min(o.cardWidth, o.cardHeight)
+		}
+		return d, d
+	}
+	return o.cardWidth, o.cardHeight
+}
+
+func getInputAndInitialize(opts options, rng *rand.Rand) (*CardGenerator, error) {
+	var imagesPerCardStr string
+	if opts.deck != "" || opts.profile != "" {
+		imagesPerCardStr = strconv.Itoa(opts.imagesPerCard)
+	}
+	roundCards := opts.roundCards
+
+	available := opts.images
+	if len(available) == 0 && !opts.placeholders {
+		var err error
+		if available, err = discoverImages(opts.imgDir, opts.filter); err != nil {
+			return nil, err
+		}
 	}
-	defer file.Close()
 
-	img, _, err := image.Decode(file)
+	// With --fill-symbols, missing images are generated later, so only the
+	// generator's capacity limits the deck. Placeholders are unlimited.
+	supply := len(available)
+	switch {
+	case opts.placeholders:
+		supply = -1
+	case opts.fillSymbols:
+		supply += maxProceduralSymbols
+	}
+
+	if opts.logo != "" {
+		available = slices.DeleteFunc(slices.Clone(available), func(path string) bool { return sameFile(path, opts.logo) })
+	}
+
+	// All discovered images start out selected; the user can deselect
+	// symbols that should not be part of the deck.
+	selected := available
+	imageOptions := make([]huh.Option[string], len(available))
+	for i, path := range available {
+		imageOptions[i] = huh.NewOption(filepath.Base(path), path).Selected(true)
+	}
+
+	// The card counts to choose from depend on the symbols per card, so
+	// they are asked for in a second form.
+	form := huh.NewForm(
+		huh.NewGroup(
+			newFeedbackInput(
+				huh.NewInput().
+					Title("Enter the number of images per card:").
+					Validate(func(v string) error { return validateSymbols(v, supply, opts.lambda, opts.logoSymbols()) }),
+				&imagesPerCardStr,
+				func(v string) string { return symbolsFeedback(v, supply, opts.lambda, opts.logoSymbols()) },
+			),
+			huh.NewConfirm().
+				Title("Do you want round cards?").
+				Value(&roundCards),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return nil, fmt.Errorf("form input failed: %w", err)
+	}
+
+	imagesPerCard, err := strconv.Atoi(imagesPerCardStr)
 	if err != nil {
-		return fmt.Errorf("failed to decode image: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidParameters, err)
+	}
+	deckSymbols := imagesPerCard - opts.logoSymbols()
+	fullDeck, err := deckSize(deckSymbols, opts.lambda)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidParameters, err)
+	}
+	totalCards := opts.totalCards
+	if totalCards <= 0 || totalCards > fullDeck {
+		totalCards = fullDeck
 	}
 
-	scaleFactor := minScaleFactor + rand.Float64()*(maxScaleFactor-minScaleFactor)
-	imgSize := size * scaleFactor
-	targetSize := uint(imgSize * dpiScale)
+	form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[int]().
+				Title("Select the total number of cards:").
+				Options(cardCountOptions(fullDeck)...).
+				Height(10).
+				Value(&totalCards),
+		),
+		huh.NewGroup(
+			huh.NewMultiSelect[string]().
+				Title("Select the images to use:").
+				Description(fmt.Sprintf("%d images available", len(available))).
+				Options(imageOptions...).
+				Filterable(true).
+				Height(15).
+				Validate(func(images []string) error {
+					if len(images) >= fullDeck || opts.fillSymbols {
+						return nil
+					}
+					// Fewer images can still make a partial deck, which
+					// is offered after the form.
+					if cards, err := partialDeckCards(deckSymbols, opts.lambda, len(images)); err == nil && cards >= 2 {
+						return nil
+					}
+					return fmt.Errorf("select at least %d images, %d selected", fullDeck, len(images))
+				}).
+				Value(&selected),
+		).WithHide(opts.placeholders),
+	)
 
-	img = imaging.Fit(img, int(targetSize), int(targetSize), imaging.Lanczos)
+	if err := form.Run(); err != nil {
+		return nil, fmt.Errorf("form input failed: %w", err)
+	}
+
+	opts.totalCards = totalCards
+	opts.imagesPerCard = imagesPerCard
+	opts.roundCards = roundCards
+	opts.images = selected
+
+	if err := offerPartialDeck(&opts); err != nil {
+		return nil, err
+	}
+	return newCardGenerator(opts, rng)
+}
 
-	rotation := rand.Intn(4) * 90
-	rotatedImg := imaging.Rotate(img, float64(rotation), color.Transparent)
+func newCardGenerator(opts options, rng *rand.Rand) (*CardGenerator, error) {
+	if opts.totalCards < 0 {
+		return nil, fmt.Errorf("%w: total cards must not be negative, got %d", ErrInvalidParameters, opts.totalCards)
+	}
+	var design *blockDesign
+	symbols := opts.deckSymbols()
+	if opts.lambda > 1 {
+		d, err := findDesign(symbols, opts.lambda)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w%s", ErrInvalidParameters, err, opts.logoHint())
+		}
+		design = &d
+	} else if _, _, ok := primePower(symbols - 1); !ok {
+		return nil, fmt.Errorf("%w: %d symbols per card cannot form a valid deck (symbols per card minus one must be a prime power); valid values up to 32: %s%s",
+			ErrInvalidParameters, symbols, formatCounts(validSymbolCounts(32)), opts.logoHint())
+	}
+
+	cg := &CardGenerator{
+		TotalCards:       opts.totalCards,
+		ImagesPerCard:    symbols,
+		RoundCards:       opts.roundCards,
+		ImgDir:           opts.imgDir,
+		Filter:           opts.filter,
+		ImageFiles:       slices.Clone(opts.images),
+		FillSymbols:      opts.fillSymbols,
+		Placeholders:     opts.placeholders,
+		KeepOrder:        opts.symbolList != "",
+		SubstituteBroken: opts.substituteBroken,
+		GeneratedDir:     opts.generatedDir,
+		Partial:          opts.partial,
+		Design:           design,
+		Logo:             opts.logo,
+		Rand:             rng,
+	}
 
-	tmpFile, err := os.CreateTemp("", "processed_*.png")
+	if cg.TotalCards == 0 {
+		cg.TotalCards = cg.calculateRequiredImages()
+		slog.Info("Generating the full deck", "cards", cg.TotalCards)
+	}
+
+	if err := cg.loadImageFiles(); err != nil {
+		return nil, err
+	}
+
+	return cg, nil
+}
+
+func (cg *CardGenerator) generateCards() ([][]string, error) {
+	totalCards := cg.calculateRequiredImages()
+
+	if len(cg.ImageFiles) < totalCards && !cg.Partial {
+		return nil, fmt.Errorf("%w for the given parameters: required %d, available %d",
+			ErrNotEnoughImages, totalCards, len(cg.ImageFiles))
+	}
+
+	cards, err := cg.cardIndices()
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return nil, fmt.Errorf("cannot construct deck with %d symbols per card: %w", cg.ImagesPerCard, err)
+	}
+	if len(cg.ImageFiles) < totalCards {
+		cards = largestSubDeck(cards, totalCards, len(cg.ImageFiles))
+		if len(cards) < 2 {
+			return nil, fmt.Errorf("%w for even a partial deck: %d available", ErrNotEnoughImages, len(cg.ImageFiles))
+		}
+		slog.Warn("Generating a partial deck", "cards", len(cards), "full", totalCards, "images", len(cg.ImageFiles))
+		cg.TotalCards = min(cg.TotalCards, len(cards))
 	}
-	defer os.Remove(tmpFile.Name())
 
-	if err := png.Encode(tmpFile, rotatedImg); err != nil {
-		return fmt.Errorf("failed to encode processed image: %w", err)
+	imageCards := cg.convertToImageCards(cards)
+	cg.shuffleCards(imageCards)
+
+	return cg.limitCards(imageCards), nil
+}
+
+// generateCardIndices builds the projective plane of order n over GF(n).
+// Symbols are the points of the plane and cards its lines: the n+1 points at
+// infinity (one per slope, plus the vertical direction) come first, followed
+// by the n² affine points. Indices are 1-based.
+func (cg *CardGenerator) generateCardIndices(n int) ([][]int, error) {
+	f, err := newGaloisField(n)
+	if err != nil {
+		return nil, err
 	}
-	tmpFile.Close()
 
-	pdf.ImageOptions(
-		tmpFile.Name(),
-		x, y,
-		imgSize, imgSize,
-		false,
-		fpdf.ImageOptions{ImageType: "PNG"},
-		0,
-		"",
-	)
+	infinity := func(slope int) int { return slope + 1 }
+	affine := func(x, y int) int { return n + 1 + x*n + y + 1 }
 
-	return nil
+	cards := make([][]int, 0, n*n+n+1)
+
+	// Lines y = m·x + b.
+	for m := 0; m < n; m++ {
+		for b := 0; b < n; b++ {
+			card := make([]int, 0, cg.ImagesPerCard)
+			card = append(card, infinity(m))
+			for x := 0; x < n; x++ {
+				card = append(card, affine(x, f.add[f.mul[m][x]][b]))
+			}
+			cards = append(cards, card)
+		}
+	}
+
+	// Vertical lines x = c.
+	for c := 0; c < n; c++ {
+		card := make([]int, 0, cg.ImagesPerCard)
+		card = append(card, infinity(n))
+		for y := 0; y < n; y++ {
+			card = append(card, affine(c, y))
+		}
+		cards = append(cards, card)
+	}
+
+	// The line at infinity.
+	card := make([]int, 0, cg.ImagesPerCard)
+	for slope := 0; slope <= n; slope++ {
+		card = append(card, infinity(slope))
+	}
+	cards = append(cards, card)
+
+	return cards, nil
+}
+
+func (cg *CardGenerator) convertToImageCards(cards [][]int) [][]string {
+	imageCards := make([][]string, len(cards))
+	for i, card := range cards {
+		imageCards[i] = make([]string, len(card))
+		for j, symbolIndex := range card {
+			imageCards[i][j] = cg.ImageFiles[symbolIndex-1]
+		}
+	}
+	return imageCards
+}
+
+func (cg *CardGenerator) shuffleCards(cards [][]string) {
+	cg.Rand.Shuffle(len(cards), func(i, j int) {
+		cards[i], cards[j] = cards[j], cards[i]
+	})
+
+	for i := range cards {
+		cg.Rand.Shuffle(len(cards[i]), func(j, k int) {
+			cards[i][j], cards[i][k] = cards[i][k], cards[i][j]
+		})
+	}
+}
+
+func (cg *CardGenerator) limitCards(cards [][]string) [][]string {
+	if cg.TotalCards < len(cards) {
+		return cards[:cg.TotalCards]
+	}
+	return cards
+}
+
+// loadImageFiles checks that enough symbol images are available and
+// shuffles them. Unless an explicit selection was given, all images in the
+// image directory are used.
+func (cg *CardGenerator) loadImageFiles() error {
+	if cg.Placeholders {
+		files, err := generatePlaceholders(cg.GeneratedDir, cg.calculateRequiredImages())
+		if err != nil {
+			return err
+		}
+		cg.ImageFiles = files
+	} else if len(cg.ImageFiles) == 0 {
+		files, err := discoverImages(cg.ImgDir, cg.Filter)
+		if err != nil {
+			return err
+		}
+		cg.ImageFiles = files
+	}
+	if cg.Logo != "" {
+		cg.ImageFiles = slices.DeleteFunc(cg.ImageFiles, func(path string) bool { return sameFile(path, cg.Logo) })
+	}
+
+	requiredImages := cg.calculateRequiredImages()
+
+	// Plenty of spare images could make a bigger deck.
+	if symbols, cards, ok := suggestSymbols(len(cg.ImageFiles), cg.lambda()); ok && !cg.Placeholders && symbols > cg.ImagesPerCard {
+		slog.Info("The images suffice for more symbols per card", "images", len(cg.ImageFiles), "symbols", symbols+cg.logoSymbols(), "cards", cards)
+	}
+
+	if missing := requiredImages - len(cg.ImageFiles); missing > 0 && cg.FillSymbols {
+		generated, err := generateSymbols(cg.GeneratedDir, missing)
+		if err != nil {
+			return err
+		}
+		slog.Info("Generated missing symbols", "count", missing, "dir", cg.GeneratedDir)
+		cg.ImageFiles = append(cg.ImageFiles, generated...)
+	}
+
+	if len(cg.ImageFiles) < requiredImages && !cg.Partial {
+		return fmt.Errorf("%w in the img folder: required %d, found %d%s",
+			ErrNotEnoughImages, requiredImages, len(cg.ImageFiles), cg.shortageHint())
+	}
+
+	if !cg.KeepOrder {
+		cg.Rand.Shuffle(len(cg.ImageFiles), func(i, j int) {
+			cg.ImageFiles[i], cg.ImageFiles[j] = cg.ImageFiles[j], cg.ImageFiles[i]
+		})
+	}
+
+	return cg.checkImages()
+}
+
+func (cg *CardGenerator) calculateRequiredImages() int {
+	if cg.Design != nil {
+		return cg.Design.v
+	}
+	n := cg.ImagesPerCard - 1
+	return n*n + n + 1
+}
+
+// matteTransparent blends the transparent and semi-transparent pixels of
+// img with the color of the card below them, in proportion to their
+// transparency. Their color is invisible where transparency is honored, but
+// some PDF viewers and printers ignore the alpha channel and show it, which
+// turns the black that rotating and cropping leave behind into boxes and
+// dark fringes around the symbols. Symbols keep their alpha channel, since
+// flattening would paint the corners of rotated symbols over their
+// neighbors.
+func matteTransparent(img image.Image, bg color.Color) *image.NRGBA {
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	c := color.NRGBAModel.Convert(bg).(color.NRGBA)
+	for i := 0; i < len(dst.Pix); i += 4 {
+		a := uint32(dst.Pix[i+3])
+		if a == 0xff {
+			continue
+		}
+		for j, bg := range [3]uint8{c.R, c.G, c.B} {
+			dst.Pix[i+j] = uint8((uint32(dst.Pix[i+j])*a + uint32(bg)*(0xff-a) + 0x7f) / 0xff)
+		}
+	}
+	return dst
+}
+
+// flatten draws img onto an opaque background so formats without an alpha
+// channel (JPEG) end up as plain RGB in the embedded PNG.
+func flatten(img image.Image, bg color.Color) *image.NRGBA {
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+	return dst
 }
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestMatteTransparent(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{0, 0, 0, 0})
+	img.SetNRGBA(1, 0, color.NRGBA{0, 0, 0, 0x80})
+	img.SetNRGBA(2, 0, color.NRGBA{10, 20, 30, 0xff})
+
+	got := matteTransparent(img, color.White)
+	for x, want := range []color.NRGBA{
+		{0xff, 0xff, 0xff, 0},
+		{0x7f, 0x7f, 0x7f, 0x80},
+		{10, 20, 30, 0xff},
+	} {
+		if c := got.NRGBAAt(x, 0); c != want {
+			t.Errorf("pixel %d = %v, want %v", x, c, want)
+		}
+	}
+}
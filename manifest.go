@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+const defaultManifestName = "deck.json"
+
+// Manifest describes a generated deck: the parameters it was built with and
+// where every symbol ended up. Lengths are in millimeters.
+type Manifest struct {
+	Seed           int64             `json:"seed"`
+	DeckID         string            `json:"deckId,omitempty"`
+	SymbolsPerCard int               `json:"symbolsPerCard"`
+	Lambda         int               `json:"lambda,omitempty"` // symbols every two cards share, if more than one
+	Logo           string            `json:"logo,omitempty"`   // image on every card, besides the deck
+	RoundCards     bool              `json:"roundCards"`
+	HexCards       bool              `json:"hexCards,omitempty"`
+	CornerRadius   float64           `json:"cornerRadius,omitempty"`
+	CardWidth      float64           `json:"cardWidth"`
+	CardHeight     float64           `json:"cardHeight"`
+	Bleed          float64           `json:"bleed"`
+	PageSize       string            `json:"pageSize"`
+	Orientation    string            `json:"orientation"`
+	Output         string            `json:"output"`
+	Pages          int               `json:"pages,omitempty"`       // pages of the PDF
+	SymbolNames    map[string]string `json:"symbolNames,omitempty"` // display names keyed by file
+	SymbolCodes    map[string]int    `json:"symbolCodes,omitempty"` // braille numbers of tactile decks keyed by file
+	Cards          []ManifestCard    `json:"cards"`
+}
+
+// ManifestCard lists the symbols printed on a single card and the position
+// of the card on its page.
+type ManifestCard struct {
+	Index      int              `json:"index"`
+	Page       int              `json:"page"`
+	X          float64          `json:"x"`
+	Y          float64          `json:"y"`
+	Symbols    []string         `json:"symbols"`
+	Placements []ManifestSymbol `json:"placements"`
+}
+
+// ManifestSymbol is a symbol drawn on a card. X and Y are the top-left corner
+// of its square box relative to the card; Rotation is in degrees.
+type ManifestSymbol struct {
+	File     string  `json:"file"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Size     float64 `json:"size"`
+	Rotation int     `json:"rotation"`
+	SymbolLook
+}
+
+func loadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+
+	return &m, nil
+}
+
+// symbolCards returns the symbols of every card, without the logo, which
+// is not part of the deck's construction.
+func (m *Manifest) symbolCards() [][]string {
+	cards := make([][]string, len(m.Cards))
+	for i, card := range m.Cards {
+		cards[i] = slices.DeleteFunc(slices.Clone(card.Symbols), func(s string) bool { return m.Logo != "" && s == m.Logo })
+	}
+	return cards
+}
+
+// deckSymbols returns the symbols per card of the deck's construction,
+// which does not count the logo.
+func (m *Manifest) deckSymbols() int {
+	if m.Logo != "" {
+		return m.SymbolsPerCard - 1
+	}
+	return m.SymbolsPerCard
+}
+
+func (m *Manifest) save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	err = writeAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
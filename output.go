@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// templateField matches a {name} placeholder in an output path.
+var templateField = regexp.MustCompile(`\{(\w+)\}`)
+
+// outputFields returns the values of the placeholders that output paths may
+// contain.
+func outputFields(cg *CardGenerator, seed int64, now time.Time) map[string]string {
+	return map[string]string{
+		"date":    now.Format("2006-01-02"),
+		"time":    now.Format("150405"),
+		"symbols": strconv.Itoa(cg.ImagesPerCard + cg.logoSymbols()),
+		"cards":   strconv.Itoa(cg.TotalCards),
+		"seed":    strconv.FormatInt(seed, 10),
+	}
+}
+
+// expandOutputPath replaces the {name} placeholders in path with their
+// values. Unknown placeholders are an error rather than left in file names.
+func expandOutputPath(path string, fields map[string]string) (string, error) {
+	var unknown string
+	expanded := templateField.ReplaceAllStringFunc(path, func(m string) string {
+		name := m[1 : len(m)-1]
+		v, ok := fields[name]
+		if !ok && unknown == "" {
+			unknown = name
+		}
+		return v
+	})
+	if unknown != "" {
+		return "", fmt.Errorf("unknown placeholder {%s} in output path %q", unknown, path)
+	}
+	return expanded, nil
+}
+
+// expandOutputPaths expands the placeholders in every output path of opts.
+func (o *options) expandOutputPaths(fields map[string]string) error {
+	for _, p := range []*string{&o.output, &o.manifest, &o.stats, &o.usageCSV, &o.tuckBoxFile, &o.pngDir, &o.svgDir, &o.ttsDir, &o.htmlDir} {
+		expanded, err := expandOutputPath(*p, fields)
+		if err != nil {
+			return err
+		}
+		*p = expanded
+	}
+	return nil
+}
+
+// errOutputExists is returned by checkOverwrite without --force.
+var errOutputExists = errors.New("use --force to overwrite it")
+
+// checkOverwrite refuses to replace an existing file at path unless force
+// is set, so a new run cannot clobber a previous deck by accident.
+func checkOverwrite(path string, force bool) error {
+	if force {
+		return nil
+	}
+	_, err := os.Stat(path)
+	if err == nil {
+		return fmt.Errorf("%s already exists, %w", path, errOutputExists)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// writeAtomic writes a file through a temporary file in the same directory
+// that only replaces path once write has succeeded. A failed run leaves any
+// previous file untouched. Missing parent directories are created. The file
+// keeps the mode of the one it replaces; new files are readable by all.
+func writeAtomic(path string, write func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*.part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// CreateTemp makes the file private to the owner.
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
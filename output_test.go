@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAtomicMode(t *testing.T) {
+	dir := t.TempDir()
+	write := func(w io.Writer) error {
+		_, err := io.WriteString(w, "data")
+		return err
+	}
+	mode := func(path string) os.FileMode {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.Mode().Perm()
+	}
+
+	created := filepath.Join(dir, "new.pdf")
+	if err := writeAtomic(created, write); err != nil {
+		t.Fatal(err)
+	}
+	if got := mode(created); got != 0o644 {
+		t.Errorf("new file has mode %o, want 644", got)
+	}
+
+	replaced := filepath.Join(dir, "old.pdf")
+	if err := os.WriteFile(replaced, nil, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(replaced, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeAtomic(replaced, write); err != nil {
+		t.Fatal(err)
+	}
+	if got := mode(replaced); got != 0o640 {
+		t.Errorf("replaced file has mode %o, want 640", got)
+	}
+}
+
+func TestExpandOutputPaths(t *testing.T) {
+	fields := map[string]string{"date": "2024-05-01", "seed": "42"}
+	opts := options{
+		output:   "out/deck-{seed}.pdf",
+		manifest: "out/deck-{seed}.json",
+		stats:    "out/stats-{date}.json",
+		usageCSV: "out/usage-{seed}-{date}.csv",
+		pngDir:   "out/png-{seed}",
+	}
+	if err := opts.expandOutputPaths(fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ got, want string }{
+		{opts.output, "out/deck-42.pdf"},
+		{opts.manifest, "out/deck-42.json"},
+		{opts.stats, "out/stats-2024-05-01.json"},
+		{opts.usageCSV, "out/usage-42-2024-05-01.csv"},
+		{opts.pngDir, "out/png-42"},
+	} {
+		if c.got != c.want {
+			t.Errorf("expanded to %q, want %q", c.got, c.want)
+		}
+	}
+
+	opts = options{usageCSV: "usage-{deck}.csv"}
+	if err := opts.expandOutputPaths(fields); err == nil {
+		t.Error("unknown placeholder in --usage-csv accepted")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+)
+
+// cardIndices builds the full deck as 1-based symbol indices.
+func (cg *CardGenerator) cardIndices() ([][]int, error) {
+	if cg.Design != nil {
+		cards := cg.Design.build()
+		return cards, cg.Design.check(cards)
+	}
+	return cg.generateCardIndices(cg.ImagesPerCard - 1)
+}
+
+// largestSubDeck returns the cards of a full deck of v symbols that only use
+// available of them, renumbered 1..available. Whole cards are left out, so
+// the rest keep all their symbols and still share as many with each other.
+// Symbols are dropped greedily, each time the one on the fewest remaining
+// cards, which keeps most of the deck.
+func largestSubDeck(cards [][]int, v, available int) [][]int {
+	dropped := make([]bool, v+1)
+	for range v - available {
+		counts := make([]int, v+1)
+		for _, card := range cards {
+			for _, s := range card {
+				counts[s]++
+			}
+		}
+		drop := 0
+		for s := 1; s <= v; s++ {
+			if !dropped[s] && (drop == 0 || counts[s] < counts[drop]) {
+				drop = s
+			}
+		}
+		dropped[drop] = true
+		cards = slices.DeleteFunc(slices.Clone(cards), func(card []int) bool { return slices.Contains(card, drop) })
+	}
+
+	renumber := make([]int, v+1)
+	next := 0
+	for s := 1; s <= v; s++ {
+		if !dropped[s] {
+			next++
+			renumber[s] = next
+		}
+	}
+	sub := make([][]int, len(cards))
+	for i, card := range cards {
+		sub[i] = make([]int, len(card))
+		for j, s := range card {
+			sub[i][j] = renumber[s]
+		}
+	}
+	return sub
+}
+
+// partialDeckCards returns how many cards the largest deck with the given
+// symbols per card has when only available images are there.
+func partialDeckCards(symbols, lambda, available int) (int, error) {
+	cg := &CardGenerator{ImagesPerCard: symbols}
+	if lambda > 1 {
+		d, err := findDesign(symbols, lambda)
+		if err != nil {
+			return 0, err
+		}
+		cg.Design = &d
+	}
+	cards, err := cg.cardIndices()
+	if err != nil {
+		return 0, err
+	}
+	return len(largestSubDeck(cards, cg.calculateRequiredImages(), available)), nil
+}
+
+// shortageHint suggests fewer symbols per card or --partial in errors about
+// missing images.
+func (cg *CardGenerator) shortageHint() string {
+	var hints []string
+	if symbols, cards, ok := suggestSymbols(len(cg.ImageFiles), cg.lambda()); ok {
+		hints = append(hints, fmt.Sprintf("--symbols %d gives a full deck of %d cards", symbols+cg.logoSymbols(), cards))
+	}
+	if cards, err := cg.cardIndices(); err == nil {
+		if n := len(largestSubDeck(cards, cg.calculateRequiredImages(), len(cg.ImageFiles))); n >= 2 {
+			hints = append(hints, fmt.Sprintf("--partial generates the %d cards they allow", n))
+		}
+	}
+	if len(hints) == 0 {
+		return ""
+	}
+	return "; " + strings.Join(hints, ", ")
+}
+
+// offerPartialDeck asks whether to generate a partial deck when fewer images
+// are selected than the full deck needs, and enables --partial if so.
+func offerPartialDeck(opts *options) error {
+	if opts.partial || opts.placeholders || opts.fillSymbols {
+		return nil
+	}
+	required, err := deckSize(opts.deckSymbols(), opts.lambda)
+	if err != nil || len(opts.images) >= required {
+		return nil
+	}
+	cards, err := partialDeckCards(opts.deckSymbols(), opts.lambda, len(opts.images))
+	if err != nil {
+		return err
+	}
+
+	err = huh.NewConfirm().
+		Title(fmt.Sprintf("Only %d of the %d images for the full deck are selected.", len(opts.images), required)).
+		Description(fmt.Sprintf("Generate a partial deck of %d cards instead?", cards)).
+		Value(&opts.partial).
+		Run()
+	if err != nil {
+		return fmt.Errorf("form input failed: %w", err)
+	}
+	if !opts.partial {
+		return fmt.Errorf("%w selected: required %d, selected %d", ErrNotEnoughImages, required, len(opts.images))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestLargestSubDeck(t *testing.T) {
+	tests := []struct {
+		symbols, lambda, available, cards int
+	}{
+		{8, 1, 57, 57},
+		{8, 1, 56, 49}, // dropping a symbol drops the 8 cards it is on
+		{8, 1, 55, 42},
+		{6, 1, 31, 31},
+		{6, 1, 30, 25},
+		{4, 2, 7, 7},
+		{4, 2, 6, 3},
+		{6, 2, 15, 10},
+	}
+	for _, tt := range tests {
+		cg := &CardGenerator{ImagesPerCard: tt.symbols}
+		if tt.lambda > 1 {
+			d, err := findDesign(tt.symbols, tt.lambda)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cg.Design = &d
+		}
+		full, err := cg.cardIndices()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sub := largestSubDeck(full, cg.calculateRequiredImages(), tt.available)
+		if len(sub) != tt.cards {
+			t.Errorf("%d symbols, λ = %d, %d available: %d cards, want %d",
+				tt.symbols, tt.lambda, tt.available, len(sub), tt.cards)
+		}
+		if n, _ := partialDeckCards(tt.symbols, tt.lambda, tt.available); n != len(sub) {
+			t.Errorf("partialDeckCards = %d, largestSubDeck has %d cards", n, len(sub))
+		}
+
+		// The sub-deck keeps whole cards that share λ symbols, all within
+		// the available ones.
+		for i, card := range sub {
+			if len(card) != tt.symbols {
+				t.Fatalf("card %d has %d symbols, want %d", i, len(card), tt.symbols)
+			}
+			for _, s := range card {
+				if s < 1 || s > tt.available {
+					t.Fatalf("card %d uses symbol %d of %d available", i, s, tt.available)
+				}
+			}
+			for j := i + 1; j < len(sub); j++ {
+				if shared := sharedSymbols(card, sub[j]); shared != tt.lambda {
+					t.Fatalf("cards %d and %d share %d symbols, want %d", i, j, shared, tt.lambda)
+				}
+			}
+		}
+	}
+}
+
+func sharedSymbols(a, b []int) int {
+	n := 0
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				n++
+			}
+		}
+	}
+	return n
+}
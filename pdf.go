@@ -0,0 +1,574 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// defaultLineWidth is fpdf's initial line width in mm.
+const defaultLineWidth = 0.2
+
+// pageSizes holds the supported paper sizes in portrait orientation, in mm.
+var pageSizes = map[string]fpdf.SizeType{
+	"a3":     {Wd: 297, Ht: 420},
+	"a4":     {Wd: 210, Ht: 297},
+	"a5":     {Wd: 148, Ht: 210},
+	"letter": {Wd: 215.9, Ht: 279.4},
+	"legal":  {Wd: 215.9, Ht: 355.6},
+}
+
+// pageDimensions resolves a paper size name and orientation to the page
+// width and height in mm.
+func pageDimensions(name, orientation string) (fpdf.SizeType, error) {
+	size, ok := pageSizes[strings.ToLower(name)]
+	if !ok {
+		return fpdf.SizeType{}, fmt.Errorf("unknown page size %q (want A3, A4, A5, Letter or Legal)", name)
+	}
+
+	switch strings.ToLower(orientation) {
+	case "portrait", "p":
+	case "landscape", "l":
+		size.Wd, size.Ht = size.Ht, size.Wd
+	default:
+		return fpdf.SizeType{}, fmt.Errorf("unknown orientation %q (want portrait or landscape)", orientation)
+	}
+
+	return size, nil
+}
+
+// slotSize returns the space a card occupies on the page: its trim size
+// plus the bleed on each side.
+func (o options) slotSize() (float64, float64) {
+	w, h := o.cardSize()
+	return w + 2*o.bleed, h + 2*o.bleed
+}
+
+// pageGrid returns the page size and how many cards fit across and down a
+// page.
+func pageGrid(opts options) (fpdf.SizeType, int, int, error) {
+	// Print shops expect every card on a page of its own, bleed included.
+	slotW, slotH := opts.slotSize()
+	if opts.printReady {
+		return fpdf.SizeType{Wd: slotW, Ht: slotH}, 1, 1, nil
+	}
+
+	pageSize, err := pageDimensions(opts.pageSize, opts.orientation)
+	if err != nil {
+		return fpdf.SizeType{}, 0, 0, err
+	}
+
+	cols := int((pageSize.Wd - 2*opts.pageMargin + opts.gutter) / (slotW + opts.gutter))
+	rows := int((pageSize.Ht - 2*opts.pageMargin + opts.gutter) / (slotH + opts.gutter))
+	if cols*rows == 0 {
+		return fpdf.SizeType{}, 0, 0, fmt.Errorf("a %.0fx%.0f mm card does not fit on a %.0fx%.0f mm page",
+			slotW, slotH, pageSize.Wd, pageSize.Ht)
+	}
+
+	return pageSize, cols, rows, nil
+}
+
+// fitCards scales the card up or down to the largest size at which
+// perPage cards fit on a page, keeping its aspect ratio and bleed. Sizes are
+// rounded down to a tenth of a millimeter.
+func (o *options) fitCards(perPage int) error {
+	pageSize, err := pageDimensions(o.pageSize, o.orientation)
+	if err != nil {
+		return err
+	}
+
+	w, h := o.cardSize()
+	best := 0.0
+	for cols := 1; cols <= perPage; cols++ {
+		rows := (perPage + cols - 1) / cols
+		maxW := (pageSize.Wd-2*o.pageMargin-float64(cols-1)*o.gutter)/float64(cols) - 2*o.bleed
+		maxH := (pageSize.Ht-2*o.pageMargin-float64(rows-1)*o.gutter)/float64(rows) - 2*o.bleed
+		best = max(best, math.Min(maxW/w, maxH/h))
+	}
+	if best <= 0 {
+		return fmt.Errorf("%d cards do not fit on a %.0fx%.0f mm page", perPage, pageSize.Wd, pageSize.Ht)
+	}
+
+	scale := func(mm float64) float64 { return math.Floor(mm*best*10) / 10 }
+	if o.roundCards {
+		o.diameter = scale(w)
+	} else {
+		o.cardWidth, o.cardHeight = scale(w), scale(h)
+	}
+	w, h = o.cardSize()
+	slog.Info("Fitted card size", "perPage", perPage, "width", w, "height", h)
+	return nil
+}
+
+// renderer draws cards onto a PDF document.
+type renderer struct {
+	pdf   *fpdf.Fpdf
+	opts  options
+	back  *cardBack
+	cache *symbolCache
+	temp  *tempImages   // nil unless --keep-temp
+	icc   *iccTransform // converts symbols to --icc-profile; nil keeps sRGB
+}
+
+// generatePDF draws the planned deck and records the page position of every
+// card in the manifest.
+func generatePDF(m *Manifest, opts options) error {
+	pageSize, cardsPerRow, cardsPerCol, err := pageGrid(opts)
+	if err != nil {
+		return err
+	}
+	cardsPerPage := cardsPerRow * cardsPerCol
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "mm",
+		Size:           pageSize,
+	})
+	r := &renderer{pdf: pdf, opts: opts, cache: newSymbolCache()}
+	if opts.backs {
+		r.back = &opts.back
+	}
+	if opts.keepTemp {
+		if r.temp, err = newTempImages(opts.tempDir); err != nil {
+			return err
+		}
+		defer r.temp.list()
+	}
+
+	// The profile is embedded as the output intent, and symbols are
+	// converted to it when it is an RGB profile.
+	var profile []byte
+	var condition string
+	if opts.printReady || opts.iccProfile != "" {
+		if profile, condition, err = outputProfile(opts.iccProfile); err != nil {
+			return err
+		}
+	}
+	if opts.iccProfile != "" {
+		if r.icc, err = newICCTransform(profile); err != nil {
+			slog.Warn("Symbols are not converted to the ICC profile, it is only embedded", "profile", opts.iccProfile, "reason", err)
+		}
+	}
+	pdf.SetAutoPageBreak(true, 10)
+	pdf.SetMargins(20, 20, 20)
+	if opts.printReady {
+		w, h := opts.cardSize()
+		pdf.SetTitle(opts.deckName, true)
+		pdf.SetPageBox("trim", opts.bleed, opts.bleed, w, h)
+		pdf.SetPageBox("bleed", 0, 0, pageSize.Wd, pageSize.Ht)
+	}
+	if opts.labels {
+		if err := r.addLabelFont(); err != nil {
+			return err
+		}
+	}
+	r.drawIntroPages(m)
+
+	// Symbols are decoded a page ahead, at no more than the largest size
+	// they are drawn at, and dropped after their last card, which keeps
+	// memory bounded for large decks.
+	for _, card := range m.Cards {
+		for _, s := range card.Placements {
+			r.cache.limit = max(r.cache.limit, mmToPx(s.Size, opts.imageDPI))
+		}
+	}
+	r.cache.expect(m.Cards, max(opts.copies, 1))
+	r.cache.filter = opts.resampleFilter()
+
+	slotW, slotH := opts.slotSize()
+
+	prog := newProgress("pdf", len(m.Cards)*max(opts.copies, 1), opts)
+	defer prog.finish()
+
+	// Every copy of the deck starts on a new page, so the sets stay apart
+	// after cutting. Positions are recorded from the first copy.
+	for copyIndex := 0; copyIndex < max(opts.copies, 1); copyIndex++ {
+		if r.back != nil && len(opts.copyBackColors) > 0 {
+			back := opts.back
+			back.Color = opts.copyBackColors[copyIndex%len(opts.copyBackColors)]
+			r.back = &back
+		}
+
+		var pagePositions []fpdf.PointType
+		var pageCards []int
+		for i := range m.Cards {
+			card := &m.Cards[i]
+			if i%cardsPerPage == 0 {
+				pdf.AddPage()
+				pagePositions = pagePositions[:0]
+				pageCards = pageCards[:0]
+				if err := r.preparePage(m.Cards[i:min(i+cardsPerPage, len(m.Cards))]); err != nil {
+					return err
+				}
+			}
+
+			col := i % cardsPerRow
+			row := (i / cardsPerRow) % cardsPerCol
+
+			x := opts.pageMargin + opts.bleed + float64(col)*(slotW+opts.gutter)
+			y := opts.pageMargin + opts.bleed + float64(row)*(slotH+opts.gutter)
+
+			slog.Debug("Processing card", "copy", copyIndex+1, "index", i, "x", x, "y", y)
+
+			if opts.roundCards {
+				if err := r.processRoundCard(x, y, card.Placements); err != nil {
+					return fmt.Errorf("failed to process round card %d: %w", i, err)
+				}
+			} else {
+				if err := r.processSquareCard(x, y, card.Placements); err != nil {
+					return fmt.Errorf("failed to process square card %d: %w", i, err)
+				}
+			}
+
+			for _, s := range card.Placements {
+				r.cache.done(s.File)
+			}
+
+			r.drawCropMarks(x, y)
+			r.drawSafeZone(x, y)
+			if opts.cardNumbers == "front" {
+				r.drawCardLabel(x, y, card.Index, false)
+			}
+			if opts.qrCodes == "front" {
+				if err := r.drawCardQR(x, y, card.Index); err != nil {
+					return err
+				}
+			}
+			if err := prog.step(len(card.Placements)); err != nil {
+				return err
+			}
+
+			if copyIndex == 0 {
+				card.Page = pdf.PageNo()
+				card.X, card.Y = x, y
+			}
+
+			pagePositions = append(pagePositions, fpdf.PointType{X: x, Y: y})
+			pageCards = append(pageCards, card.Index)
+			if r.back != nil && (i%cardsPerPage == cardsPerPage-1 || i == len(m.Cards)-1) {
+				if err := r.drawBackPage(pagePositions, pageCards); err != nil {
+					return fmt.Errorf("failed to draw card backs: %w", err)
+				}
+			}
+		}
+	}
+
+	if opts.tuckBox && opts.tuckBoxFile == "" {
+		if err := addTuckBoxPage(pdf, newTuckBox(m, opts.cardThickness), opts); err != nil {
+			return err
+		}
+	}
+	m.Pages = pdf.PageCount()
+
+	if profile == nil {
+		return writeAtomic(opts.output, func(w io.Writer) error {
+			defer pdf.Close()
+			return pdf.Output(w)
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return err
+	}
+	doc, err := addOutputIntent(buf.Bytes(), profile, condition)
+	if err != nil {
+		return fmt.Errorf("failed to add output intent: %w", err)
+	}
+	return writeAtomic(opts.output, func(w io.Writer) error {
+		_, err := w.Write(doc)
+		return err
+	})
+}
+
+func (r *renderer) processRoundCard(x, y float64, symbols []ManifestSymbol) error {
+	r.drawCutLine(x, y)
+	return r.drawSymbols(x, y, symbols)
+}
+
+func (r *renderer) processSquareCard(x, y float64, symbols []ManifestSymbol) error {
+	r.drawCutLine(x, y)
+	return r.drawSymbols(x, y, symbols)
+}
+
+// drawSymbols draws the planned symbols relative to the card origin (x, y).
+func (r *renderer) drawSymbols(x, y float64, symbols []ManifestSymbol) error {
+	for _, s := range symbols {
+		if r.opts.labels {
+			if err := r.drawLabeledSymbol(x, y, s); err != nil {
+				return err
+			}
+			continue
+		}
+		if r.opts.tactile {
+			if err := r.drawTactileSymbol(x, y, s); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := r.processImage(s.File, x+s.X, y+s.Y, s.Size, s.Rotation, s.SymbolLook); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *renderer) processImage(imgFile string, x, y, imgSize float64, rotation int, look SymbolLook) error {
+	hash, src, err := r.cache.source(imgFile)
+	if err != nil {
+		return err
+	}
+
+	if look.Mirror {
+		r.pdf.TransformBegin()
+		defer r.pdf.TransformEnd()
+		r.pdf.TransformMirrorHorizontal(x + imgSize/2)
+	}
+
+	if r.drawsVector(src, look) {
+		stop := bench.start(phaseEmbed)
+		ink, err := parseHexColor(look.Tint)
+		if err != nil {
+			ink = color.RGBA{A: 0xff}
+		}
+		c := look.adjust(color.NRGBA{R: ink.R, G: ink.G, B: ink.B, A: 0xff})
+		if r.icc != nil {
+			c = r.icc.convertColor(c)
+		}
+		drawSVG(r.pdf, src.svg, x, y, imgSize, rotation, color.RGBA{R: c.R, G: c.G, B: c.B, A: 0xff})
+		stop()
+		return nil
+	}
+
+	key := r.symbolKey(hash, src, imgSize, rotation, look)
+
+	// JPEG has no transparency, so opaque symbols are embedded upright and
+	// rotated by the PDF instead of leaving transparent corners.
+	if r.opts.jpegQuality > 0 {
+		upright := key
+		upright.rotation = 0
+		rend, err := r.rendition(src, upright)
+		if err != nil {
+			return err
+		}
+		if rend.imageType == "JPEG" {
+			w, h := fitRotated(rend.bounds, rotation, imgSize)
+			cx, cy := x+imgSize/2, y+imgSize/2
+			r.pdf.TransformBegin()
+			r.pdf.TransformRotate(float64(rotation), cx, cy)
+			err := r.keepTemp(imgFile, upright, rend)
+			if err == nil {
+				err = r.embedRendition(upright, rend, cx-w/2, cy-h/2, w, h)
+			}
+			r.pdf.TransformEnd()
+			return err
+		}
+	}
+
+	rend, err := r.rendition(src, key)
+	if err != nil {
+		return err
+	}
+
+	if err := r.keepTemp(imgFile, key, rend); err != nil {
+		return err
+	}
+
+	// Symbols keep their aspect ratio, centered in the square box.
+	w, h := fitBox(rend.bounds, imgSize)
+	return r.embedRendition(key, rend, x+(imgSize-w)/2, y+(imgSize-h)/2, w, h)
+}
+
+// drawsVector reports whether an SVG symbol is embedded as vectors. Shape
+// badges of --color-shapes need the raster path.
+func (r *renderer) drawsVector(src *symbolSource, look SymbolLook) bool {
+	return src.svg != nil && !r.opts.svgRaster && (!r.opts.colorShapes || look.Tint == "")
+}
+
+// symbolKey returns the key of the rendition of a raster symbol drawn at
+// imgSize mm.
+func (r *renderer) symbolKey(hash string, src *symbolSource, imgSize float64, rotation int, look SymbolLook) renditionKey {
+	key := renditionKey{hash: hash, sizePx: mmToPx(imgSize, r.opts.imageDPI), rotation: rotation}
+	// Mirroring is done by the PDF, and tints only change monochrome symbols.
+	look.Mirror = false
+	if look.Tint != "" && !src.monochrome() {
+		look.Tint = ""
+	}
+	key.look = look
+	if src.svg != nil {
+		key.sizePx = int(imgSize / mmPerInch * r.opts.svgDPI)
+	} else {
+		// Scaling a small image up only makes the file bigger.
+		b := src.img.Bounds()
+		key.sizePx = min(key.sizePx, max(b.Dx(), b.Dy()))
+	}
+	return key
+}
+
+// rendition returns the processed symbol for key, rendering and encoding it
+// on first use.
+func (r *renderer) rendition(src *symbolSource, key renditionKey) (rendition, error) {
+	if rend, ok := r.cache.renditions[key]; ok {
+		return rend, nil
+	}
+
+	rend, err := r.render(src, key)
+	if err != nil {
+		return rendition{}, err
+	}
+	r.cache.renditions[key] = rend
+
+	return rend, nil
+}
+
+// render processes the symbol for key. It does not touch the cache, so
+// several symbols can be rendered concurrently.
+func (r *renderer) render(src *symbolSource, key renditionKey) (rendition, error) {
+	img := key.look.apply(src.render(key.sizePx, key.rotation, r.opts.resampleFilter()), src)
+	img = r.opts.applyInkMode(r.opts.applyAccessibility(img, key.look, src))
+	if r.icc != nil {
+		img = r.icc.convert(img)
+	}
+	if r.opts.printReady {
+		img = flatten(img, color.White)
+	} else {
+		img = matteTransparent(img, color.White)
+	}
+	return encodeRendition(img, r.opts.jpegQuality)
+}
+
+// embedRendition places a cached rendition on the current page. fpdf keeps
+// its own copy of registered images, so the encoded data is dropped from the
+// cache afterwards and later uses only refer to the image by name.
+func (r *renderer) embedRendition(key renditionKey, rend rendition, x, y, w, h float64) error {
+	if err := r.embed(rend, x, y, w, h); err != nil {
+		return err
+	}
+	rend.data = nil
+	r.cache.renditions[key] = rend
+	return nil
+}
+
+// preparePage decodes and renders the symbols of the cards on the next page
+// on up to opts.jobs goroutines, so drawing the page only hits the cache.
+func (r *renderer) preparePage(cards []ManifestCard) error {
+	var paths []string
+	for _, card := range cards {
+		for _, s := range card.Placements {
+			paths = append(paths, s.File)
+		}
+	}
+	if err := r.cache.load(paths, r.opts.jobs); err != nil {
+		return err
+	}
+
+	var keys []renditionKey
+	var sources []*symbolSource
+	for _, card := range cards {
+		for _, s := range card.Placements {
+			hash, src, err := r.cache.source(s.File)
+			if err != nil {
+				return err
+			}
+			if r.drawsVector(src, s.SymbolLook) {
+				continue
+			}
+
+			key := r.symbolKey(hash, src, s.Size, s.Rotation, s.SymbolLook)
+			if r.opts.jpegQuality > 0 {
+				key.rotation = 0
+			}
+			if _, ok := r.cache.renditions[key]; ok || slices.Contains(keys, key) {
+				continue
+			}
+			keys = append(keys, key)
+			sources = append(sources, src)
+		}
+	}
+
+	rends := make([]rendition, len(keys))
+	err := parallel(len(keys), r.opts.jobs, func(i int) error {
+		var err error
+		rends[i], err = r.render(sources[i], keys[i])
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for i, key := range keys {
+		r.cache.renditions[key] = rends[i]
+	}
+	return nil
+}
+
+// encodeRendition encodes img as JPEG with the given quality if it is
+// opaque and quality is set, and as PNG otherwise.
+func encodeRendition(img image.Image, quality int) (rendition, error) {
+	defer bench.start(phaseEncode)()
+
+	var buf bytes.Buffer
+	if o, ok := img.(interface{ Opaque() bool }); ok && quality > 0 && o.Opaque() {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return rendition{}, fmt.Errorf("failed to encode processed image: %w", err)
+		}
+		return newRendition(buf.Bytes(), img.Bounds(), "JPEG"), nil
+	}
+
+	if err := png.Encode(&buf, img); err != nil {
+		return rendition{}, fmt.Errorf("failed to encode processed image: %w", err)
+	}
+	return newRendition(buf.Bytes(), img.Bounds(), "PNG"), nil
+}
+
+// fitRotated returns the size of an upright image with the given bounds
+// scaled so that, rotated by rotation degrees, it fits a square of the given
+// edge length.
+func fitRotated(bounds image.Rectangle, rotation int, size float64) (float64, float64) {
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	if w <= 0 || h <= 0 {
+		return size, size
+	}
+	rad := float64(rotation) * math.Pi / 180
+	sin, cos := math.Abs(math.Sin(rad)), math.Abs(math.Cos(rad))
+	scale := size / math.Max(w*cos+h*sin, w*sin+h*cos)
+	return w * scale, h * scale
+}
+
+// embedImage encodes img as PNG in memory and places it on the current page.
+func (r *renderer) embedImage(img image.Image, x, y, w, h float64) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return fmt.Errorf("failed to encode processed image: %w", err)
+	}
+
+	return r.embed(newRendition(buf.Bytes(), img.Bounds(), "PNG"), x, y, w, h)
+}
+
+// embed places the encoded image on the current page. Images are registered
+// with the PDF under their content hash, so an image drawn many times, like a
+// symbol rendition or a card back, is stored once and referenced.
+func (r *renderer) embed(rend rendition, x, y, w, h float64) error {
+	defer bench.start(phaseEmbed)()
+
+	options := fpdf.ImageOptions{ImageType: rend.imageType}
+
+	r.pdf.RegisterImageOptionsReader(rend.name, options, bytes.NewReader(rend.data))
+	if err := r.pdf.Error(); err != nil {
+		return fmt.Errorf("failed to embed image: %w", err)
+	}
+
+	r.pdf.ImageOptions(rend.name, x, y, w, h, false, options, 0, "")
+
+	return nil
+}
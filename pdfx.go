@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// srgbConditionName identifies the built-in profile in the output intent.
+const srgbConditionName = "sRGB IEC61966-2.1"
+
+var (
+	pdfTrailerSize = regexp.MustCompile(`/Size (\d+)`)
+	pdfTrailerRoot = regexp.MustCompile(`/Root (\d+) 0 R`)
+	pdfTrailerInfo = regexp.MustCompile(`/Info (\d+) 0 R`)
+	pdfStartXref   = regexp.MustCompile(`startxref\s+(\d+)\s+%%EOF\s*$`)
+)
+
+// outputProfile returns the ICC profile to embed and the name of its
+// printing condition: the file at path, or the built-in sRGB profile.
+func outputProfile(path string) ([]byte, string, error) {
+	if path == "" {
+		return srgbProfile(), srgbConditionName, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read ICC profile: %w", err)
+	}
+	if len(data) < 128 || string(data[36:40]) != "acsp" {
+		return nil, "", fmt.Errorf("%s is not an ICC profile", path)
+	}
+	if _, err := iccComponents(data); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	return data, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), nil
+}
+
+// iccComponents returns the number of color components of the data color
+// space of an ICC profile, the /N of its stream. Output intents describe
+// RGB, CMYK or gray printing conditions, so other spaces are rejected.
+func iccComponents(profile []byte) (int, error) {
+	switch space := string(profile[16:20]); space {
+	case "RGB ":
+		return 3, nil
+	case "CMYK":
+		return 4, nil
+	case "GRAY":
+		return 1, nil
+	default:
+		return 0, fmt.Errorf("unsupported ICC color space %q; use an RGB, CMYK or gray profile", strings.TrimSpace(space))
+	}
+}
+
+// pdfObject returns the dictionary of object num, without its closing >>.
+func pdfObject(doc []byte, num int) (string, error) {
+	start := bytes.LastIndex(doc, []byte(fmt.Sprintf("\n%d 0 obj\n", num)))
+	if start < 0 {
+		return "", fmt.Errorf("object %d not found", num)
+	}
+	body := doc[start:]
+	body = body[bytes.IndexByte(body[1:], '\n')+2:]
+	end := bytes.Index(body, []byte("\nendobj"))
+	if end < 0 {
+		return "", fmt.Errorf("object %d is not terminated", num)
+	}
+	dict := strings.TrimSpace(string(body[:end]))
+	if !strings.HasSuffix(dict, ">>") {
+		return "", fmt.Errorf("object %d is not a dictionary", num)
+	}
+	return strings.TrimSuffix(dict, ">>"), nil
+}
+
+// addOutputIntent embeds the ICC profile as the output intent of the
+// catalog of a finished fpdf document. fpdf has no API for it, so the change
+// is appended as an incremental update, which leaves the original objects
+// untouched. The document does not claim PDF/X conformance: the core fonts
+// fpdf uses for labels are not embedded.
+func addOutputIntent(doc, profile []byte, condition string) ([]byte, error) {
+	components, err := iccComponents(profile)
+	if err != nil {
+		return nil, err
+	}
+	tail := doc[max(len(doc)-1024, 0):]
+	size, err1 := pdfTrailerInt(pdfTrailerSize, tail)
+	root, err2 := pdfTrailerInt(pdfTrailerRoot, tail)
+	info, err3 := pdfTrailerInt(pdfTrailerInfo, tail)
+	prev, err4 := pdfTrailerInt(pdfStartXref, tail)
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		return nil, fmt.Errorf("unexpected PDF trailer: %w", err)
+	}
+
+	catalog, err := pdfObject(doc, root)
+	if err != nil {
+		return nil, err
+	}
+
+	out := bytes.NewBuffer(doc)
+	if !bytes.HasSuffix(doc, []byte("\n")) {
+		out.WriteByte('\n')
+	}
+	offsets := make(map[int]int)
+	writeObj := func(num int, body string) {
+		offsets[num] = out.Len()
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", num, body)
+	}
+
+	iccNum, intentNum := size, size+1
+	offsets[iccNum] = out.Len()
+	fmt.Fprintf(out, "%d 0 obj\n<< /N %d /Length %d >>\nstream\n", iccNum, components, len(profile))
+	out.Write(profile)
+	out.WriteString("\nendstream\nendobj\n")
+
+	writeObj(intentNum, fmt.Sprintf("<< /Type /OutputIntent /S /GTS_PDFX /OutputConditionIdentifier %s /Info %[1]s /RegistryName (http://www.color.org) /DestOutputProfile %d 0 R >>",
+		pdfString(condition), iccNum))
+	writeObj(root, fmt.Sprintf("%s/OutputIntents [%d 0 R]\n>>", catalog, intentNum))
+
+	xref := out.Len()
+	out.WriteString("xref\n")
+	for _, num := range []int{root, iccNum, intentNum} {
+		fmt.Fprintf(out, "%d 1\n%010d 00000 n \n", num, offsets[num])
+	}
+	fmt.Fprintf(out, "trailer\n<<\n/Size %d\n/Root %d 0 R\n/Info %d 0 R\n/Prev %d\n>>\nstartxref\n%d\n%%%%EOF\n",
+		intentNum+1, root, info, prev, xref)
+
+	return out.Bytes(), nil
+}
+
+func pdfTrailerInt(re *regexp.Regexp, tail []byte) (int, error) {
+	m := re.FindAllSubmatch(tail, -1)
+	if m == nil {
+		return 0, fmt.Errorf("missing %s", re)
+	}
+	return strconv.Atoi(string(m[len(m)-1][1]))
+}
+
+// pdfString encodes s as a PDF literal string.
+func pdfString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`)
+	return "(" + r.Replace(s) + ")"
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// placeholderSymbol draws symbol number i (1-based) of count as a colored
+// disc with its number. The number also shows how the symbol was rotated.
+func placeholderSymbol(face font.Face, i, count, sizePx int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, sizePx, sizePx))
+
+	// Spread the hues evenly so neighbouring numbers get different colors.
+	hue := float64(i-1) / float64(max(count, 1))
+	bg := hsvColor(hue, 0.65, 0.85)
+	r := float64(sizePx) / 2
+	mask := &circleMask{cx: r, cy: r, r: r, inner: -1, bounds: img.Bounds()}
+	draw.DrawMask(img, img.Bounds(), image.NewUniform(bg), image.Point{}, mask, image.Point{}, draw.Over)
+
+	label := strconv.Itoa(i)
+	bounds, _ := font.BoundString(face, label)
+	w := (bounds.Max.X - bounds.Min.X).Ceil()
+	h := (bounds.Max.Y - bounds.Min.Y).Ceil()
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.White,
+		Face: face,
+		Dot:  fixed.P((sizePx-w)/2-bounds.Min.X.Floor(), (sizePx-h)/2-bounds.Min.Y.Floor()),
+	}
+	d.DrawString(label)
+
+	return img
+}
+
+// hsvColor converts a hue in [0, 1) and saturation and value in [0, 1] to
+// RGB.
+func hsvColor(h, s, v float64) color.RGBA {
+	h = math.Mod(h, 1) * 6
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h, 2)-1))
+	var r, g, b float64
+	switch int(h) {
+	case 0:
+		r, g = c, x
+	case 1:
+		r, g = x, c
+	case 2:
+		g, b = c, x
+	case 3:
+		g, b = x, c
+	case 4:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := v - c
+	return color.RGBA{uint8((r + m) * 255), uint8((g + m) * 255), uint8((b + m) * 255), 255}
+}
+
+// generatePlaceholders writes count numbered placeholder symbols as PNGs to
+// dir and returns their paths.
+func generatePlaceholders(dir string, count int) ([]string, error) {
+	face, err := loadFontFace("", proceduralSymbolPx*0.45)
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create symbol directory: %w", err)
+	}
+
+	files := make([]string, count)
+	for i := range files {
+		path := filepath.Join(dir, fmt.Sprintf("placeholder_%03d.png", i+1))
+		if err := writePNG(path, placeholderSymbol(face, i+1, count, proceduralSymbolPx), generatedSymbolDPI); err != nil {
+			return nil, err
+		}
+		files[i] = path
+	}
+
+	return files, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// exportPNGs writes every card of the planned deck as its own PNG file.
+func exportPNGs(m *Manifest, opts options) error {
+	if err := os.MkdirAll(opts.pngDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create PNG directory: %w", err)
+	}
+
+	cache := newSymbolCache()
+	prog := newProgress("png", len(m.Cards), opts)
+	defer prog.finish()
+	for _, card := range m.Cards {
+		img, err := rasterizeCard(card, opts, cache, opts.pngDPI)
+		if err != nil {
+			return fmt.Errorf("failed to render card %d: %w", card.Index, err)
+		}
+
+		path := filepath.Join(opts.pngDir, cardFileName(card.Index, "png"))
+		if err := writePNG(path, img, opts.pngDPI); err != nil {
+			return err
+		}
+
+		slog.Debug("Card exported", "index", card.Index, "path", path)
+		if err := prog.step(len(card.Placements)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writePNG writes img through a temporary file, so an interrupted run never
+// leaves a truncated card behind.
+func writePNG(path string, img image.Image, dpi float64) error {
+	err := writeAtomic(path, func(w io.Writer) error {
+		return encodePNGWithDPI(w, img, dpi)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// encodePNGWithDPI encodes img as PNG and adds a pHYs chunk so that image
+// editors and print services pick up the intended resolution.
+func encodePNGWithDPI(w io.Writer, img image.Image, dpi float64) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+
+	// The 8-byte signature is followed by the 25-byte IHDR chunk; pHYs has
+	// to come before the image data, so it goes right after IHDR.
+	const ihdrEnd = 8 + 25
+	data := buf.Bytes()
+
+	ppm := uint32(dpi / mmPerInch * 1000)
+	chunk := make([]byte, 0, 21)
+	chunk = binary.BigEndian.AppendUint32(chunk, 9)
+	chunk = append(chunk, "pHYs"...)
+	chunk = binary.BigEndian.AppendUint32(chunk, ppm)
+	chunk = binary.BigEndian.AppendUint32(chunk, ppm)
+	chunk = append(chunk, 1) // unit: meter
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	for _, part := range [][]byte{data[:ihdrEnd], chunk, data[ihdrEnd:]} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// terminalDPI is the resolution cards are rasterized at for the terminal,
+// before they are scaled to the requested width.
+const terminalDPI = 100
+
+// terminalProtocols lists the values accepted by preview --protocol.
+var terminalProtocols = []string{"auto", "ansi", "sixel", "kitty"}
+
+// runPreview implements the preview command. It draws cards of a saved deck
+// in the terminal, so layouts can be checked without a PDF viewer.
+func runPreview(args []string) error {
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dobble preview [flags] <manifest.json> [cards]")
+		fmt.Fprintln(fs.Output(), "\nCards are numbered from 1, e.g. 3,7,12-14 (default: 1).")
+		fs.PrintDefaults()
+	}
+	protocol := fs.String("protocol", "auto", "terminal graphics: "+strings.Join(terminalProtocols, ", ")+" (auto uses kitty where supported, ANSI colors elsewhere)")
+	width := fs.Int("width", 40, "width of each card in terminal columns")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || fs.NArg() > 2 || *width < 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a manifest file and an optional list of cards")
+	}
+	if *protocol == "auto" {
+		*protocol = detectTerminalProtocol()
+	}
+
+	m, err := loadManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	cards := "1"
+	if fs.NArg() == 2 {
+		cards = fs.Arg(1)
+	}
+	indices, err := parseCardList(cards, len(m.Cards))
+	if err != nil {
+		return err
+	}
+
+	opts := defaultOptions()
+	m.applyTo(&opts)
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	cache := newSymbolCache()
+	for _, idx := range indices {
+		img, err := rasterizeCard(m.Cards[idx], opts, cache, terminalDPI)
+		if err != nil {
+			return fmt.Errorf("failed to render card %d: %w", idx+1, err)
+		}
+
+		fmt.Fprintf(out, "Card %d\n", idx+1)
+		switch *protocol {
+		case "ansi":
+			writeANSI(out, img, *width)
+		case "sixel":
+			writeSixel(out, img, *width)
+		case "kitty":
+			err = writeKitty(out, img, *width)
+		default:
+			return fmt.Errorf("unknown terminal protocol %q", *protocol)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out)
+	}
+	return nil
+}
+
+// detectTerminalProtocol picks the kitty graphics protocol in terminals known
+// to support it. Sixel support cannot be detected without querying the
+// terminal, so it has to be requested.
+func detectTerminalProtocol() string {
+	if os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("TERM") == "xterm-kitty" ||
+		os.Getenv("TERM_PROGRAM") == "WezTerm" || os.Getenv("TERM_PROGRAM") == "ghostty" {
+		return "kitty"
+	}
+	return "ansi"
+}
+
+// writeANSI draws img with 24-bit colored half blocks, two pixels per
+// character cell. Transparent pixels keep the terminal background.
+func writeANSI(w io.Writer, img image.Image, cols int) {
+	img = imaging.Resize(img, cols, 0, imaging.Box)
+	b := img.Bounds()
+	opaque := func(c color.NRGBA) bool { return c.A >= 0x80 }
+
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			top := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			bottom := color.NRGBA{}
+			if y+1 < b.Max.Y {
+				bottom = color.NRGBAModel.Convert(img.At(x, y+1)).(color.NRGBA)
+			}
+
+			switch {
+			case opaque(top) && opaque(bottom):
+				fmt.Fprintf(w, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", top.R, top.G, top.B, bottom.R, bottom.G, bottom.B)
+			case opaque(top):
+				fmt.Fprintf(w, "\x1b[49m\x1b[38;2;%d;%d;%dm▀", top.R, top.G, top.B)
+			case opaque(bottom):
+				fmt.Fprintf(w, "\x1b[49m\x1b[38;2;%d;%d;%dm▄", bottom.R, bottom.G, bottom.B)
+			default:
+				fmt.Fprint(w, "\x1b[0m ")
+			}
+		}
+		fmt.Fprintln(w, "\x1b[0m")
+	}
+}
+
+// sixelCellWidth is the assumed width of a terminal cell in pixels, used to
+// size sixel images, which are measured in pixels rather than cells.
+const sixelCellWidth = 10
+
+// writeSixel draws img as a sixel image with a 256-color palette.
+// Transparent pixels are left out, so they keep the terminal background.
+func writeSixel(w io.Writer, img image.Image, cols int) {
+	img = imaging.Resize(img, cols*sixelCellWidth, 0, imaging.Lanczos)
+	b := img.Bounds()
+	pal := image.NewPaletted(b, palette.Plan9)
+	draw.FloydSteinberg.Draw(pal, b, img, b.Min)
+
+	fmt.Fprintf(w, "\x1bP0;1;0q\"1;1;%d;%d", b.Dx(), b.Dy())
+	for i, c := range palette.Plan9 {
+		r, g, bl, _ := c.RGBA()
+		fmt.Fprintf(w, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, bl*100/0xffff)
+	}
+
+	transparent := func(x, y int) bool {
+		_, _, _, a := img.At(x, y).RGBA()
+		return a < 0x8000
+	}
+	row := make([]byte, b.Dx())
+	for band := b.Min.Y; band < b.Max.Y; band += 6 {
+		var used [256]bool
+		for y := band; y < min(band+6, b.Max.Y); y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if !transparent(x, y) {
+					used[pal.ColorIndexAt(x, y)] = true
+				}
+			}
+		}
+
+		for c := range used {
+			if !used[c] {
+				continue
+			}
+			for x := b.Min.X; x < b.Max.X; x++ {
+				var bits byte
+				for y := band; y < min(band+6, b.Max.Y); y++ {
+					if int(pal.ColorIndexAt(x, y)) == c && !transparent(x, y) {
+						bits |= 1 << (y - band)
+					}
+				}
+				row[x-b.Min.X] = '?' + bits
+			}
+			fmt.Fprintf(w, "#%d", c)
+			writeSixelRuns(w, row)
+			fmt.Fprint(w, "$")
+		}
+		fmt.Fprint(w, "-")
+	}
+	fmt.Fprint(w, "\x1b\\")
+}
+
+// writeSixelRuns writes a row of sixels with repeats run-length encoded.
+func writeSixelRuns(w io.Writer, row []byte) {
+	for i := 0; i < len(row); {
+		n := 1
+		for i+n < len(row) && row[i+n] == row[i] {
+			n++
+		}
+		if n > 3 {
+			fmt.Fprintf(w, "!%d%c", n, row[i])
+		} else {
+			w.Write(bytes.Repeat(row[i:i+1], n))
+		}
+		i += n
+	}
+}
+
+// kittyChunk is the largest base64 payload of one kitty graphics escape.
+const kittyChunk = 4096
+
+// writeKitty sends img as PNG with the kitty graphics protocol, scaled by
+// the terminal to the given number of columns.
+func writeKitty(w io.Writer, img image.Image, cols int) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return fmt.Errorf("failed to encode preview: %w", err)
+	}
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	for i := 0; i < len(data); i += kittyChunk {
+		end := min(i+kittyChunk, len(data))
+		more := 0
+		if end < len(data) {
+			more = 1
+		}
+		if i == 0 {
+			fmt.Fprintf(w, "\x1b_Gf=100,a=T,c=%d,m=%d;%s\x1b\\", cols, more, data[i:end])
+		} else {
+			fmt.Fprintf(w, "\x1b_Gm=%d;%s\x1b\\", more, data[i:end])
+		}
+	}
+	return nil
+}
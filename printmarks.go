@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	cropMarkOffset = 1.0 // gap between the bleed edge and the start of a crop mark
+	cropMarkLength = 3.0
+	cropMarkWidth  = 0.1
+
+	hairlineWidth    = 0.05
+	cornerTickLength = 3.0  // length of corner ticks along each edge in mm
+	cornerTickAngle  = 10.0 // half the arc of a tick on round cards in degrees
+)
+
+// cutLineStyles lists the values accepted by --cut-lines.
+var cutLineStyles = []string{"solid", "dashed", "hairline", "corners", "none"}
+
+// cutLines configures the outline drawn along the trim edge of every card.
+type cutLines struct {
+	Style string
+	Color color.RGBA
+	Width float64 // mm; 0 picks the default for the style
+}
+
+func (c cutLines) validate() error {
+	if !slices.Contains(cutLineStyles, c.Style) {
+		return fmt.Errorf("unknown cut line style %q", c.Style)
+	}
+	if c.Width < 0 {
+		return fmt.Errorf("cut line width must not be negative")
+	}
+	return nil
+}
+
+// drawCutLine outlines the trim edge of the card at (x, y) in the configured
+// style. Corner ticks mark only the corners of the card, or the four
+// quadrant points of round ones, so no line shows on a cut card.
+func (r *renderer) drawCutLine(x, y float64) {
+	c := r.opts.cutLines
+	if c.Style == "none" {
+		return
+	}
+
+	width := c.Width
+	if width == 0 {
+		width = defaultLineWidth
+		if c.Style == "hairline" {
+			width = hairlineWidth
+		}
+	}
+
+	pdf := r.pdf
+	pdf.SetDrawColor(int(c.Color.R), int(c.Color.G), int(c.Color.B))
+	pdf.SetLineWidth(width)
+	if c.Style == "dashed" {
+		pdf.SetDashPattern([]float64{2, 1}, 0)
+	}
+
+	w, h := r.opts.cardSize()
+	switch {
+	case c.Style != "corners":
+		r.cardOutline(x, y, 0, "D")
+	case r.opts.roundCards:
+		for _, a := range []float64{0, 90, 180, 270} {
+			pdf.Arc(x+w/2, y+h/2, w/2, h/2, 0, a-cornerTickAngle, a+cornerTickAngle, "D")
+		}
+	default:
+		// Ticks run from every corner along both adjacent edges. Rounded
+		// cards are marked at the corners of their box.
+		corners := []fpdf.PointType{{X: x, Y: y}, {X: x + w, Y: y}, {X: x + w, Y: y + h}, {X: x, Y: y + h}}
+		if r.opts.hexCards {
+			corners = hexPoints(x, y, w, h)
+		}
+		for i, p := range corners {
+			for _, q := range []fpdf.PointType{corners[(i+1)%len(corners)], corners[(i+len(corners)-1)%len(corners)]} {
+				t := cornerTickLength / math.Hypot(q.X-p.X, q.Y-p.Y)
+				pdf.Line(p.X, p.Y, p.X+(q.X-p.X)*t, p.Y+(q.Y-p.Y)*t)
+			}
+		}
+	}
+
+	pdf.SetDashPattern([]float64{}, 0)
+	pdf.SetLineWidth(defaultLineWidth)
+	pdf.SetDrawColor(0, 0, 0)
+}
+
+// drawCropMarks draws short cut marks in line with the trim edges of the card
+// at (x, y), just outside the bleed area. Round cards are marked at the
+// corners of their bounding square.
+func (r *renderer) drawCropMarks(x, y float64) {
+	if !r.opts.cropMarks {
+		return
+	}
+
+	w, h := r.opts.cardSize()
+	start := r.opts.bleed + cropMarkOffset
+	end := start + cropMarkLength
+
+	pdf := r.pdf
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(cropMarkWidth)
+
+	for _, cx := range []float64{x, x + w} {
+		pdf.Line(cx, y-start, cx, y-end)
+		pdf.Line(cx, y+h+start, cx, y+h+end)
+	}
+	for _, cy := range []float64{y, y + h} {
+		pdf.Line(x-start, cy, x-end, cy)
+		pdf.Line(x+w+start, cy, x+w+end, cy)
+	}
+
+	pdf.SetLineWidth(defaultLineWidth)
+}
+
+// drawSafeZone outlines the area inside which content is safe from cutting
+// tolerances, as a dashed guide inset from the trim edge.
+func (r *renderer) drawSafeZone(x, y float64) {
+	inset := r.opts.safeZone
+	if inset <= 0 {
+		return
+	}
+
+	pdf := r.pdf
+	pdf.SetDrawColor(160, 160, 160)
+	pdf.SetLineWidth(cropMarkWidth)
+	pdf.SetDashPattern([]float64{1, 1}, 0)
+
+	r.cardOutline(x, y, inset, "D")
+
+	pdf.SetDashPattern([]float64{}, 0)
+	pdf.SetLineWidth(defaultLineWidth)
+	pdf.SetDrawColor(0, 0, 0)
+}
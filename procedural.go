@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/image/vector"
+)
+
+// proceduralSymbolPx is the edge length of generated symbol images.
+const proceduralSymbolPx = 512
+
+// proceduralShapes are the outlines of generated symbols as polygons in the
+// unit square centered on the origin.
+var proceduralShapes = []func() [][2]float64{
+	func() [][2]float64 { return regularPolygon(48, 1, 0) },           // circle
+	func() [][2]float64 { return regularPolygon(4, 1.3, math.Pi/4) },  // square
+	func() [][2]float64 { return regularPolygon(3, 1.1, -math.Pi/2) }, // triangle
+	func() [][2]float64 { return regularPolygon(4, 1, -math.Pi/2) },   // diamond
+	func() [][2]float64 { return regularPolygon(5, 1, -math.Pi/2) },   // pentagon
+	func() [][2]float64 { return regularPolygon(6, 1, 0) },            // hexagon
+	func() [][2]float64 { return star(5, 1, 0.45) },
+	func() [][2]float64 { // cross
+		const a, b = 0.35, 0.95
+		return [][2]float64{{-a, -b}, {a, -b}, {a, -a}, {b, -a}, {b, a}, {a, a}, {a, b}, {-a, b}, {-a, a}, {-b, a}, {-b, -a}, {-a, -a}}
+	},
+}
+
+// proceduralColors are clearly distinguishable on white card stock.
+var proceduralColors = []color.RGBA{
+	{0xd6, 0x27, 0x28, 0xff}, // red
+	{0xff, 0x7f, 0x0e, 0xff}, // orange
+	{0xc9, 0xa2, 0x00, 0xff}, // gold
+	{0x2c, 0xa0, 0x2c, 0xff}, // green
+	{0x17, 0xbe, 0xcf, 0xff}, // cyan
+	{0x1f, 0x4e, 0xb4, 0xff}, // blue
+	{0x94, 0x67, 0xbd, 0xff}, // purple
+	{0x33, 0x33, 0x33, 0xff}, // charcoal
+}
+
+// proceduralStyles: solid, outlined and solid with a hole in the middle.
+const proceduralStyles = 3
+
+// maxProceduralSymbols is the number of distinct symbols the generator can
+// produce.
+var maxProceduralSymbols = len(proceduralShapes) * len(proceduralColors) * proceduralStyles
+
+func regularPolygon(sides int, radius, phase float64) [][2]float64 {
+	pts := make([][2]float64, sides)
+	for i := range pts {
+		a := phase + 2*math.Pi*float64(i)/float64(sides)
+		pts[i] = [2]float64{radius * math.Cos(a), radius * math.Sin(a)}
+	}
+	return pts
+}
+
+func star(points int, outer, inner float64) [][2]float64 {
+	pts := make([][2]float64, 2*points)
+	for i := range pts {
+		r := outer
+		if i%2 == 1 {
+			r = inner
+		}
+		a := -math.Pi/2 + math.Pi*float64(i)/float64(points)
+		pts[i] = [2]float64{r * math.Cos(a), r * math.Sin(a)}
+	}
+	return pts
+}
+
+// proceduralSymbol draws the i-th generated symbol. Consecutive indices
+// differ in shape and color, so small decks get the most distinct symbols.
+func proceduralSymbol(i, sizePx int) *image.NRGBA {
+	shapes, colors := len(proceduralShapes), len(proceduralColors)
+	shape := i % shapes
+	block := i / shapes
+	col := proceduralColors[(shape+block)%colors]
+	style := block / colors
+
+	half := float32(sizePx) / 2
+	scale := half * 0.9
+	r := vector.NewRasterizer(sizePx, sizePx)
+	addPolygon := func(pts [][2]float64, k float64, reverse bool) {
+		for j := range pts {
+			p := pts[j]
+			if reverse {
+				p = pts[len(pts)-1-j]
+			}
+			x, y := half+float32(p[0]*k)*scale, half+float32(p[1]*k)*scale
+			if j == 0 {
+				r.MoveTo(x, y)
+			} else {
+				r.LineTo(x, y)
+			}
+		}
+		r.ClosePath()
+	}
+
+	// The rasterizer fills by winding, so a reversed inner path cuts a hole.
+	outline := proceduralShapes[shape]()
+	addPolygon(outline, 1, false)
+	switch style {
+	case 1:
+		addPolygon(outline, 0.6, true)
+	case 2:
+		addPolygon(regularPolygon(32, 0.3, 0), 1, true)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, sizePx, sizePx))
+	r.Draw(img, img.Bounds(), image.NewUniform(col), image.Point{})
+	return img
+}
+
+// generateSymbols writes count generated symbols as PNGs to dir and returns
+// their paths.
+func generateSymbols(dir string, count int) ([]string, error) {
+	if count > maxProceduralSymbols {
+		return nil, fmt.Errorf("cannot generate %d distinct symbols, at most %d", count, maxProceduralSymbols)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create symbol directory: %w", err)
+	}
+
+	files := make([]string, count)
+	for i := range files {
+		path := filepath.Join(dir, fmt.Sprintf("symbol_%03d.png", i+1))
+		if err := writePNG(path, proceduralSymbol(i, proceduralSymbolPx), generatedSymbolDPI); err != nil {
+			return nil, err
+		}
+		files[i] = path
+	}
+
+	return files, nil
+}
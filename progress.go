@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// progressBarWidth is the number of characters of the progress bar.
+const progressBarWidth = 30
+
+// progressLogSteps is the number of progress events logged at info level
+// per export, one every tenth of the cards; the others are debug events.
+const progressLogSteps = 10
+
+// progress reports how far an export has come. In interactive mode it
+// redraws a bar on stderr; otherwise every step is logged as a structured
+// event, so scripts can follow along; only every tenth of the cards at info
+// level. It also stops the export between two cards when the run is
+// cancelled.
+type progress struct {
+	task   string
+	total  int
+	done   int
+	images int
+	start  time.Time
+	bar    io.Writer // nil logs events instead of drawing a bar
+	ctx    context.Context
+}
+
+func newProgress(task string, total int, opts options) *progress {
+	p := &progress{task: task, total: total, start: time.Now(), ctx: opts.context()}
+	if opts.interactive {
+		p.bar = os.Stderr
+	}
+	return p
+}
+
+// step records a finished card with the given number of symbol images. It
+// returns an error wrapping ErrCancelled once the run is cancelled, after
+// which the export must stop.
+func (p *progress) step(images int) error {
+	p.done++
+	p.images += images
+
+	if p.bar == nil {
+		level := slog.LevelDebug
+		if total := max(p.total, 1); p.done*progressLogSteps/total > (p.done-1)*progressLogSteps/total {
+			level = slog.LevelInfo
+		}
+		slog.Log(context.Background(), level, "Progress", "task", p.task, "cards", p.done, "total", p.total,
+			"images", p.images, "eta", p.eta().Round(time.Second))
+	} else {
+		filled := progressBarWidth * p.done / max(p.total, 1)
+		fmt.Fprintf(p.bar, "\r%-4s [%s%s] %d/%d cards, %d images, ETA %s ",
+			p.task, strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled),
+			p.done, p.total, p.images, p.eta().Round(time.Second))
+	}
+
+	if p.ctx.Err() != nil && p.done < p.total {
+		return fmt.Errorf("%w: %s export stopped after %d of %d cards", ErrCancelled, p.task, p.done, p.total)
+	}
+	return nil
+}
+
+// finish ends the bar's line.
+func (p *progress) finish() {
+	if p.bar != nil {
+		fmt.Fprintln(p.bar)
+	}
+}
+
+// eta extrapolates the remaining time from the average time per card so far.
+func (p *progress) eta() time.Duration {
+	if p.done == 0 {
+		return 0
+	}
+	perCard := time.Since(p.start) / time.Duration(p.done)
+	return perCard * time.Duration(p.total-p.done)
+}
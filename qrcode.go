@@ -0,0 +1,413 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// qrQuietZone is the light border around a card's QR code in modules, as
+// the standard asks for.
+const qrQuietZone = 4
+
+// qrVersion is the layout of a QR code version at error correction level M,
+// the only level encoded here.
+type qrVersion struct {
+	size      int // modules per side
+	dataBytes int // data codewords, over all blocks
+	ecBytes   int // error correction codewords per block
+	blocks    int
+	alignment int // center of the alignment pattern, 0 if none
+}
+
+// qrVersions lists versions 1 to 4, which hold up to 62 bytes: plenty for a
+// deck ID and card number.
+var qrVersions = []qrVersion{
+	{size: 21, dataBytes: 16, ecBytes: 10, blocks: 1},
+	{size: 25, dataBytes: 28, ecBytes: 16, blocks: 1, alignment: 18},
+	{size: 29, dataBytes: 44, ecBytes: 26, blocks: 1, alignment: 22},
+	{size: 33, dataBytes: 64, ecBytes: 18, blocks: 2, alignment: 26},
+}
+
+// qrCode is an encoded QR symbol; dark modules are true, indexed [y][x].
+type qrCode struct {
+	size     int
+	modules  [][]bool
+	function [][]bool // finder, timing, alignment and format modules
+}
+
+// encodeQR encodes data in byte mode in the smallest version that holds it,
+// with error correction level M and the mask of lowest penalty.
+func encodeQR(data []byte) (*qrCode, error) {
+	var v qrVersion
+	for _, v = range qrVersions {
+		if len(data) <= v.dataBytes-2 {
+			break
+		}
+	}
+	if len(data) > v.dataBytes-2 {
+		return nil, fmt.Errorf("%d bytes do not fit a QR code of at most %d", len(data), v.dataBytes-2)
+	}
+
+	q := &qrCode{size: v.size}
+	q.modules = make([][]bool, v.size)
+	q.function = make([][]bool, v.size)
+	for y := range v.size {
+		q.modules[y] = make([]bool, v.size)
+		q.function[y] = make([]bool, v.size)
+	}
+	q.drawFunctionPatterns(v)
+	q.drawCodewords(qrCodewords(data, v))
+
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		q.applyMask(mask)
+		q.drawFormat(mask)
+		if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		q.applyMask(mask) // undo
+	}
+	q.applyMask(best)
+	q.drawFormat(best)
+	return q, nil
+}
+
+// qrCodewords returns the data in byte mode, padded to the capacity of v,
+// followed by its error correction, interleaved over the blocks.
+func qrCodewords(data []byte, v qrVersion) []byte {
+	var bits []bool
+	put := func(value, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, value>>i&1 == 1)
+		}
+	}
+	put(0b0100, 4) // byte mode
+	put(len(data), 8)
+	for _, b := range data {
+		put(int(b), 8)
+	}
+	put(0, min(4, v.dataBytes*8-len(bits)))
+	put(0, (8-len(bits)%8)%8)
+
+	codewords := make([]byte, 0, v.dataBytes)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for _, bit := range bits[i : i+8] {
+			b <<= 1
+			if bit {
+				b |= 1
+			}
+		}
+		codewords = append(codewords, b)
+	}
+	for pad := byte(0xec); len(codewords) < v.dataBytes; pad ^= 0xec ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+
+	per := v.dataBytes / v.blocks
+	divisor := reedSolomonDivisor(v.ecBytes)
+	var out []byte
+	for i := range per {
+		for b := range v.blocks {
+			out = append(out, codewords[b*per+i])
+		}
+	}
+	ec := make([][]byte, v.blocks)
+	for b := range v.blocks {
+		ec[b] = reedSolomonRemainder(codewords[b*per:(b+1)*per], divisor)
+	}
+	for i := range v.ecBytes {
+		for b := range v.blocks {
+			out = append(out, ec[b][i])
+		}
+	}
+	return out
+}
+
+// gf256Mul multiplies in GF(2^8) modulo x^8+x^4+x^3+x^2+1, the field of
+// QR error correction.
+func gf256Mul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11d
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// reedSolomonDivisor returns the generator polynomial of the given degree,
+// without its leading coefficient, highest power first.
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gf256Mul(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gf256Mul(root, 2)
+	}
+	return result
+}
+
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gf256Mul(d, factor)
+		}
+	}
+	return result
+}
+
+func (q *qrCode) setFunction(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+func (q *qrCode) drawFunctionPatterns(v qrVersion) {
+	for i := range q.size {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+	for _, c := range [][2]int{{3, 3}, {q.size - 4, 3}, {3, q.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x >= 0 && x < q.size && y >= 0 && y < q.size {
+					d := max(abs(dx), abs(dy))
+					q.setFunction(x, y, d != 2 && d != 4)
+				}
+			}
+		}
+	}
+	if a := v.alignment; a > 0 {
+		for dy := -2; dy <= 2; dy++ {
+			for dx := -2; dx <= 2; dx++ {
+				q.setFunction(a+dx, a+dy, max(abs(dx), abs(dy)) != 1)
+			}
+		}
+	}
+	q.drawFormat(0) // reserves the format modules until the mask is chosen
+}
+
+// drawFormat writes both copies of the format information: level M and the
+// mask, protected by a BCH code.
+func (q *qrCode) drawFormat(mask int) {
+	data := 0b00<<3 | mask // level M
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := range 6 {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+	for i := range 8 {
+		q.setFunction(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, q.size-15+i, bit(i))
+	}
+	q.setFunction(8, q.size-8, true)
+}
+
+// drawCodewords fills the data modules in the zigzag order of the standard,
+// two columns at a time from the bottom right.
+func (q *qrCode) drawCodewords(data []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		upward := (right+1)&2 == 0
+		for vert := range q.size {
+			y := vert
+			if upward {
+				y = q.size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if !q.function[y][x] && i < len(data)*8 {
+					q.modules[y][x] = data[i/8]>>(7-i%8)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask inverts the data modules selected by the mask pattern. Applying
+// it twice undoes it.
+func (q *qrCode) applyMask(mask int) {
+	for y := range q.size {
+		for x := range q.size {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !q.function[y][x] {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the symbol is to scan, by the rules the standard
+// uses to choose a mask: long runs, 2×2 blocks, finder-like patterns and an
+// unbalanced share of dark modules.
+func (q *qrCode) penalty() int {
+	n := q.size
+	at := func(x, y int, transposed bool) bool {
+		if transposed {
+			return q.modules[x][y]
+		}
+		return q.modules[y][x]
+	}
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+
+	penalty := 0
+	for _, transposed := range []bool{false, true} {
+		for y := range n {
+			run := 1
+			for x := 1; x <= n; x++ {
+				if x < n && at(x, y, transposed) == at(x-1, y, transposed) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += 3 + run - 5
+				}
+				run = 1
+			}
+			for x := 0; x+11 <= n; x++ {
+				for _, pattern := range finderLike {
+					match := true
+					for i, dark := range pattern {
+						if at(x+i, y, transposed) != dark {
+							match = false
+							break
+						}
+					}
+					if match {
+						penalty += 40
+					}
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := range n {
+		for x := range n {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				c := q.modules[y][x]
+				if q.modules[y][x+1] == c && q.modules[y+1][x] == c && q.modules[y+1][x+1] == c {
+					penalty += 3
+				}
+			}
+		}
+	}
+	total := n * n
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return penalty + max(k, 0)*10
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// cardQRPayload is the text encoded in the QR code of a card, for apps that
+// identify physical cards: the deck ID and the 1-based card number.
+func cardQRPayload(index int, deckID string) string {
+	return "DOBBLE:" + deckID + ":" + strconv.Itoa(index+1)
+}
+
+// qrBox returns where the QR code of a card goes, relative to the card,
+// including its quiet zone. It takes the corner opposite the card number:
+// bottom left on rectangular cards, at the top of round cards and at the
+// foot of the left edge of hexagonal ones.
+func (o options) qrBox() placement {
+	size := o.qrSize
+	w, h := o.cardSize()
+	switch {
+	case o.roundCards:
+		return placement{X: (w - size) / 2, Y: 2 * cardLabelInset, Size: size}
+	case o.hexCards:
+		return placement{X: cardLabelInset, Y: 3*h/4 - cardLabelInset - size, Size: size}
+	default:
+		inset := cardLabelInset + o.cornerRadius*0.3
+		return placement{X: inset, Y: h - inset - size, Size: size}
+	}
+}
+
+// drawCardQR prints a QR code identifying the card at (x, y), on a white
+// square so it scans on colored backs too. On fronts the layout keeps its
+// box free of symbols.
+func (r *renderer) drawCardQR(x, y float64, index int) error {
+	q, err := encodeQR([]byte(cardQRPayload(index, r.opts.deckID)))
+	if err != nil {
+		return fmt.Errorf("failed to encode the QR code of card %d: %w", index+1, err)
+	}
+
+	box := r.opts.qrBox()
+	pdf := r.pdf
+	pdf.SetFillColor(255, 255, 255)
+	pdf.Rect(x+box.X, y+box.Y, box.Size, box.Size, "F")
+	module := box.Size / float64(q.size+2*qrQuietZone)
+	qx := x + box.X + qrQuietZone*module
+	qy := y + box.Y + qrQuietZone*module
+	pdf.SetFillColor(0, 0, 0)
+	for row := range q.size {
+		// Dark runs of a row become one rectangle, which keeps the PDF small.
+		for col := 0; col < q.size; col++ {
+			if !q.modules[row][col] {
+				continue
+			}
+			start := col
+			for col+1 < q.size && q.modules[row][col+1] {
+				col++
+			}
+			pdf.Rect(qx+float64(start)*module, qy+float64(row)*module, float64(col-start+1)*module, module, "F")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestQRCodeKeptFreeOfSymbols(t *testing.T) {
+	cards := make([][]string, 20)
+	for i := range cards {
+		for j := range 8 {
+			cards[i] = append(cards[i], fmt.Sprintf("s%d.png", i*8+j))
+		}
+	}
+
+	for _, shape := range []string{"rect", "rounded", "round", "hex"} {
+		for _, layout := range layoutNames() {
+			opts := options{
+				cardWidth: 80, cardHeight: 80, padding: 2, spacing: 1,
+				layout: layout, layoutAttempts: 3, qrCodes: "front", qrSize: 12,
+			}
+			if err := opts.applyShape(shape, false); err != nil {
+				t.Fatal(err)
+			}
+			box := opts.qrBox()
+			m := planDeck(cards, opts, rand.New(rand.NewSource(1)))
+			for _, card := range m.Cards {
+				if len(card.Placements) != len(card.Symbols) {
+					t.Fatalf("%s/%s: card %d has %d of %d symbols", shape, layout, card.Index, len(card.Placements), len(card.Symbols))
+				}
+				for _, p := range card.Placements {
+					if p.X < box.X+box.Size && box.X < p.X+p.Size && p.Y < box.Y+box.Size && box.Y < p.Y+p.Size {
+						t.Errorf("%s/%s: %s on card %d overlaps the QR code", shape, layout, p.File, card.Index)
+					}
+				}
+			}
+		}
+	}
+}
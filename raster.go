@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+)
+
+// mmToPx converts a length in mm to pixels at the given resolution.
+func mmToPx(mm, dpi float64) int {
+	return int(math.Round(mm / mmPerInch * dpi))
+}
+
+// circleMask is an anti-aliased ring (or disc, when inner is negative)
+// usable as a draw mask.
+type circleMask struct {
+	cx, cy, r, inner float64
+	bounds           image.Rectangle
+}
+
+func (m *circleMask) ColorModel() color.Model { return color.AlphaModel }
+func (m *circleMask) Bounds() image.Rectangle { return m.bounds }
+
+func (m *circleMask) At(x, y int) color.Color {
+	d := math.Hypot(float64(x)+0.5-m.cx, float64(y)+0.5-m.cy)
+	a := clamp01(m.r-d+0.5) * clamp01(d-m.inner+0.5)
+	return color.Alpha{A: uint8(a * 255)}
+}
+
+// shapeMask is an anti-aliased card outline of the given width (or the
+// whole card, when line is negative) usable as a draw mask.
+type shapeMask struct {
+	shape  cardShape // in pixels
+	line   float64
+	bounds image.Rectangle
+}
+
+func (m *shapeMask) ColorModel() color.Model { return color.AlphaModel }
+func (m *shapeMask) Bounds() image.Rectangle { return m.bounds }
+
+func (m *shapeMask) At(x, y int) color.Color {
+	d := m.shape.distance(float64(x)+0.5, float64(y)+0.5)
+	a := clamp01(0.5 - d)
+	if m.line >= 0 {
+		a *= clamp01(d + m.line + 0.5)
+	}
+	return color.Alpha{A: uint8(a * 255)}
+}
+
+// cardMask returns the mask of the card outline for an image of the given
+// bounds, or nil for plain rectangular cards.
+func cardMask(opts options, bounds image.Rectangle, dpi, line float64) image.Image {
+	if !opts.roundCards && !opts.hexCards && opts.cornerRadius <= 0 {
+		return nil
+	}
+	shape := cardShape{
+		Round:  opts.roundCards,
+		Hex:    opts.hexCards,
+		Corner: opts.cornerRadius / mmPerInch * dpi,
+		Width:  float64(bounds.Dx()),
+		Height: float64(bounds.Dy()),
+	}
+	return &shapeMask{shape: shape, line: line, bounds: bounds}
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// rasterizeCard draws a planned card at the given resolution. Round,
+// rounded and hexagonal cards are transparent outside their outline.
+func rasterizeCard(card ManifestCard, opts options, cache *symbolCache, dpi float64) (*image.NRGBA, error) {
+	w, h := opts.cardSize()
+	img := image.NewNRGBA(image.Rect(0, 0, mmToPx(w, dpi), mmToPx(h, dpi)))
+	bounds := img.Bounds()
+	line := math.Max(1, float64(mmToPx(defaultLineWidth, dpi)))
+
+	white := image.NewUniform(color.White)
+	black := image.NewUniform(color.Black)
+
+	if card := cardMask(opts, bounds, dpi, -1); card != nil {
+		ring := cardMask(opts, bounds, dpi, line)
+		draw.DrawMask(img, bounds, white, image.Point{}, card, image.Point{}, draw.Over)
+		draw.DrawMask(img, bounds, black, image.Point{}, ring, image.Point{}, draw.Over)
+	} else {
+		draw.Draw(img, bounds, black, image.Point{}, draw.Src)
+		inner := bounds.Inset(int(line))
+		draw.Draw(img, inner, white, image.Point{}, draw.Src)
+	}
+
+	var face font.Face
+	if opts.labels {
+		var err error
+		if face, err = loadFontFace(opts.labelFontFile, opts.labelSize*dpi/72); err != nil {
+			return nil, err
+		}
+		defer face.Close()
+	}
+
+	for _, s := range card.Placements {
+		_, src, err := cache.source(s.File)
+		if err != nil {
+			return nil, err
+		}
+
+		size := mmToPx(s.Size, dpi)
+		var sym image.Image
+		switch {
+		case opts.labels:
+			sym = rasterLabeledSymbol(src, s, opts, face, dpi)
+		case opts.tactile:
+			sym = rasterTactileSymbol(src, s, opts, dpi)
+		default:
+			sym = s.apply(src.render(size, s.Rotation, opts.resampleFilter()), src)
+			sym = opts.applyInkMode(opts.applyAccessibility(sym, s.SymbolLook, src))
+			if s.Mirror {
+				sym = imaging.FlipH(sym)
+			}
+		}
+		sb := sym.Bounds()
+
+		// Center the symbol in its square box, as in the PDF.
+		at := image.Pt(mmToPx(s.X, dpi)+(size-sb.Dx())/2, mmToPx(s.Y, dpi)+(size-sb.Dy())/2)
+		draw.Draw(img, sb.Sub(sb.Min).Add(at), sym, sb.Min, draw.Over)
+	}
+
+	return img, nil
+}
+
+// cardFileName returns the file name used for a card in per-card exports.
+func cardFileName(index int, ext string) string {
+	return fmt.Sprintf("card_%03d.%s", index+1, ext)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// remoteTimeout bounds a single symbol download.
+const remoteTimeout = 30 * time.Second
+
+// maxDownloadSize bounds a single downloaded file, so a wrong URL cannot
+// fill the disk.
+const maxDownloadSize = 32 << 20
+
+// isRemote reports whether the symbol source is an http(s) URL.
+func isRemote(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// fetchRemoteSymbols downloads the symbol images at urls into dir and returns
+// the local paths. Files already in dir are reused, so a symbol set is only
+// downloaded once.
+func fetchRemoteSymbols(urls []string, dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create download directory: %w", err)
+	}
+
+	client := &http.Client{Timeout: remoteTimeout}
+	files := make([]string, len(urls))
+	for i, u := range urls {
+		path, err := fetchRemote(client, u, dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", u, err)
+		}
+		files[i] = path
+	}
+
+	return files, nil
+}
+
+// fetchRemote downloads a single image. The cached file is named after a hash
+// of the URL plus the image's extension.
+func fetchRemote(client *http.Client, rawURL, dir string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(rawURL))
+	name := hex.EncodeToString(sum[:8])
+	for ext := range supportedImageExts {
+		if cached := filepath.Join(dir, name+ext); fileExists(cached) {
+			return cached, nil
+		}
+	}
+	// Left behind by an interrupted run.
+	stale, _ := filepath.Glob(filepath.Join(dir, "."+name+".*-*.part"))
+	for _, f := range stale {
+		os.Remove(f)
+	}
+
+	dest, err := download(client, rawURL, func(resp *http.Response) (string, error) {
+		ext := remoteExt(u, resp.Header.Get("Content-Type"))
+		if ext == "" {
+			return "", fmt.Errorf("unsupported image type %q", resp.Header.Get("Content-Type"))
+		}
+		return filepath.Join(dir, name+ext), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	slog.Debug("Downloaded symbol", "url", rawURL, "path", dest)
+
+	return dest, nil
+}
+
+// download saves the body of url at the path dest picks for the response.
+// It goes through a hidden temporary file next to it, so an interrupted
+// download is neither taken for a cached file nor picked up as a symbol, and
+// fails for bodies over maxDownloadSize.
+func download(client *http.Client, url string, dest func(*http.Response) (string, error)) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	path, err := dest(resp)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.part")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err == nil && n > maxDownloadSize {
+		err = fmt.Errorf("file is larger than %d MiB", maxDownloadSize>>20)
+	}
+	if err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// remoteExt picks the file extension of a downloaded image from the URL
+// path, falling back to the Content-Type header.
+func remoteExt(u *url.URL, contentType string) string {
+	if ext := strings.ToLower(path.Ext(u.Path)); supportedImageExts[ext] {
+		return ext
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	exts, _ := mime.ExtensionsByType(mediaType)
+	for _, ext := range exts {
+		if supportedImageExts[ext] {
+			return ext
+		}
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDownload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large" {
+			io.CopyN(w, zeros{}, maxDownloadSize+1)
+			return
+		}
+		w.Write([]byte("icon"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	to := func(name string) func(*http.Response) (string, error) {
+		return func(*http.Response) (string, error) { return filepath.Join(dir, name), nil }
+	}
+
+	path, err := download(srv.Client(), srv.URL+"/icon", to("icon.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, []byte("icon")) {
+		t.Errorf("downloaded %q, want icon", data)
+	}
+
+	if _, err := download(srv.Client(), srv.URL+"/large", to("large.png")); err == nil {
+		t.Error("download of a file over maxDownloadSize succeeded")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("download left %d files, want only icon.png", len(entries))
+	}
+}
+
+func TestFetchRemoteAfterInterruptedDownload(t *testing.T) {
+	var complete atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "8")
+		w.Write([]byte("sym"))
+		if !complete.Load() {
+			panic(http.ErrAbortHandler) // drops the connection mid-body
+		}
+		w.Write([]byte("bol!!"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	u := srv.URL + "/symbol.png"
+	if _, err := fetchRemote(srv.Client(), u, dir); err == nil {
+		t.Fatal("interrupted download succeeded")
+	}
+	// A run killed during the download leaves its temporary file.
+	sum := sha256.Sum256([]byte(u))
+	stale := filepath.Join(dir, "."+hex.EncodeToString(sum[:8])+".png-1.part")
+	if err := os.WriteFile(stale, []byte("sym"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	complete.Store(true)
+	path, err := fetchRemote(srv.Client(), u, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "symbol!!" {
+		t.Errorf("fetched %q from %s, want the complete symbol", data, path)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("download directory holds %d files, want only the symbol", len(entries))
+	}
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
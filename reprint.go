@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const reprintFileName = "dobble_reprint.pdf"
+
+// parseCardList parses 1-based card numbers as printed on the cards, e.g.
+// "3,7,12-14", into 0-based indices of a deck with total cards.
+func parseCardList(list string, total int) ([]int, error) {
+	var indices []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return nil, fmt.Errorf("invalid card number %q", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(strings.TrimSpace(last)); err != nil || to < from {
+				return nil, fmt.Errorf("invalid card range %q", part)
+			}
+		}
+		if from < 1 || to > total {
+			return nil, fmt.Errorf("card %q is out of range, the deck has cards 1 to %d", part, total)
+		}
+		for n := from; n <= to; n++ {
+			if !seen[n] {
+				seen[n] = true
+				indices = append(indices, n-1)
+			}
+		}
+	}
+	return indices, nil
+}
+
+// applyTo sets the card geometry and deck ID of opts to the ones the deck
+// was generated with.
+func (m *Manifest) applyTo(opts *options) {
+	opts.seed = m.Seed
+	opts.deckID = m.DeckID
+	opts.imagesPerCard = m.SymbolsPerCard
+	opts.roundCards = m.RoundCards
+	opts.hexCards = m.HexCards
+	opts.cornerRadius = m.CornerRadius
+	opts.cardWidth, opts.cardHeight = m.CardWidth, m.CardHeight
+	opts.diameter = 0
+	if m.RoundCards {
+		opts.diameter = m.CardWidth
+	}
+	opts.bleed = m.Bleed
+	opts.symbolNames = m.SymbolNames
+	// Tactile decks are reprinted with their braille numbers; others get
+	// them with --tactile.
+	opts.symbolCodes = m.SymbolCodes
+	if len(opts.symbolCodes) > 0 {
+		opts.tactile = true
+	} else if opts.tactile {
+		opts.symbolCodes = numberSymbols(m)
+	}
+}
+
+// runReprint implements the reprint command. It renders selected cards of a
+// saved deck again from the placements recorded in its manifest, so they
+// match the original print exactly. Remaining flags are those of generate
+// and control the paper and print marks.
+func runReprint(args []string) error {
+	if len(args) < 2 || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[1], "-") {
+		fmt.Fprintln(os.Stderr, "Usage: dobble reprint <manifest.json> <cards> [generate flags]")
+		fmt.Fprintln(os.Stderr, "\nCards are numbered from 1 as printed with --card-numbers, e.g. 3,7,12-14.")
+		return fmt.Errorf("expected a manifest file and a list of cards")
+	}
+
+	m, err := loadManifest(args[0])
+	if err != nil {
+		return err
+	}
+	indices, err := parseCardList(args[1], len(m.Cards))
+	if err != nil {
+		return err
+	}
+
+	opts, err := parseFlags(args[2:])
+	if err != nil {
+		return err
+	}
+	if opts.output == outputFileName {
+		opts.output = reprintFileName
+	}
+	m.applyTo(&opts)
+	if err := checkOverwrite(opts.output, opts.force); err != nil {
+		return err
+	}
+
+	subset := *m
+	subset.Cards = make([]ManifestCard, len(indices))
+	for i, idx := range indices {
+		subset.Cards[i] = m.Cards[idx]
+	}
+
+	if err := generatePDF(&subset, opts); err != nil {
+		return fmt.Errorf("%w: %w", ErrPDFWrite, err)
+	}
+	slog.Info("Cards reprinted", "cards", len(indices), "output", opts.output)
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+)
+
+// lowResSymbol is a symbol image with too few pixels for the largest size it
+// is printed at.
+type lowResSymbol struct {
+	Path string
+	DPI  float64 // effective resolution at the largest printed size
+}
+
+// findLowResSymbols returns the raster symbols whose effective resolution at
+// their largest placement in the deck is below minDPI, lowest first. Vector
+// symbols are never too small.
+func findLowResSymbols(m *Manifest, minDPI float64) ([]lowResSymbol, error) {
+	largest := make(map[string]float64)
+	for _, card := range m.Cards {
+		for _, p := range card.Placements {
+			largest[p.File] = math.Max(largest[p.File], p.Size)
+		}
+	}
+
+	// Sources are decoded one at a time and not kept, since only their size
+	// is needed.
+	cache := newSymbolCache()
+	var low []lowResSymbol
+	for path, size := range largest {
+		_, src, err := cache.decode(path)
+		if err != nil {
+			return nil, err
+		}
+		if src.img == nil || size <= 0 {
+			continue
+		}
+
+		// Symbols are fit into their box by the longer side.
+		b := src.img.Bounds()
+		px := float64(max(b.Dx(), b.Dy()))
+		if dpi := px / (size / mmPerInch); dpi < minDPI {
+			low = append(low, lowResSymbol{Path: path, DPI: dpi})
+		}
+	}
+
+	slices.SortFunc(low, func(a, b lowResSymbol) int {
+		return int(math.Copysign(1, a.DPI-b.DPI))
+	})
+	return low, nil
+}
+
+// checkResolution warns about every symbol below minDPI. In strict mode it
+// fails instead, listing the offending files.
+func checkResolution(m *Manifest, minDPI float64, strict bool) error {
+	if minDPI <= 0 {
+		return nil
+	}
+
+	low, err := findLowResSymbols(m, minDPI)
+	if err != nil {
+		return err
+	}
+	for _, s := range low {
+		slog.Warn("Symbol resolution too low for print", "path", s.Path, "dpi", math.Round(s.DPI), "min", minDPI)
+	}
+	if strict && len(low) > 0 {
+		paths := make([]string, len(low))
+		for i, s := range low {
+			paths[i] = s.Path
+		}
+		return fmt.Errorf("%d symbols are below %.0f DPI at their printed size: %s", len(low), minDPI, strings.Join(paths, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// runResult summarizes a generate run for scripts that wrap the generator.
+// --result-json prints it to stdout when the run ends, also after a failure.
+type runResult struct {
+	OK       bool              `json:"ok"`
+	Error    string            `json:"error,omitempty"`
+	Outputs  map[string]string `json:"outputs,omitempty"` // paths keyed by format
+	Manifest string            `json:"manifest,omitempty"`
+	Pages    int               `json:"pages,omitempty"`
+	Cards    int               `json:"cards,omitempty"`
+	Seed     int64             `json:"seed"`
+	DeckID   string            `json:"deckId,omitempty"`
+	Warnings []string          `json:"warnings,omitempty"`
+}
+
+// newRunResult describes the outputs of a run of opts. m is the generated
+// deck, or nil if the run failed before it was planned.
+func newRunResult(m *Manifest, opts options, warnings []string, err error) runResult {
+	r := runResult{OK: err == nil, Seed: opts.seed, DeckID: opts.deckID, Warnings: warnings}
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+
+	r.Outputs = make(map[string]string, len(opts.formats))
+	for _, format := range opts.formats {
+		r.Outputs[format] = opts.outputPath(format)
+	}
+	if opts.tuckBoxFile != "" {
+		r.Outputs["tuck-box"] = opts.tuckBoxFile
+	}
+	if opts.usageCSV != "" {
+		r.Outputs["usage-csv"] = opts.usageCSV
+	}
+	r.Manifest = opts.manifestPath()
+	if m != nil {
+		r.Pages = m.Pages
+		r.Cards = len(m.Cards)
+	}
+	return r
+}
+
+func (r runResult) print(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// warningRecorder is a log handler that keeps the warnings and errors
+// logged during a run, whatever the log level, and passes every record on.
+type warningRecorder struct {
+	slog.Handler
+	mu       *sync.Mutex
+	warnings *[]string
+	attrs    []slog.Attr
+}
+
+func newWarningRecorder(next slog.Handler) *warningRecorder {
+	return &warningRecorder{Handler: next, mu: new(sync.Mutex), warnings: new([]string)}
+}
+
+func (h *warningRecorder) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn || h.Handler.Enabled(ctx, level)
+}
+
+func (h *warningRecorder) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn {
+		var b strings.Builder
+		b.WriteString(r.Message)
+		write := func(a slog.Attr) bool {
+			fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+			return true
+		}
+		for _, a := range h.attrs {
+			write(a)
+		}
+		r.Attrs(write)
+
+		h.mu.Lock()
+		*h.warnings = append(*h.warnings, b.String())
+		h.mu.Unlock()
+	}
+	if !h.Handler.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *warningRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.Handler = h.Handler.WithAttrs(attrs)
+	c.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &c
+}
+
+func (h *warningRecorder) WithGroup(name string) slog.Handler {
+	c := *h
+	c.Handler = h.Handler.WithGroup(name)
+	return &c
+}
+
+// recorded returns the warnings logged so far.
+func (h *warningRecorder) recorded() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), *h.warnings...)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+)
+
+// templateShrinkBy is finer than layoutShrinkBy, since templates are cheap
+// to evaluate and every step lost shrinks all symbols.
+const templateShrinkBy = 0.99
+
+// goldenAngle spreads sunflower points so that no two line up radially.
+var goldenAngle = math.Pi * (3 - math.Sqrt(5))
+
+// roundTemplates are the symmetric layouts of round cards known from the
+// original game. A template returns the symbol centers, relative to the card
+// center, for symbols of the given size on a card whose symbols must stay
+// within radius. phase rotates the whole arrangement.
+var roundTemplates = map[string]func(count int, size, radius, phase float64) [][2]float64{
+	// One symbol in the center, the others on a ring around it.
+	"ring": func(count int, size, radius, phase float64) [][2]float64 {
+		centers := [][2]float64{{0, 0}}
+		return append(centers, ringCenters(count-1, radius-size/math.Sqrt2, phase)...)
+	},
+	// Two concentric rings, about a third of the symbols on the inner one.
+	"rings": func(count int, size, radius, phase float64) [][2]float64 {
+		inner := count / 3
+		outer := radius - size/math.Sqrt2
+		// Keep the inner ring one symbol in from the outer one, but wide
+		// enough for its own symbols not to collide, and put its symbols in
+		// the gaps of the outer ring.
+		innerR := math.Max(outer-1.2*size, size/(math.Sqrt2*math.Sin(math.Pi/float64(max(inner, 2)))))
+		centers := ringCenters(inner, innerR, phase+math.Pi/float64(count-inner))
+		return append(centers, ringCenters(count-inner, outer, phase)...)
+	},
+	// Golden-angle spiral, spreading the symbols evenly over the disc.
+	"sunflower": func(count int, size, radius, phase float64) [][2]float64 {
+		outer := radius - size/math.Sqrt2
+		centers := make([][2]float64, count)
+		for i := range centers {
+			r := outer * math.Sqrt((float64(i)+0.5)/float64(count))
+			a := phase + float64(i)*goldenAngle
+			centers[i] = [2]float64{r * math.Cos(a), r * math.Sin(a)}
+		}
+		return centers
+	},
+}
+
+func init() {
+	for name := range roundTemplates {
+		registerLayout(name, templateLayout{name: name})
+	}
+	registerLayout("mixed", templateLayout{})
+}
+
+func ringCenters(count int, r, phase float64) [][2]float64 {
+	centers := make([][2]float64, count)
+	for i := range centers {
+		a := phase + 2*math.Pi*float64(i)/float64(count)
+		centers[i] = [2]float64{r * math.Cos(a), r * math.Sin(a)}
+	}
+	return centers
+}
+
+// templateLayout places the symbols using a named round template, rotated by
+// a random angle. Without a name it picks a template per card. The symbol
+// size is the largest for which the template fits without overlaps. On
+// rectangular cards the templates use the inscribed circle.
+type templateLayout struct {
+	name string
+}
+
+func (t templateLayout) Place(rng *rand.Rand, shape cardShape, count int) []placement {
+	name := t.name
+	if name == "" {
+		templates := []string{"ring", "rings", "sunflower"}
+		name = templates[rng.Intn(len(templates))]
+	}
+	template := roundTemplates[name]
+
+	radius := math.Min(shape.Width, shape.Height)/2 - shape.Padding
+	phase := rng.Float64() * 2 * math.Pi
+	place := func(size float64) ([]placement, bool) {
+		centers := template(count, size, radius, phase)
+		placements := make([]placement, 0, count)
+		for _, c := range centers {
+			p := placement{
+				X:    shape.Width/2 + c[0] - size/2,
+				Y:    shape.Height/2 + c[1] - size/2,
+				Size: size,
+			}
+			if !shape.contains(p) || shape.collidesAny(p, placements) {
+				return nil, false
+			}
+			placements = append(placements, p)
+		}
+		return placements, true
+	}
+
+	// Fitting is not monotonic in the size for every template, so search
+	// downwards from the largest conceivable size.
+	for size := 2 * radius; size >= radius*0.1; size *= templateShrinkBy {
+		if placements, ok := place(size); ok {
+			return placements
+		}
+	}
+	return nil
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	mathrand "math/rand"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxUploadSize = 64 << 20
+	// maxDeckRequestSize bounds the JSON body of a deck request, which
+	// only holds a handful of settings.
+	maxDeckRequestSize = 4 << 10
+	previewDPI         = 150
+
+	// serveExpiry is how long uploads and decks are kept after their last
+	// use before their files are removed.
+	serveExpiry = time.Hour
+	// maxBuiltDecks is the number of decks kept per upload, so previews
+	// and downloads of the same request do not build it again.
+	maxBuiltDecks = 8
+)
+
+// server is the local web UI: users upload a symbol set once and can then
+// preview and download decks built from it.
+type server struct {
+	base options
+	root string
+
+	mu      sync.Mutex
+	uploads map[string]*stored // upload ID → directory holding the images
+	decks   map[string]*stored // deck ID → directory holding PDF and manifest
+}
+
+// stored is a directory of an upload or deck and when it was last used.
+type stored struct {
+	dir   string
+	used  time.Time
+	built map[deckRequest]builtDeck // decks built from an upload
+}
+
+// builtDeck is a deck laid out for a request, with the options it used.
+type builtDeck struct {
+	manifest *Manifest
+	opts     options
+}
+
+// lookup returns the directory stored under id and marks it as used.
+func (s *server) lookup(entries map[string]*stored, id string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := entries[id]
+	if !ok {
+		return "", false
+	}
+	e.used = time.Now()
+	return e.dir, true
+}
+
+// expire removes the uploads and decks not used since before the given
+// time, with their files.
+func (s *server) expire(before time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entries := range []map[string]*stored{s.uploads, s.decks} {
+		for id, e := range entries {
+			if e.used.Before(before) {
+				if err := os.RemoveAll(e.dir); err != nil {
+					slog.Warn("Removing expired files failed", "dir", e.dir, "error", err)
+				}
+				delete(entries, id)
+			}
+		}
+	}
+}
+
+// deckRequest describes a deck to build from previously uploaded images.
+type deckRequest struct {
+	Upload   string `json:"upload"`
+	Symbols  int    `json:"symbols"`
+	Cards    int    `json:"cards"` // 0 builds the full deck
+	Round    bool   `json:"round"`
+	Seed     int64  `json:"seed"`
+	PageSize string `json:"pageSize,omitempty"`
+}
+
+// deckResponse is returned by POST /decks.
+type deckResponse struct {
+	ID       string `json:"id"`
+	Cards    int    `json:"cards"`
+	Seed     int64  `json:"seed"`
+	PDF      string `json:"pdf"`
+	Manifest string `json:"manifest"`
+}
+
+// runServe implements the serve command.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "address to listen on")
+	var logs logConfig
+	logs.register(fs)
+	fs.Parse(args)
+	if err := logs.apply(fs); err != nil {
+		return err
+	}
+
+	root, err := os.MkdirTemp("", "dobble-serve-*")
+	if err != nil {
+		return fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	defer os.RemoveAll(root)
+
+	// Uploads must not reach the external AVIF converters.
+	avifConvertersAllowed = false
+
+	base := defaultOptions()
+	s := &server{
+		base:    base,
+		root:    root,
+		uploads: make(map[string]*stored),
+		decks:   make(map[string]*stored),
+	}
+	go func() {
+		for now := range time.Tick(serveExpiry / 4) {
+			s.expire(now.Add(-serveExpiry))
+		}
+	}()
+
+	slog.Info("Serving web UI", "url", "http://"+*addr)
+	return http.ListenAndServe(*addr, s.routes())
+}
+
+func (s *server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("POST /upload", s.handleUpload)
+	mux.HandleFunc("GET /preview", s.handlePreview)
+	mux.HandleFunc("GET /download", s.handleDownload)
+
+	// JSON API: upload images with POST /upload, then create decks from them.
+	mux.HandleFunc("POST /decks", s.handleCreateDeck)
+	mux.HandleFunc("GET /decks/{file}", s.handleGetDeck)
+	return mux
+}
+
+func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.Execute(w, validSymbolCounts(14)); err != nil {
+		slog.Error("Rendering index failed", "error", err)
+	}
+}
+
+// handleUpload stores the uploaded images and returns the ID used to refer
+// to them in later requests.
+func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		http.Error(w, "invalid upload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, dir, err := s.newUpload()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	count := 0
+	for _, fh := range r.MultipartForm.File["images"] {
+		name := filepath.Base(fh.Filename)
+		if ext := strings.ToLower(filepath.Ext(name)); !supportedImageExts[ext] || ext == ".avif" {
+			continue
+		}
+		if err := saveUpload(fh, filepath.Join(dir, name)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		count++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"id": id, "images": count})
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *server) newUpload() (string, string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", "", err
+	}
+
+	dir := filepath.Join(s.root, id)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return "", "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	s.mu.Lock()
+	s.uploads[id] = &stored{dir: dir, used: time.Now()}
+	s.mu.Unlock()
+
+	return id, dir, nil
+}
+
+func saveUpload(fh *multipart.FileHeader, path string) error {
+	src, err := fh.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to store upload: %w", err)
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return fmt.Errorf("failed to store upload: %w", err)
+	}
+	return dst.Close()
+}
+
+// queryDeckRequest reads a deck request from the URL query, as sent by the
+// web UI.
+func queryDeckRequest(r *http.Request) (deckRequest, error) {
+	q := r.URL.Query()
+	req := deckRequest{
+		Upload: q.Get("upload"),
+		Round:  q.Get("round") == "on" || q.Get("round") == "true",
+	}
+
+	var err error
+	if req.Symbols, err = strconv.Atoi(q.Get("symbols")); err != nil {
+		return req, fmt.Errorf("invalid symbols per card: %w", err)
+	}
+	if cards := q.Get("cards"); cards != "" {
+		if req.Cards, err = strconv.Atoi(cards); err != nil {
+			return req, fmt.Errorf("invalid number of cards: %w", err)
+		}
+	}
+	if req.Seed, err = strconv.ParseInt(q.Get("seed"), 10, 64); err != nil {
+		return req, fmt.Errorf("invalid seed: %w", err)
+	}
+
+	return req, nil
+}
+
+// deck builds the requested deck from an upload. Decks with a seed are kept
+// with the upload and reused for the same request.
+func (s *server) deck(req deckRequest) (*Manifest, options, error) {
+	if _, ok := pageSizes[strings.ToLower(req.PageSize)]; req.PageSize != "" && !ok {
+		return nil, options{}, fmt.Errorf("unknown page size %q", req.PageSize)
+	}
+	dir, ok := s.lookup(s.uploads, req.Upload)
+	if !ok {
+		return nil, options{}, fmt.Errorf("unknown upload %q", req.Upload)
+	}
+	if d, ok := s.builtDeck(req); ok {
+		return d.manifest, d.opts, nil
+	}
+
+	opts := s.base
+	opts.imgDir = dir
+	opts.imagesPerCard = req.Symbols
+	opts.totalCards = req.Cards
+	opts.roundCards = req.Round
+	opts.seed = req.Seed
+	if opts.seed == 0 {
+		opts.seed = time.Now().UnixNano()
+	}
+	if req.PageSize != "" {
+		opts.pageSize = req.PageSize
+	}
+
+	rng := mathrand.New(mathrand.NewSource(opts.seed))
+	cg, err := newCardGenerator(opts, rng)
+	if err != nil {
+		return nil, opts, err
+	}
+
+	m, err := buildDeck(cg, &opts, rng)
+	if err != nil {
+		return nil, opts, err
+	}
+	if req.Seed != 0 {
+		s.keepDeck(req, builtDeck{manifest: m, opts: opts})
+	}
+	return m, opts, nil
+}
+
+// builtDeck returns the deck kept for the request.
+func (s *server) builtDeck(req deckRequest) (builtDeck, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.uploads[req.Upload]; ok {
+		d, ok := u.built[req]
+		return d, ok
+	}
+	return builtDeck{}, false
+}
+
+// keepDeck stores the deck built for the request with its upload, starting
+// over once maxBuiltDecks are kept.
+func (s *server) keepDeck(req deckRequest, d builtDeck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[req.Upload]
+	if !ok {
+		return
+	}
+	if u.built == nil || len(u.built) >= maxBuiltDecks {
+		u.built = make(map[deckRequest]builtDeck)
+	}
+	u.built[req] = d
+}
+
+// handlePreview renders the first card of the deck as PNG.
+func (s *server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	req, err := queryDeckRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m, opts, err := s.deck(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	img, err := rasterizeCard(m.Cards[0], opts, newSymbolCache(), previewDPI)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	encodePNGWithDPI(w, img, previewDPI)
+}
+
+// handleDownload renders the whole deck as PDF.
+func (s *server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	req, err := queryDeckRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m, opts, err := s.deck(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	out, err := os.CreateTemp(s.root, "deck-*.pdf")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+
+	opts.output = out.Name()
+	if err := generatePDF(m, opts); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="`+outputFileName+`"`)
+	http.ServeFile(w, r, out.Name())
+}
+
+// handleCreateDeck builds a deck from a JSON deck request and stores the PDF
+// and manifest for later download.
+func (s *server) handleCreateDeck(w http.ResponseWriter, r *http.Request) {
+	var req deckRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxDeckRequestSize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		writeJSONError(w, http.StatusBadRequest, errors.New("invalid request: data after the JSON object"))
+		return
+	}
+
+	m, opts, err := s.deck(req)
+	if err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	id, err := newID()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	dir := filepath.Join(s.root, "decks", id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	opts.output = filepath.Join(dir, id+".pdf")
+	err = generatePDF(m, opts)
+	if err == nil {
+		err = m.save(filepath.Join(dir, id+".json"))
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.mu.Lock()
+	s.decks[id] = &stored{dir: dir, used: time.Now()}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/decks/"+id+".pdf")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(deckResponse{
+		ID:       id,
+		Cards:    len(m.Cards),
+		Seed:     m.Seed,
+		PDF:      "/decks/" + id + ".pdf",
+		Manifest: "/decks/" + id + ".json",
+	})
+}
+
+// handleGetDeck serves the PDF (<id>.pdf) or manifest (<id>.json) of a
+// deck created through the API.
+func (s *server) handleGetDeck(w http.ResponseWriter, r *http.Request) {
+	file := r.PathValue("file")
+	ext := filepath.Ext(file)
+	id := strings.TrimSuffix(file, ext)
+
+	dir, ok := s.lookup(s.decks, id)
+	if !ok || (ext != ".pdf" && ext != ".json") {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("deck %q not found", file))
+		return
+	}
+
+	http.ServeFile(w, r, filepath.Join(dir, id+ext))
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Dobble card generator</title>
+<style>
+body { font-family: sans-serif; max-width: 48rem; margin: 2rem auto; }
+label { display: block; margin: .5rem 0; }
+#preview { display: block; margin: 1rem 0; max-width: 100%; border: 1px solid #ccc; }
+#error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Dobble card generator</h1>
+<form id="form">
+<label>Symbol images <input type="file" name="images" accept=".png,.jpg,.jpeg,.svg,.webp" multiple></label>
+<label>Symbols per card <select name="symbols">{{range .}}<option{{if eq . 8}} selected{{end}}>{{.}}</option>{{end}}</select></label>
+<label>Number of cards <input type="number" name="cards" placeholder="full deck" min="0"></label>
+<label><input type="checkbox" name="round"> Round cards</label>
+<button type="button" id="shuffle">Shuffle</button>
+<a id="download" href="#">Download PDF</a>
+</form>
+<p id="error"></p>
+<img id="preview" alt="">
+<script>
+const form = document.getElementById("form");
+let upload = "", seed = Date.now();
+
+function query() {
+	const p = new URLSearchParams({upload, seed, symbols: form.symbols.value, cards: form.cards.value});
+	if (form.round.checked) p.set("round", "on");
+	return p.toString();
+}
+
+function refresh() {
+	if (!upload) return;
+	document.getElementById("download").href = "/download?" + query();
+	fetch("/preview?" + query()).then(async r => {
+		if (!r.ok) throw new Error(await r.text());
+		document.getElementById("preview").src = URL.createObjectURL(await r.blob());
+		document.getElementById("error").textContent = "";
+	}).catch(e => document.getElementById("error").textContent = e.message);
+}
+
+form.images.addEventListener("change", () => {
+	fetch("/upload", {method: "POST", body: new FormData(form)})
+		.then(r => r.json()).then(j => { upload = j.id; refresh(); });
+});
+form.addEventListener("change", e => { if (e.target !== form.images) refresh(); });
+document.getElementById("shuffle").addEventListener("click", () => { seed = Date.now(); refresh(); });
+</script>
+</body>
+</html>
+`))
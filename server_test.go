@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerExpire(t *testing.T) {
+	root := t.TempDir()
+	s := &server{root: root, uploads: make(map[string]*stored), decks: make(map[string]*stored)}
+
+	now := time.Now()
+	for id, used := range map[string]time.Time{"old": now.Add(-2 * serveExpiry), "new": now} {
+		dir := filepath.Join(root, id)
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		s.uploads[id] = &stored{dir: dir, used: used}
+	}
+
+	s.expire(now.Add(-serveExpiry))
+	if _, ok := s.lookup(s.uploads, "old"); ok {
+		t.Error("expired upload is still known")
+	}
+	if _, err := os.Stat(filepath.Join(root, "old")); !os.IsNotExist(err) {
+		t.Errorf("expired upload directory not removed: %v", err)
+	}
+	if _, ok := s.lookup(s.uploads, "new"); !ok {
+		t.Error("recent upload expired")
+	}
+}
+
+func TestServerDeckRequests(t *testing.T) {
+	s := &server{uploads: map[string]*stored{"u": {dir: t.TempDir(), used: time.Now()}}}
+
+	if _, _, err := s.deck(deckRequest{Upload: "u", Symbols: 3, PageSize: "B5"}); err == nil {
+		t.Error("deck accepted the unknown page size B5")
+	}
+
+	req := deckRequest{Upload: "u", Symbols: 3, Seed: 1, PageSize: "letter"}
+	m := &Manifest{Seed: 1}
+	s.keepDeck(req, builtDeck{manifest: m})
+	if got, _, err := s.deck(req); err != nil || got != m {
+		t.Errorf("deck built %p, %v again, want the kept deck %p", got, err, m)
+	}
+	req.Seed = 2
+	if _, ok := s.builtDeck(req); ok {
+		t.Error("deck kept for another seed reused")
+	}
+}
+
+func TestServerCreateDeckRejectsBadBodies(t *testing.T) {
+	s := &server{root: t.TempDir(), uploads: make(map[string]*stored), decks: make(map[string]*stored)}
+	for name, body := range map[string]string{
+		"unknown field": `{"upload": "u", "symbols": 3, "colour": "red"}`,
+		"trailing data": `{"upload": "u", "symbols": 3} {"upload": "v"}`,
+		"too large":     `{"upload": "` + strings.Repeat("u", maxDeckRequestSize) + `"}`,
+	} {
+		rec := httptest.NewRecorder()
+		s.handleCreateDeck(rec, httptest.NewRequest(http.MethodPost, "/decks", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d", name, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
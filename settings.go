@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// lastProfile is the profile holding the settings of the last successful
+// run. It is kept in the state directory, named profiles in the config
+// directory.
+const lastProfile = "last"
+
+// unsavedFlags are left out of saved settings: the ones choosing and saving
+// profiles, the form's values, which are saved as they were answered, and
+// flags meant for a single run, so a later run neither overwrites files
+// without asking, nor writes to an old path, nor repeats the same deck.
+var unsavedFlags = []string{
+	"profile", "save-profile", "interactive", "symbols", "cards", "round",
+	"keep-temp", "force", "dry-run", "seed", "result-json",
+	"output", "manifest", "stats", "usage-csv", "tuck-box-file",
+	"png-dir", "svg-dir", "tts-dir", "html-dir",
+}
+
+// profilesPath returns the file holding the named profiles, in
+// $XDG_CONFIG_HOME/dobble.
+func profilesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the config directory: %w", err)
+	}
+	return filepath.Join(dir, "dobble", "profiles.json"), nil
+}
+
+// lastSettingsPath returns the file holding the settings of the last
+// successful run, in $XDG_STATE_HOME/dobble.
+func lastSettingsPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to locate the state directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "dobble", "last.json"), nil
+}
+
+// loadProfiles reads the named profiles: generate flags keyed by name. A
+// missing file holds no profiles.
+func loadProfiles() (map[string][]string, error) {
+	path, err := profilesPath()
+	if err != nil {
+		return nil, err
+	}
+	profiles := make(map[string][]string)
+	if err := readJSONFile(path, &profiles); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// loadProfile returns the flags saved as the named profile.
+func loadProfile(name string) ([]string, error) {
+	if name == lastProfile {
+		path, err := lastSettingsPath()
+		if err != nil {
+			return nil, err
+		}
+		var args []string
+		if err := readJSONFile(path, &args); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("no settings saved yet; they are saved after every successful run")
+			}
+			return nil, err
+		}
+		return args, nil
+	}
+
+	profiles, err := loadProfiles()
+	if err != nil {
+		return nil, err
+	}
+	args, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q; save one with --save-profile", name)
+	}
+	return args, nil
+}
+
+// saveSettings records the flags of a successful run as the last settings
+// and, with --save-profile, as a named profile. Values chosen in the form
+// are added as flags, so the next run starts from them.
+func saveSettings(opts options) error {
+	args := append(slices.Clone(opts.settings),
+		"--symbols="+strconv.Itoa(opts.imagesPerCard),
+		"--cards="+strconv.Itoa(opts.totalCards),
+		"--round="+strconv.FormatBool(opts.roundCards))
+
+	path, err := lastSettingsPath()
+	if err != nil {
+		return err
+	}
+	if err := writeJSONFile(path, args); err != nil {
+		return err
+	}
+
+	if opts.saveProfile == "" {
+		return nil
+	}
+	profiles, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+	profiles[opts.saveProfile] = args
+	if path, err = profilesPath(); err != nil {
+		return err
+	}
+	return writeJSONFile(path, profiles)
+}
+
+// scanFlag returns the value of a flag in args before they are parsed, and
+// whether it is there. Boolean flags given without a value are "true".
+func scanFlag(args []string, name string) (string, bool) {
+	for i, arg := range args {
+		key, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || key != name {
+			continue
+		}
+		if hasValue {
+			return value, true
+		}
+		if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			return args[i+1], true
+		}
+		return "true", true
+	}
+	return "", false
+}
+
+// profileName returns the profile to load before parsing args: the one of
+// --profile, or else of DOBBLE_PROFILE. It has to be known before the
+// environment is applied, since the flags given there override the profile.
+func profileName(args []string) (string, bool) {
+	if name, ok := scanFlag(args, "profile"); ok {
+		return name, true
+	}
+	return os.LookupEnv(envName("profile"))
+}
+
+// withoutFlags returns args without the named flags and their values.
+func withoutFlags(flags *flag.FlagSet, args []string, names ...string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		key, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || !slices.Contains(names, key) {
+			out = append(out, args[i])
+			continue
+		}
+		if f := flags.Lookup(key); f != nil && !hasValue && !isBoolFlag(f) {
+			i++ // skip the value
+		}
+	}
+	return out
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// envPrefix starts the environment variables that set generate flags, e.g.
+// DOBBLE_IMG_DIR for --img-dir.
+const envPrefix = "DOBBLE_"
+
+// envName returns the environment variable setting a flag.
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// applyEnv sets every flag that has an environment variable, for containers
+// and CI where flags are awkward to pass. Flags given on the command line
+// are parsed later and override them.
+func applyEnv(flags *flag.FlagSet) error {
+	var errs []error
+	flags.VisitAll(func(f *flag.Flag) {
+		if v, ok := os.LookupEnv(envName(f.Name)); ok {
+			if err := flags.Set(f.Name, v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %w", envName(f.Name), err))
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// givenFlags returns the generate flags set in args or, with env, in the
+// environment. Values from saved settings do not count, so the --deck and
+// --large-print presets still apply on top of a profile.
+func givenFlags(args []string, env bool) map[string]bool {
+	var opts options
+	var raw generateFlagValues
+	fs := newGenerateFlagSet(&opts, &raw)
+	opts.log.register(fs)
+	fs.SetOutput(io.Discard)
+
+	given := make(map[string]bool)
+	if env {
+		fs.VisitAll(func(f *flag.Flag) {
+			if _, ok := os.LookupEnv(envName(f.Name)); ok {
+				given[f.Name] = true
+			}
+		})
+	}
+	fs.Parse(args) // args were parsed without errors before
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	return given
+}
+
+// hasEnvConfig reports whether any deck flag of generate is set in the
+// environment. The logging flags and DOBBLE_PROFILE do not count, so they
+// can be set without turning off the form.
+func hasEnvConfig() bool {
+	var opts options
+	var raw generateFlagValues
+	found := false
+	newGenerateFlagSet(&opts, &raw).VisitAll(func(f *flag.Flag) {
+		if _, ok := os.LookupEnv(envName(f.Name)); ok && f.Name != "profile" {
+			found = true
+		}
+	})
+	return found
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+func TestProfileName(t *testing.T) {
+	if name, ok := profileName([]string{"--seed", "1"}); ok {
+		t.Errorf("profile %q chosen without --profile or DOBBLE_PROFILE", name)
+	}
+
+	t.Setenv("DOBBLE_PROFILE", "classroom")
+	if name, ok := profileName(nil); !ok || name != "classroom" {
+		t.Errorf("profileName with DOBBLE_PROFILE = %q, %v, want classroom", name, ok)
+	}
+	if name, ok := profileName([]string{"--profile=print"}); !ok || name != "print" {
+		t.Errorf("profileName with --profile and DOBBLE_PROFILE = %q, %v, want print", name, ok)
+	}
+}
+
+func TestUnsavedFlagsLeftOut(t *testing.T) {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	fs.Bool("force", false, "")
+	fs.Bool("dry-run", false, "")
+	fs.String("output", "", "")
+	fs.Int("seed", 0, "")
+	fs.String("png-dir", "", "")
+	fs.Int("copies", 1, "")
+
+	args := []string{"--force", "--output", "old.pdf", "--dry-run", "--seed", "7", "--png-dir=old", "--copies", "2"}
+	got := withoutFlags(fs, args, unsavedFlags...)
+	if want := []string{"--copies", "2"}; !slices.Equal(got, want) {
+		t.Errorf("saved settings %q, want %q", got, want)
+	}
+}
+
+func TestDefaultOptionsIgnoreEnvironment(t *testing.T) {
+	t.Setenv("DOBBLE_SYMBOLS", "6")
+	t.Setenv("DOBBLE_PROFILE", "missing")
+	t.Setenv("DOBBLE_LOG_LEVEL", "not-a-level")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	old := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(old)
+
+	if opts := defaultOptions(); opts.imagesPerCard != 8 {
+		t.Errorf("defaultOptions took %d symbols per card from DOBBLE_SYMBOLS, want the default 8", opts.imagesPerCard)
+	}
+	if slog.Default() != logger {
+		t.Error("defaultOptions replaced the logger")
+	}
+}
+
+func TestHasEnvConfig(t *testing.T) {
+	t.Setenv("DOBBLE_LOG_LEVEL", "debug")
+	t.Setenv("DOBBLE_PROFILE", "classroom")
+	t.Setenv("DOBBLE_NOT_A_FLAG", "1")
+	if hasEnvConfig() {
+		t.Error("hasEnvConfig is true without a deck flag in the environment")
+	}
+
+	t.Setenv("DOBBLE_IMG_DIR", "symbols")
+	if !hasEnvConfig() {
+		t.Error("hasEnvConfig is false with DOBBLE_IMG_DIR set")
+	}
+}
+
+func TestDeckPresetOverridesProfile(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path, err := profilesPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := map[string][]string{"print": {"--page-size=A3", "--symbols=8", "--cards=0", "--round=false"}}
+	if err := writeJSONFile(path, profile); err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := parseFlags([]string{"--profile", "print", "--deck", "kids", "--log-level", "error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.imagesPerCard != 6 || opts.totalCards != 31 || opts.pageSize != "A3" {
+		t.Errorf("--profile print --deck kids gave %d symbols, %d cards on %s, want 6, 31 on A3",
+			opts.imagesPerCard, opts.totalCards, opts.pageSize)
+	}
+
+	opts, err = parseFlags([]string{"--profile", "print", "--deck", "kids", "--symbols", "4", "--log-level", "error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.imagesPerCard != 4 || opts.totalCards != 31 {
+		t.Errorf("--deck kids --symbols 4 gave %d symbols, %d cards, want 4, 31", opts.imagesPerCard, opts.totalCards)
+	}
+}
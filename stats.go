@@ -0,0 +1,197 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// symbolUsage is how often, and on which cards, a symbol appears in a deck.
+type symbolUsage struct {
+	File  string `json:"file"`
+	Name  string `json:"name,omitempty"`
+	Count int    `json:"count"`
+	Cards []int  `json:"cards"` // 1-based, as printed
+}
+
+// deckStats summarizes a generated deck as proof that it is balanced.
+type deckStats struct {
+	Cards          int           `json:"cards"`
+	Pages          int           `json:"pages,omitempty"`
+	SymbolsPerCard int           `json:"symbolsPerCard"`
+	Symbols        int           `json:"symbols"`
+	FullDeck       bool          `json:"fullDeck"`
+	ExpectedCount  int           `json:"expectedCount,omitempty"` // appearances per symbol in a full deck
+	Balanced       bool          `json:"balanced"`
+	Violations     []string      `json:"violations,omitempty"`
+	AverageSize    float64       `json:"averageSize"` // mm
+	MinSize        float64       `json:"minSize"`
+	MaxSize        float64       `json:"maxSize"`
+	Usage          []symbolUsage `json:"usage"`
+}
+
+// computeStats counts the symbols of a deck and checks it. In a full deck of
+// k symbols per card every symbol appears exactly k times; a partial deck
+// only has to satisfy the Dobble property.
+func computeStats(m *Manifest) deckStats {
+	full, err := deckSize(m.deckSymbols(), m.Lambda)
+	s := deckStats{
+		Cards:          len(m.Cards),
+		SymbolsPerCard: m.SymbolsPerCard,
+		FullDeck:       err == nil && len(m.Cards) == full,
+	}
+
+	counts := make(map[string]int)
+	cards := make(map[string][]int)
+	var sizes float64
+	var placements int
+	for i, card := range m.Cards {
+		s.Pages = max(s.Pages, card.Page)
+		for _, p := range card.Placements {
+			counts[p.File]++
+			cards[p.File] = append(cards[p.File], i+1)
+			sizes += p.Size
+			placements++
+			if s.MinSize == 0 || p.Size < s.MinSize {
+				s.MinSize = p.Size
+			}
+			s.MaxSize = max(s.MaxSize, p.Size)
+		}
+	}
+	if placements > 0 {
+		s.AverageSize = sizes / float64(placements)
+	}
+
+	s.Symbols = len(counts)
+	for file, count := range counts {
+		s.Usage = append(s.Usage, symbolUsage{File: file, Name: m.SymbolNames[file], Count: count, Cards: cards[file]})
+	}
+	slices.SortFunc(s.Usage, func(a, b symbolUsage) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.File, b.File))
+	})
+
+	for _, v := range validateDeck(m.symbolCards(), m.Lambda) {
+		s.Violations = append(s.Violations, v.String())
+	}
+	s.Balanced = len(s.Violations) == 0
+	if s.FullDeck {
+		s.ExpectedCount = m.deckSymbols()
+		for _, u := range s.Usage {
+			if u.File != m.Logo && u.Count != s.ExpectedCount {
+				s.Balanced = false
+			}
+		}
+	}
+
+	return s
+}
+
+// print writes the statistics in a human-readable form.
+func (s deckStats) print(w io.Writer) {
+	fmt.Fprintf(w, "Cards:             %d\n", s.Cards)
+	if s.Pages > 0 {
+		fmt.Fprintf(w, "Pages:             %d\n", s.Pages)
+	}
+	fmt.Fprintf(w, "Symbols per card:  %d\n", s.SymbolsPerCard)
+	fmt.Fprintf(w, "Distinct symbols:  %d\n", s.Symbols)
+	fmt.Fprintf(w, "Symbol size:       %.1f mm average, %.1f to %.1f mm\n", s.AverageSize, s.MinSize, s.MaxSize)
+	switch {
+	case !s.Balanced:
+		fmt.Fprintln(w, "Balanced:          no")
+	case s.FullDeck:
+		fmt.Fprintf(w, "Balanced:          yes, every symbol appears %d times\n", s.ExpectedCount)
+	default:
+		fmt.Fprintln(w, "Balanced:          yes, every pair of cards shares exactly one symbol (partial deck)")
+	}
+	for _, v := range s.Violations {
+		fmt.Fprintf(w, "  %s\n", v)
+	}
+
+	fmt.Fprintln(w, "\nUsage:")
+	for _, u := range s.Usage {
+		label := u.File
+		if u.Name != "" {
+			label = u.Name + " (" + filepath.Base(u.File) + ")"
+		}
+		marker := ""
+		if s.FullDeck && u.Count != s.ExpectedCount {
+			marker = "  !"
+		}
+		fmt.Fprintf(w, "  %4d  %s%s\n", u.Count, label, marker)
+	}
+}
+
+// save writes the statistics as JSON.
+func (s deckStats) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode statistics: %w", err)
+	}
+	err = writeAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write statistics: %w", err)
+	}
+	return nil
+}
+
+// writeCSV writes the symbol usage as CSV, one symbol per row with the
+// cards it is on separated by spaces, e.g. for answer keys or spreadsheets.
+func (s deckStats) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"symbol", "name", "count", "cards"})
+	for _, u := range s.Usage {
+		cards := make([]string, len(u.Cards))
+		for i, c := range u.Cards {
+			cards[i] = strconv.Itoa(c)
+		}
+		cw.Write([]string{u.File, u.Name, strconv.Itoa(u.Count), strings.Join(cards, " ")})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// runStats implements the stats command, which reports on the deck stored
+// in a manifest file.
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dobble stats [flags] <manifest.json>")
+		fs.PrintDefaults()
+	}
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	asCSV := fs.Bool("csv", false, "print the symbol usage as CSV: symbol, name, count and cards")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one manifest file")
+	}
+
+	m, err := loadManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	s := computeStats(m)
+	if *asCSV {
+		return s.writeCSV(os.Stdout)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+	s.print(os.Stdout)
+	return nil
+}
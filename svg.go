@@ -0,0 +1,101 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/vector"
+)
+
+const mmPerInch = 25.4
+
+func isSVG(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".svg")
+}
+
+// svgScale returns the factor that fits the SVG's user space into a square of
+// the given size.
+func svgScale(sig *fpdf.SVGBasicType, size float64) float64 {
+	extent := math.Max(sig.Wd, sig.Ht)
+	if extent <= 0 {
+		return 1
+	}
+	return size / extent
+}
+
+// drawSVG embeds the SVG paths as vector strokes in the ink color, centered
+// in the square at (x, y) and rotated around its center by rotation degrees.
+func drawSVG(pdf *fpdf.Fpdf, sig *fpdf.SVGBasicType, x, y, size float64, rotation int, ink color.RGBA) {
+	scale := svgScale(sig, size)
+	originX := x + (size-sig.Wd*scale)/2
+	originY := y + (size-sig.Ht*scale)/2
+
+	pdf.TransformBegin()
+	pdf.TransformRotate(float64(rotation), x+size/2, y+size/2)
+	pdf.SetDrawColor(int(ink.R), int(ink.G), int(ink.B))
+	pdf.SetXY(originX, originY)
+	pdf.SVGBasicWrite(sig, scale)
+	pdf.TransformEnd()
+}
+
+// rasterizeSVG fills the SVG paths into a square image of sizePx pixels. The
+// basic SVG parser carries no color information, so shapes are filled black.
+func rasterizeSVG(sig *fpdf.SVGBasicType, sizePx int) *image.NRGBA {
+	if sizePx < 1 {
+		sizePx = 1
+	}
+	scale := svgScale(sig, float64(sizePx))
+	offX := (float64(sizePx) - sig.Wd*scale) / 2
+	offY := (float64(sizePx) - sig.Ht*scale) / 2
+	pt := func(x, y float64) (float32, float32) {
+		return float32(x*scale + offX), float32(y*scale + offY)
+	}
+
+	r := vector.NewRasterizer(sizePx, sizePx)
+	for _, path := range sig.Segments {
+		var curX, curY, startX, startY float64
+		for i, seg := range path {
+			switch seg.Cmd {
+			case 'M':
+				if i > 0 {
+					r.ClosePath()
+				}
+				curX, curY = seg.Arg[0], seg.Arg[1]
+				startX, startY = curX, curY
+				r.MoveTo(pt(curX, curY))
+			case 'L':
+				curX, curY = seg.Arg[0], seg.Arg[1]
+				r.LineTo(pt(curX, curY))
+			case 'H':
+				curX = seg.Arg[0]
+				r.LineTo(pt(curX, curY))
+			case 'V':
+				curY = seg.Arg[0]
+				r.LineTo(pt(curX, curY))
+			case 'Q':
+				bx, by := pt(seg.Arg[0], seg.Arg[1])
+				curX, curY = seg.Arg[2], seg.Arg[3]
+				cx, cy := pt(curX, curY)
+				r.QuadTo(bx, by, cx, cy)
+			case 'C':
+				bx, by := pt(seg.Arg[0], seg.Arg[1])
+				cx, cy := pt(seg.Arg[2], seg.Arg[3])
+				curX, curY = seg.Arg[4], seg.Arg[5]
+				dx, dy := pt(curX, curY)
+				r.CubeTo(bx, by, cx, cy, dx, dy)
+			case 'Z':
+				r.ClosePath()
+				curX, curY = startX, startY
+			}
+		}
+		r.ClosePath()
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, sizePx, sizePx))
+	r.Draw(dst, dst.Bounds(), image.NewUniform(color.Black), image.Point{})
+	return dst
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"math"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// exportSVGs writes every card of the planned deck as an SVG document.
+// Symbols are either embedded as data URIs or referenced by a path relative
+// to the SVG file.
+func exportSVGs(m *Manifest, opts options) error {
+	if err := os.MkdirAll(opts.svgDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create SVG directory: %w", err)
+	}
+
+	hrefs := make(map[string]string)
+	prog := newProgress("svg", len(m.Cards), opts)
+	defer prog.finish()
+	for _, card := range m.Cards {
+		path := filepath.Join(opts.svgDir, cardFileName(card.Index, "svg"))
+		if err := writeCardSVG(path, card, opts, hrefs); err != nil {
+			return err
+		}
+		slog.Debug("Card exported", "index", card.Index, "path", path)
+		if err := prog.step(len(card.Placements)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeCardSVG(path string, card ManifestCard, opts options, hrefs map[string]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	w, h := opts.cardSize()
+	out := bufio.NewWriter(f)
+
+	fmt.Fprintf(out, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%gmm" height="%gmm" viewBox="0 0 %g %g">`+"\n", w, h, w, h)
+	switch {
+	case opts.roundCards:
+		fmt.Fprintf(out, `  <circle id="cut" cx="%g" cy="%g" r="%g" fill="white" stroke="black" stroke-width="%g"/>`+"\n", w/2, h/2, w/2-defaultLineWidth/2, defaultLineWidth)
+	case opts.hexCards:
+		var points []string
+		for _, p := range hexPoints(defaultLineWidth/2, defaultLineWidth/2, w-defaultLineWidth, h-defaultLineWidth) {
+			points = append(points, fmt.Sprintf("%g,%g", p.X, p.Y))
+		}
+		fmt.Fprintf(out, `  <polygon id="cut" points="%s" fill="white" stroke="black" stroke-width="%g"/>`+"\n", strings.Join(points, " "), defaultLineWidth)
+	case opts.cornerRadius > 0:
+		fmt.Fprintf(out, `  <rect id="cut" x="%g" y="%g" width="%g" height="%g" rx="%g" fill="white" stroke="black" stroke-width="%g"/>`+"\n", defaultLineWidth/2, defaultLineWidth/2, w-defaultLineWidth, h-defaultLineWidth, opts.cornerRadius, defaultLineWidth)
+	default:
+		fmt.Fprintf(out, `  <rect id="cut" x="%g" y="%g" width="%g" height="%g" fill="white" stroke="black" stroke-width="%g"/>`+"\n", defaultLineWidth/2, defaultLineWidth/2, w-defaultLineWidth, h-defaultLineWidth, defaultLineWidth)
+	}
+
+	for _, s := range card.Placements {
+		href, ok := hrefs[s.File]
+		if !ok {
+			if href, err = symbolHref(s.File, opts); err != nil {
+				return err
+			}
+			hrefs[s.File] = href
+		}
+
+		// SVG rotates clockwise, the PDF renderer counter-clockwise.
+		cx, cy := s.X+s.Size/2, s.Y+s.Size/2
+		if opts.labels {
+			writeLabeledSymbolSVG(out, s, opts, href)
+			continue
+		}
+		if opts.tactile {
+			writeTactileSymbolSVG(out, s, opts, href)
+			continue
+		}
+		fmt.Fprintf(out, `  <image x="%g" y="%g" width="%g" height="%g" preserveAspectRatio="xMidYMid meet" transform="rotate(%d %g %g)" href="%s" xlink:href="%[8]s"/>`+"\n",
+			s.X, s.Y, s.Size, s.Size, -s.Rotation, cx, cy, html.EscapeString(href))
+	}
+
+	fmt.Fprintln(out, "</svg>")
+
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// svgGlyphWidth is the average advance of a glyph relative to the font size,
+// used to shrink labels that would not fit their box. SVG viewers pick their
+// own font, so it cannot be measured.
+const svgGlyphWidth = 0.55
+
+// writeLabeledSymbolSVG writes the symbol with its label below, rotated
+// together like in the PDF.
+func writeLabeledSymbolSVG(out io.Writer, s ManifestSymbol, opts options, href string) {
+	imgSize, labelHeight := opts.labelSplit(s.Size)
+	lines := opts.labelLines(s.File)
+	lineHeight := labelHeight / float64(len(lines))
+
+	cx, cy := s.X+s.Size/2, s.Y+s.Size/2
+	fmt.Fprintf(out, `  <g transform="rotate(%d %g %g)">`+"\n", -s.Rotation, cx, cy)
+	fmt.Fprintf(out, `    <image x="%g" y="%g" width="%g" height="%g" preserveAspectRatio="xMidYMid meet" href="%s" xlink:href="%[5]s"/>`+"\n",
+		cx-imgSize/2, s.Y, imgSize, imgSize, html.EscapeString(href))
+	for i, label := range lines {
+		fontSize := math.Min(opts.labelSize*mmPerPt, lineHeight/labelLineHeight)
+		if w := float64(utf8.RuneCountInString(label)) * svgGlyphWidth * fontSize; w > s.Size {
+			fontSize *= s.Size / w
+		}
+		fmt.Fprintf(out, `    <text x="%g" y="%g" font-family="sans-serif" font-size="%g" text-anchor="middle" dominant-baseline="central">%s</text>`+"\n",
+			cx, s.Y+imgSize+(float64(i)+0.5)*lineHeight, fontSize, html.EscapeString(label))
+	}
+	fmt.Fprintln(out, "  </g>")
+}
+
+// symbolHref returns how a symbol file is referenced from a card SVG.
+func symbolHref(file string, opts options) (string, error) {
+	// Entries of zip archives cannot be referenced, so they are always
+	// embedded.
+	if _, _, inZip := splitZipPath(file); !opts.svgEmbed && !inZip {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return "", err
+		}
+		dir, err := filepath.Abs(opts.svgDir)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(dir, abs)
+		if err != nil {
+			return "", err
+		}
+		return filepath.ToSlash(rel), nil
+	}
+
+	data, err := readSymbolFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read symbol %s: %w", file, err)
+	}
+
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(file)))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/font"
+)
+
+// textEntryPrefix marks symbol list entries that are words, numbers or
+// letters rather than image paths, e.g. "text:Katze".
+const textEntryPrefix = "text:"
+
+// symbolEntry is a line of a symbol list: the image and an optional display
+// name.
+type symbolEntry struct {
+	Path string
+	Name string
+}
+
+// readSymbolEntries reads a symbol list in CSV form, one "path[,name]"
+// record per line; a plain list of paths is valid as well. Lines starting
+// with # are comments. Relative paths are resolved against the directory of
+// the list file; http(s) URLs and text entries are kept as they are.
+func readSymbolEntries(listPath string) ([]symbolEntry, error) {
+	f, err := os.Open(listPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open symbol list: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	base := filepath.Dir(listPath)
+	var entries []symbolEntry
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read symbol list: %w", err)
+		}
+
+		p := strings.TrimSpace(record[0])
+		if p == "" {
+			continue
+		}
+		if !isRemote(p) && !strings.HasPrefix(p, textEntryPrefix) && !filepath.IsAbs(p) {
+			p = filepath.Join(base, p)
+		}
+
+		entry := symbolEntry{Path: p}
+		if len(record) > 1 {
+			entry.Name = strings.TrimSpace(record[1])
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// loadSymbolList resolves a symbol list into local image paths, in list
+// order, and the display names keyed by those paths. Text entries are
+// rendered with the symbol font to images in the generated directory and
+// named by their text, so a list can mix pictures and words.
+func loadSymbolList(listPath string, opts options) ([]string, map[string]string, error) {
+	entries, err := readSymbolEntries(listPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := make([]string, len(entries))
+	names := make(map[string]string)
+	var face font.Face
+	for i, e := range entries {
+		files[i] = e.Path
+		if text, ok := strings.CutPrefix(e.Path, textEntryPrefix); ok {
+			if face == nil {
+				if face, err = loadFontFace(opts.font, textSymbolPx); err != nil {
+					return nil, nil, err
+				}
+				defer face.Close()
+				if err := os.MkdirAll(opts.generatedDir, 0o755); err != nil {
+					return nil, nil, fmt.Errorf("failed to create symbol directory: %w", err)
+				}
+			}
+			files[i] = filepath.Join(opts.generatedDir, fmt.Sprintf("word_%03d.png", i+1))
+			if err := writePNG(files[i], styleText(renderText(face, text), opts.textStyle), generatedSymbolDPI); err != nil {
+				return nil, nil, err
+			}
+			if e.Name == "" {
+				e.Name = text
+			}
+		} else if isRemote(e.Path) {
+			fetched, err := fetchRemoteSymbols([]string{e.Path}, filepath.Join(opts.generatedDir, "remote"))
+			if err != nil {
+				return nil, nil, err
+			}
+			files[i] = fetched[0]
+		} else if _, err := os.Stat(e.Path); err != nil {
+			return nil, nil, fmt.Errorf("symbol list entry %d: %w", i+1, err)
+		}
+		if e.Name != "" {
+			names[files[i]] = e.Name
+		}
+	}
+
+	return files, names, nil
+}
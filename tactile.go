@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"strconv"
+
+	"github.com/disintegration/imaging"
+)
+
+// Braille dimensions in mm, within the ranges of common braille standards
+// and large enough to read on swell paper.
+const (
+	brailleDot         = 1.5 // dot diameter
+	brailleDotSpacing  = 2.5 // between dots of a cell
+	brailleCellSpacing = 6.2 // between corresponding dots of neighboring cells
+	brailleMargin      = 1.0 // above and below the dots
+)
+
+// brailleNumberSign precedes digits in braille; dots 3, 4, 5 and 6.
+const brailleNumberSign = 0b111100
+
+// brailleDigits are the cells of the digits 0 to 9, which share the cells
+// of the letters j and a to i. Bit n is dot n+1.
+var brailleDigits = [10]uint8{
+	0b011010, 0b000001, 0b000011, 0b001001, 0b011001,
+	0b010001, 0b001011, 0b011011, 0b010011, 0b001010,
+}
+
+// brailleNumber returns the cells spelling n in braille.
+func brailleNumber(n int) []uint8 {
+	cells := []uint8{brailleNumberSign}
+	for _, d := range strconv.Itoa(n) {
+		cells = append(cells, brailleDigits[d-'0'])
+	}
+	return cells
+}
+
+// numberSymbols numbers the symbols of a deck from 1 in order of first
+// appearance. Tactile decks mark every symbol with its number.
+func numberSymbols(m *Manifest) map[string]int {
+	codes := make(map[string]int)
+	for i, path := range deckSymbols(m) {
+		codes[path] = i + 1
+	}
+	return codes
+}
+
+// tactileHeight is the height of the band holding a row of braille cells.
+const tactileHeight = 2*brailleDotSpacing + brailleDot + 2*brailleMargin
+
+// tactileSplit divides the box of a symbol into the image above and the
+// braille band below, in mm. The band never takes more than half the box.
+func tactileSplit(size float64) (imgSize, band float64) {
+	band = min(tactileHeight, size/2)
+	return size - band, band
+}
+
+// brailleDots returns the centers of the raised dots of the symbol's number,
+// relative to the top left corner of a band of the given width and height.
+// Cells are scaled down if the band is too small for full-size braille.
+func brailleDots(code int, width, height float64) (dots [][2]float64, diameter float64) {
+	cells := brailleNumber(code)
+	w := float64(len(cells)-1)*brailleCellSpacing + brailleDotSpacing + brailleDot
+	scale := min(1, width/w, height/tactileHeight)
+	x0 := (width - w*scale) / 2
+	y0 := (height - (2*brailleDotSpacing+brailleDot)*scale) / 2
+
+	for i, cell := range cells {
+		for dot := range 6 {
+			if cell&(1<<dot) == 0 {
+				continue
+			}
+			col, row := float64(dot/3), float64(dot%3)
+			dots = append(dots, [2]float64{
+				x0 + (float64(i)*brailleCellSpacing+col*brailleDotSpacing+brailleDot/2)*scale,
+				y0 + (row*brailleDotSpacing+brailleDot/2)*scale,
+			})
+		}
+	}
+	return dots, brailleDot * scale
+}
+
+// drawTactileSymbol draws the symbol upright with its braille number below
+// and rotates both together. The dots are solid black, so they rise on
+// swell paper.
+func (r *renderer) drawTactileSymbol(x, y float64, s ManifestSymbol) error {
+	imgSize, band := tactileSplit(s.Size)
+	x, y = x+s.X, y+s.Y
+
+	r.pdf.TransformBegin()
+	defer r.pdf.TransformEnd()
+	r.pdf.TransformRotate(float64(s.Rotation), x+s.Size/2, y+s.Size/2)
+
+	if err := r.processImage(s.File, x+(s.Size-imgSize)/2, y, imgSize, 0, s.SymbolLook); err != nil {
+		return err
+	}
+
+	dots, diameter := brailleDots(r.opts.symbolCodes[s.File], s.Size, band)
+	r.pdf.SetFillColor(0, 0, 0)
+	for _, d := range dots {
+		r.pdf.Circle(x+d[0], y+imgSize+d[1], diameter/2, "F")
+	}
+	return nil
+}
+
+// rasterTactileSymbol draws the symbol upright with its braille number
+// below and rotates both together, like drawTactileSymbol.
+func rasterTactileSymbol(src *symbolSource, s ManifestSymbol, opts options, dpi float64) image.Image {
+	imgSize, band := tactileSplit(s.Size)
+	size, imgPx := mmToPx(s.Size, dpi), mmToPx(imgSize, dpi)
+	unit := image.NewNRGBA(image.Rect(0, 0, size, size))
+
+	sym := s.apply(src.render(imgPx, 0, opts.resampleFilter()), src)
+	sym = opts.applyInkMode(opts.applyAccessibility(sym, s.SymbolLook, src))
+	if s.Mirror {
+		sym = imaging.FlipH(sym)
+	}
+	sb := sym.Bounds()
+	at := image.Pt((size-sb.Dx())/2, (imgPx-sb.Dy())/2)
+	draw.Draw(unit, sb.Sub(sb.Min).Add(at), sym, sb.Min, draw.Over)
+
+	px := func(mm float64) float64 { return mm / mmPerInch * dpi }
+	dots, diameter := brailleDots(opts.symbolCodes[s.File], s.Size, band)
+	black := image.NewUniform(color.Black)
+	for _, d := range dots {
+		mask := &circleMask{cx: px(d[0]), cy: px(imgSize + d[1]), r: px(diameter / 2), inner: -1, bounds: unit.Bounds()}
+		draw.DrawMask(unit, unit.Bounds(), black, image.Point{}, mask, image.Point{}, draw.Over)
+	}
+
+	return imaging.Rotate(unit, float64(s.Rotation), color.Transparent)
+}
+
+// writeTactileSymbolSVG writes the symbol with its braille number below,
+// rotated together like in the PDF.
+func writeTactileSymbolSVG(out io.Writer, s ManifestSymbol, opts options, href string) {
+	imgSize, band := tactileSplit(s.Size)
+	cx, cy := s.X+s.Size/2, s.Y+s.Size/2
+	fmt.Fprintf(out, `  <g transform="rotate(%d %g %g)">`+"\n", -s.Rotation, cx, cy)
+	fmt.Fprintf(out, `    <image x="%g" y="%g" width="%g" height="%g" preserveAspectRatio="xMidYMid meet" href="%s" xlink:href="%[5]s"/>`+"\n",
+		cx-imgSize/2, s.Y, imgSize, imgSize, html.EscapeString(href))
+	dots, diameter := brailleDots(opts.symbolCodes[s.File], s.Size, band)
+	for _, d := range dots {
+		fmt.Fprintf(out, `    <circle cx="%g" cy="%g" r="%g" fill="black"/>`+"\n", s.X+d[0], s.Y+imgSize+d[1], diameter/2)
+	}
+	fmt.Fprintln(out, "  </g>")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tempImages keeps the processed symbol images embedded in the PDF, for
+// --keep-temp. They show a symbol after cropping, recoloring and rotation,
+// exactly as the PDF got it, which narrows down where rendering goes wrong.
+type tempImages struct {
+	dir   string
+	files []string
+}
+
+// newTempImages creates dir, or a new directory in the system temp
+// directory if dir is empty.
+func newTempImages(dir string) (*tempImages, error) {
+	if dir == "" {
+		d, err := os.MkdirTemp("", "dobble-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		return &tempImages{dir: d}, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	return &tempImages{dir: dir}, nil
+}
+
+// save writes a rendition of the symbol file, named after the file and the
+// size and rotation it was rendered at.
+func (t *tempImages) save(file string, key renditionKey, rend rendition) error {
+	ext := ".png"
+	if rend.imageType == "JPEG" {
+		ext = ".jpg"
+	}
+	base := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	id := strings.TrimPrefix(rend.name, "image-")[:8]
+	path := filepath.Join(t.dir, fmt.Sprintf("%s_%dpx_%ddeg_%s%s", base, key.sizePx, key.rotation, id, ext))
+
+	if err := os.WriteFile(path, rend.data, 0o644); err != nil {
+		return fmt.Errorf("failed to keep intermediate image: %w", err)
+	}
+	t.files = append(t.files, path)
+	return nil
+}
+
+// list logs every kept image, so they can be found after the run.
+func (t *tempImages) list() {
+	for _, path := range t.files {
+		slog.Info("Intermediate image kept", "path", path)
+	}
+	slog.Info("Intermediate images kept", "dir", t.dir, "count", len(t.files))
+}
+
+// keepTemp saves a rendition of the symbol file with --keep-temp, the first
+// time it is embedded.
+func (r *renderer) keepTemp(file string, key renditionKey, rend rendition) error {
+	if r.temp == nil || rend.data == nil {
+		return nil
+	}
+	return r.temp.save(file, key, rend)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// textSymbolPx is the font size in pixels at which text symbols are
+// rendered. It is large enough to stay sharp on print-size cards.
+const textSymbolPx = 512
+
+// generatedSymbolDPI is the resolution recorded in generated symbol images;
+// at 72 DPI font points equal pixels.
+const generatedSymbolDPI = 72
+
+// readSymbolList reads one symbol per line. Blank lines and lines starting
+// with # are skipped.
+func readSymbolList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open symbol list: %w", err)
+	}
+	defer f.Close()
+
+	var symbols []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		symbols = append(symbols, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read symbol list: %w", err)
+	}
+
+	return symbols, nil
+}
+
+// loadFontFace loads the TrueType or OpenType font at path, or the bundled
+// Go font if path is empty. Color emoji fonts are not supported; use a
+// monochrome font such as Noto Emoji for emoji symbols.
+func loadFontFace(path string, sizePx float64) (font.Face, error) {
+	data := goregular.TTF
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read font: %w", err)
+		}
+	}
+
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font: %w", err)
+	}
+
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: sizePx, DPI: generatedSymbolDPI, Hinting: font.HintingNone})
+}
+
+// renderText draws text in black on a transparent image cropped to the
+// inked area, so the symbol fills its placement like an image would.
+func renderText(face font.Face, text string) *image.NRGBA {
+	bounds, _ := font.BoundString(face, text)
+	rect := image.Rect(bounds.Min.X.Floor(), bounds.Min.Y.Floor(), bounds.Max.X.Ceil(), bounds.Max.Y.Ceil())
+	if rect.Empty() {
+		rect = image.Rect(0, 0, 1, 1)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.Black,
+		Face: face,
+		Dot:  fixed.P(-rect.Min.X, -rect.Min.Y),
+	}
+	d.DrawString(text)
+
+	return img
+}
+
+// textStyles lists the values accepted by --text-style.
+var textStyles = []string{"plain", "boxed", "circled"}
+
+// styleText frames rendered text for the given style, so words read as
+// symbols next to pictures rather than as captions.
+func styleText(img *image.NRGBA, style string) *image.NRGBA {
+	if style != "boxed" && style != "circled" {
+		return img
+	}
+	stroke := float64(textSymbolPx) / 24
+	margin := float64(textSymbolPx) / 6
+	tb := img.Bounds()
+	w, h := float64(tb.Dx())+2*margin, float64(tb.Dy())+2*margin
+
+	// dist returns how far a point is outside the frame's center line,
+	// negative inside, relative to the center of the symbol.
+	var dist func(x, y float64) float64
+	if style == "circled" {
+		w = math.Hypot(w, h)
+		h = w
+		dist = func(x, y float64) float64 { return math.Hypot(x, y) - w/2 + stroke }
+	} else {
+		radius := margin
+		dist = func(x, y float64) float64 {
+			qx := math.Abs(x) - (w/2 - stroke - radius)
+			qy := math.Abs(y) - (h/2 - stroke - radius)
+			return math.Hypot(math.Max(qx, 0), math.Max(qy, 0)) + math.Min(math.Max(qx, qy), 0) - radius
+		}
+	}
+
+	out := image.NewNRGBA(image.Rect(0, 0, int(math.Ceil(w)), int(math.Ceil(h))))
+	ob := out.Bounds()
+	for y := range ob.Dy() {
+		for x := range ob.Dx() {
+			d := math.Abs(dist(float64(x)+0.5-w/2, float64(y)+0.5-h/2)) - stroke/2
+			if a := math.Min(math.Max(0.5-d, 0), 1); a > 0 {
+				out.SetNRGBA(x, y, color.NRGBA{A: uint8(a * 255)})
+			}
+		}
+	}
+	at := image.Pt((ob.Dx()-tb.Dx())/2, (ob.Dy()-tb.Dy())/2)
+	draw.Draw(out, tb.Sub(tb.Min).Add(at), img, tb.Min, draw.Over)
+	return out
+}
+
+// renderTextSymbols renders every entry of the symbol list at listPath to a
+// PNG in dir and returns the file paths. The files are named by position,
+// since words and emoji do not make portable file names.
+func renderTextSymbols(listPath, fontPath, style, dir string) ([]string, error) {
+	symbols, err := readSymbolList(listPath)
+	if err != nil {
+		return nil, err
+	}
+
+	face, err := loadFontFace(fontPath, textSymbolPx)
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create symbol directory: %w", err)
+	}
+
+	files := make([]string, len(symbols))
+	for i, text := range symbols {
+		path := filepath.Join(dir, fmt.Sprintf("text_%03d.png", i+1))
+		if err := writePNG(path, styleText(renderText(face, text), style), generatedSymbolDPI); err != nil {
+			return nil, err
+		}
+		files[i] = path
+	}
+
+	return files, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+
+	"github.com/disintegration/imaging"
+)
+
+// tintModes lists the values accepted by --tint.
+var tintModes = []string{"none", "random", "cycle"}
+
+// defaultTintPalette holds colors that are easy to tell apart in print and
+// dark enough for thin line art on white.
+var defaultTintPalette = []string{
+	"#d62728", "#1f77b4", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b",
+	"#e377c2", "#17becf", "#bcbd22", "#393b79", "#ad494a", "#637939",
+}
+
+// monochromeSaturation is the largest spread between the color channels of a
+// pixel, out of 255, that still counts as gray.
+const monochromeSaturation = 32
+
+// SymbolLook is the appearance of a symbol occurrence beyond its position,
+// size and rotation.
+type SymbolLook struct {
+	Tint       string  `json:"tint,omitempty"`       // #rrggbb monochrome symbols are drawn in
+	Hue        float64 `json:"hue,omitempty"`        // hue shift in degrees
+	Brightness float64 `json:"brightness,omitempty"` // brightness shift in percent
+	Mirror     bool    `json:"mirror,omitempty"`     // flipped horizontally
+}
+
+// apply recolors img, a rendering of src, with the tint and the hue and
+// brightness jitter. Mirroring is left to the caller, since the PDF does it
+// with a transformation instead of a new image.
+func (l SymbolLook) apply(img image.Image, src *symbolSource) image.Image {
+	img = l.applyTint(img, src)
+	if l.Hue == 0 && l.Brightness == 0 {
+		return img
+	}
+	return imaging.AdjustFunc(img, l.adjust)
+}
+
+// applyTint recolors img with the tint if the source is monochrome: dark
+// pixels take the tint and light ones stay light. Colored symbols are
+// returned unchanged.
+func (l SymbolLook) applyTint(img image.Image, src *symbolSource) image.Image {
+	if l.Tint == "" || !src.monochrome() {
+		return img
+	}
+	tint, err := parseHexColor(l.Tint)
+	if err != nil {
+		return img
+	}
+
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	mix := func(c uint8, lum float64) uint8 {
+		return uint8(float64(c)*(1-lum) + 255*lum + 0.5)
+	}
+	for y := range b.Dy() {
+		for x := range b.Dx() {
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			lum := (0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)) / 255
+			dst.SetNRGBA(x, y, color.NRGBA{R: mix(tint.R, lum), G: mix(tint.G, lum), B: mix(tint.B, lum), A: c.A})
+		}
+	}
+	return dst
+}
+
+// monochrome reports whether the symbol has a single hue: SVG symbols,
+// which are drawn in black, and raster images whose visible pixels are all
+// shades of gray.
+func (s *symbolSource) monochrome() bool {
+	s.monoOnce.Do(func() {
+		if s.svg != nil {
+			s.mono = true
+			return
+		}
+		b := s.img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := color.NRGBAModel.Convert(s.img.At(x, y)).(color.NRGBA)
+				if c.A < 0x20 {
+					continue
+				}
+				if max(c.R, c.G, c.B)-min(c.R, c.G, c.B) > monochromeSaturation {
+					return
+				}
+			}
+		}
+		s.mono = true
+	})
+	return s.mono
+}
+
+// tintAssigner gives every symbol file its color for --tint. Cycling hands
+// out the palette in order of first appearance; random does the same with a
+// shuffled palette, so colors are used evenly either way. It draws from its
+// own generator, so tinting leaves the layout of the deck unchanged.
+type tintAssigner struct {
+	palette []string
+	tints   map[string]string
+}
+
+func newTintAssigner(mode string, palette []string, seed int64) *tintAssigner {
+	if mode == "" || mode == "none" || len(palette) == 0 {
+		return &tintAssigner{}
+	}
+	palette = append([]string(nil), palette...)
+	if mode == "random" {
+		rng := rand.New(rand.NewSource(seed))
+		rng.Shuffle(len(palette), func(i, j int) { palette[i], palette[j] = palette[j], palette[i] })
+	}
+	return &tintAssigner{palette: palette, tints: make(map[string]string)}
+}
+
+// tint returns the color of the symbol file, or "" if tinting is off.
+func (t *tintAssigner) tint(file string) string {
+	if len(t.palette) == 0 {
+		return ""
+	}
+	c, ok := t.tints[file]
+	if !ok {
+		c = t.palette[len(t.tints)%len(t.palette)]
+		t.tints[file] = c
+	}
+	return c
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// translations holds symbol labels in several languages, read from a CSV
+// file whose header names the languages:
+//
+//	symbol,en,de,fr
+//	cat.png,cat,Katze,chat
+//
+// The first column identifies the symbol by file name, file name without
+// extension, path or its name from the symbol list.
+type translations struct {
+	langs  []string
+	labels map[string]map[string]string // symbol key → language → label
+}
+
+func loadTranslations(path string) (translations, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return translations{}, fmt.Errorf("failed to open translations: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return translations{}, fmt.Errorf("failed to read translations: %w", err)
+	}
+	if len(records) < 2 || len(records[0]) < 2 {
+		return translations{}, fmt.Errorf("translations %s need a header with at least one language and one row", path)
+	}
+
+	t := translations{labels: make(map[string]map[string]string)}
+	for _, lang := range records[0][1:] {
+		t.langs = append(t.langs, strings.TrimSpace(lang))
+	}
+	for _, record := range records[1:] {
+		row := make(map[string]string)
+		for i, lang := range t.langs {
+			if label := strings.TrimSpace(record[i+1]); label != "" {
+				row[lang] = label
+			}
+		}
+		t.labels[strings.TrimSpace(record[0])] = row
+	}
+	return t, nil
+}
+
+// label returns the label of the symbol file in lang, or fallback if it has
+// no translation.
+func (t translations) label(file, fallback, lang string) string {
+	base := filepath.Base(file)
+	for _, key := range []string{file, filepath.ToSlash(file), base, strings.TrimSuffix(base, filepath.Ext(base)), fallback} {
+		if label := t.labels[key][lang]; label != "" {
+			return label
+		}
+	}
+	return fallback
+}
+
+// labelLines returns the label lines printed below a symbol: one per
+// language selected with --label-lang, or its name.
+func (o options) labelLines(file string) []string {
+	name := symbolLabel(o.symbolNames, file)
+	if len(o.labelLangs) == 0 {
+		return []string{name}
+	}
+	lines := make([]string, len(o.labelLangs))
+	for i, lang := range o.labelLangs {
+		lines[i] = o.translations.label(file, name, lang)
+	}
+	return lines
+}
+
+// readTranslations loads the file given with --translations and checks
+// that it has every language selected with --label-lang.
+func (o *options) readTranslations() error {
+	if o.translationsFile == "" {
+		return nil
+	}
+	t, err := loadTranslations(o.translationsFile)
+	if err != nil {
+		return err
+	}
+	for _, lang := range o.labelLangs {
+		if !slices.Contains(t.langs, lang) {
+			return fmt.Errorf("translations %s have no column for language %q, only %s", o.translationsFile, lang, strings.Join(t.langs, ", "))
+		}
+	}
+	o.translations = t
+	return nil
+}
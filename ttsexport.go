@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	ttsSheetCols    = 10
+	ttsSheetRows    = 7
+	ttsCardsPerDeck = ttsSheetCols*ttsSheetRows - 1 // the last slot is reserved by TTS
+	ttsCircleType   = 2                             // CustomDeck shape: 0 rectangle, 2 circle
+)
+
+type ttsSave struct {
+	ObjectStates []ttsObject `json:"ObjectStates"`
+}
+
+type ttsTransform struct {
+	PosX   float64 `json:"posX"`
+	PosY   float64 `json:"posY"`
+	PosZ   float64 `json:"posZ"`
+	RotX   float64 `json:"rotX"`
+	RotY   float64 `json:"rotY"`
+	RotZ   float64 `json:"rotZ"`
+	ScaleX float64 `json:"scaleX"`
+	ScaleY float64 `json:"scaleY"`
+	ScaleZ float64 `json:"scaleZ"`
+}
+
+type ttsCustomDeck struct {
+	FaceURL      string `json:"FaceURL"`
+	BackURL      string `json:"BackURL"`
+	NumWidth     int    `json:"NumWidth"`
+	NumHeight    int    `json:"NumHeight"`
+	BackIsHidden bool   `json:"BackIsHidden"`
+	UniqueBack   bool   `json:"UniqueBack"`
+	Type         int    `json:"Type"`
+}
+
+type ttsObject struct {
+	Name             string                   `json:"Name"`
+	Nickname         string                   `json:"Nickname,omitempty"`
+	Transform        ttsTransform             `json:"Transform"`
+	CardID           int                      `json:"CardID,omitempty"`
+	DeckIDs          []int                    `json:"DeckIDs,omitempty"`
+	CustomDeck       map[string]ttsCustomDeck `json:"CustomDeck"`
+	ContainedObjects []ttsObject              `json:"ContainedObjects,omitempty"`
+}
+
+var ttsFaceDown = ttsTransform{PosY: 1, RotY: 180, RotZ: 180, ScaleX: 1, ScaleY: 1, ScaleZ: 1}
+
+// exportTTS writes the deck as Tabletop Simulator sprite sheets (10×7 cards
+// each), a card back image and a saved-object JSON referencing them.
+func exportTTS(m *Manifest, opts options) error {
+	if err := os.MkdirAll(opts.ttsDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create TTS directory: %w", err)
+	}
+
+	w, _ := opts.cardSize()
+	dpi := float64(opts.ttsCardPx) / w * mmPerInch
+
+	backPath := filepath.Join(opts.ttsDir, "back.png")
+	if err := writePNG(backPath, ttsBackImage(opts, dpi), dpi); err != nil {
+		return err
+	}
+
+	deck := ttsObject{
+		Name:       "Deck",
+		Nickname:   "Dobble",
+		Transform:  ttsFaceDown,
+		CustomDeck: make(map[string]ttsCustomDeck),
+	}
+
+	cache := newSymbolCache()
+	prog := newProgress("tts", len(m.Cards), opts)
+	defer prog.finish()
+	for sheet := 0; sheet*ttsCardsPerDeck < len(m.Cards); sheet++ {
+		cards := m.Cards[sheet*ttsCardsPerDeck : min((sheet+1)*ttsCardsPerDeck, len(m.Cards))]
+		sheetPath := filepath.Join(opts.ttsDir, fmt.Sprintf("sheet_%d.png", sheet+1))
+
+		img, err := ttsSheet(cards, opts, cache, dpi, prog)
+		if err != nil {
+			return err
+		}
+		if err := writePNG(sheetPath, img, dpi); err != nil {
+			return err
+		}
+
+		deckID := sheet + 1
+		custom := ttsCustomDeck{
+			FaceURL:      ttsURL(sheetPath, opts),
+			BackURL:      ttsURL(backPath, opts),
+			NumWidth:     ttsSheetCols,
+			NumHeight:    ttsSheetRows,
+			BackIsHidden: true,
+		}
+		if opts.roundCards {
+			custom.Type = ttsCircleType
+		}
+		deck.CustomDeck[strconv.Itoa(deckID)] = custom
+
+		for i, card := range cards {
+			cardID := deckID*100 + i
+			deck.DeckIDs = append(deck.DeckIDs, cardID)
+			deck.ContainedObjects = append(deck.ContainedObjects, ttsObject{
+				Name:       "Card",
+				Nickname:   fmt.Sprintf("Card %d", card.Index+1),
+				Transform:  ttsFaceDown,
+				CardID:     cardID,
+				CustomDeck: map[string]ttsCustomDeck{strconv.Itoa(deckID): custom},
+			})
+		}
+
+		slog.Info("TTS sheet written", "path", sheetPath, "cards", len(cards))
+	}
+
+	data, err := json.MarshalIndent(ttsSave{ObjectStates: []ttsObject{deck}}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode TTS deck: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(opts.ttsDir, "deck.json"), data, 0o644)
+}
+
+func ttsSheet(cards []ManifestCard, opts options, cache *symbolCache, dpi float64, prog *progress) (*image.NRGBA, error) {
+	w, h := opts.cardSize()
+	cellW, cellH := mmToPx(w, dpi), mmToPx(h, dpi)
+	sheet := image.NewNRGBA(image.Rect(0, 0, cellW*ttsSheetCols, cellH*ttsSheetRows))
+
+	for i, card := range cards {
+		img, err := rasterizeCard(card, opts, cache, dpi)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render card %d: %w", card.Index, err)
+		}
+		at := image.Pt((i%ttsSheetCols)*cellW, (i/ttsSheetCols)*cellH)
+		draw.Draw(sheet, img.Bounds().Add(at), img, image.Point{}, draw.Over)
+		if err := prog.step(len(card.Placements)); err != nil {
+			return nil, err
+		}
+	}
+
+	return sheet, nil
+}
+
+// ttsBackImage renders the card back in the configured back color.
+func ttsBackImage(opts options, dpi float64) *image.NRGBA {
+	w, h := opts.cardSize()
+	img := image.NewNRGBA(image.Rect(0, 0, mmToPx(w, dpi), mmToPx(h, dpi)))
+	bounds := img.Bounds()
+
+	c := color.RGBA{R: 0x2b, G: 0x4c, B: 0x7e, A: 0xff}
+	if opts.backs {
+		c = opts.back.Color
+	}
+
+	mask := cardMask(opts, bounds, dpi, -1)
+	draw.DrawMask(img, bounds, image.NewUniform(c), image.Point{}, mask, image.Point{}, draw.Over)
+
+	return img
+}
+
+// ttsURL returns the URL TTS loads an image from: the configured base URL
+// when the files are hosted, otherwise the local file.
+func ttsURL(path string, opts options) string {
+	if opts.ttsBaseURL != "" {
+		return opts.ttsBaseURL + "/" + filepath.Base(path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	// Windows paths start with the drive letter, which follows a slash in
+	// file URLs.
+	p := filepath.ToSlash(abs)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTTSURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "my deck", "face 1.png")
+	got := ttsURL(path, options{})
+	if !strings.HasPrefix(got, "file:///") || !strings.HasSuffix(got, "/my%20deck/face%201.png") {
+		t.Errorf("ttsURL(%q) = %q, want an escaped file URL", path, got)
+	}
+
+	got = ttsURL(path, options{ttsBaseURL: "https://example.com/deck"})
+	if want := "https://example.com/deck/face 1.png"; got != want {
+		t.Errorf("ttsURL with a base URL = %q, want %q", got, want)
+	}
+}
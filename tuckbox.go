@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	defaultCardThickness = 0.3 // mm, typical for 300 g/m² card stock
+	tuckBoxClearance     = 1.0 // mm added to every inner dimension
+	tuckBoxGlueFlap      = 8.0
+	tuckBoxTaper         = 3.0 // how much flaps narrow towards their free edge
+)
+
+// tuckBox holds the inner dimensions of a box for a deck, in mm.
+type tuckBox struct {
+	Width, Height, Depth float64
+}
+
+// newTuckBox sizes a box for the cards of m, given the thickness of one card.
+func newTuckBox(m *Manifest, thickness float64) tuckBox {
+	return tuckBox{
+		Width:  m.CardWidth + tuckBoxClearance,
+		Height: m.CardHeight + tuckBoxClearance,
+		Depth:  float64(len(m.Cards))*thickness + tuckBoxClearance,
+	}
+}
+
+// tuck returns the length of the tuck flaps on the lid and the bottom.
+func (b tuckBox) tuck() float64 {
+	return math.Min(15, b.Height/4)
+}
+
+// size returns the width and height of the flat template.
+func (b tuckBox) size() (float64, float64) {
+	return tuckBoxGlueFlap + 2*b.Width + 2*b.Depth, 2*b.tuck() + 2*b.Depth + b.Height
+}
+
+// draw draws the template with its top-left corner at (x, y). The outline
+// is cut, dashed lines are folded. From left to right the panels are the
+// glue flap, back, side, front and side; the lid and bottom with their tuck
+// flaps hang off the back panel and the sides carry dust flaps.
+func (b tuckBox) draw(pdf *fpdf.Fpdf, x, y float64, name string) {
+	w, h, d, t := b.Width, b.Height, b.Depth, b.tuck()
+	dust := math.Min(d, 15)
+	taper := math.Min(tuckBoxTaper, d/4)
+
+	x0 := x
+	x1 := x0 + tuckBoxGlueFlap
+	x2 := x1 + w
+	x3 := x2 + d
+	x4 := x3 + w
+	x5 := x4 + d
+	yT := y
+	yL := yT + t
+	yB := yL + d
+	yE := yB + h
+	yF := yE + d
+	yZ := yF + t
+
+	outline := []fpdf.PointType{
+		// Lid and its tuck flap.
+		{X: x1, Y: yB}, {X: x1, Y: yL}, {X: x1 + tuckBoxTaper, Y: yT}, {X: x2 - tuckBoxTaper, Y: yT}, {X: x2, Y: yL}, {X: x2, Y: yB},
+		// Top dust flaps with the open top of the front between them.
+		{X: x2 + taper, Y: yB - dust}, {X: x3 - taper, Y: yB - dust}, {X: x3, Y: yB},
+		{X: x4, Y: yB}, {X: x4 + taper, Y: yB - dust}, {X: x5 - taper, Y: yB - dust}, {X: x5, Y: yB},
+		// Bottom dust flaps.
+		{X: x5, Y: yE}, {X: x5 - taper, Y: yE + dust}, {X: x4 + taper, Y: yE + dust}, {X: x4, Y: yE},
+		{X: x3, Y: yE}, {X: x3 - taper, Y: yE + dust}, {X: x2 + taper, Y: yE + dust}, {X: x2, Y: yE},
+		// Bottom and its tuck flap.
+		{X: x2, Y: yF}, {X: x2 - tuckBoxTaper, Y: yZ}, {X: x1 + tuckBoxTaper, Y: yZ}, {X: x1, Y: yF}, {X: x1, Y: yE},
+		// Glue flap.
+		{X: x0, Y: yE - tuckBoxTaper}, {X: x0, Y: yB + tuckBoxTaper},
+	}
+
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(defaultLineWidth)
+	pdf.Polygon(outline, "D")
+
+	pdf.SetDashPattern([]float64{2, 1.5}, 0)
+	for _, fx := range []float64{x1, x2, x3, x4} {
+		pdf.Line(fx, yB, fx, yE)
+	}
+	for _, fy := range []float64{yL, yB, yE, yF} {
+		pdf.Line(x1, fy, x2, fy)
+	}
+	pdf.Line(x2, yB, x3, yB)
+	pdf.Line(x4, yB, x5, yB)
+	pdf.Line(x2, yE, x3, yE)
+	pdf.Line(x4, yE, x5, yE)
+	pdf.SetDashPattern([]float64{}, 0)
+
+	// The deck name goes on the front panel.
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.SetXY(x3, yB+h/2-5)
+	pdf.CellFormat(w, 10, tr(name), "", 0, "C", false, 0, "")
+}
+
+// addTuckBoxPage adds a page with the template, on the configured paper in
+// whichever orientation the template fits.
+func addTuckBoxPage(pdf *fpdf.Fpdf, box tuckBox, opts options) error {
+	page, err := pageDimensions(opts.pageSize, "portrait")
+	if err != nil {
+		return err
+	}
+
+	bw, bh := box.size()
+	fits := func(p fpdf.SizeType) bool {
+		return bw+2*opts.pageMargin <= p.Wd && bh+2*opts.pageMargin <= p.Ht
+	}
+	if !fits(page) {
+		page.Wd, page.Ht = page.Ht, page.Wd
+		if !fits(page) {
+			return fmt.Errorf("the %.0fx%.0f mm tuck box template does not fit on %s paper", bw, bh, opts.pageSize)
+		}
+	}
+
+	pdf.AddPageFormat("P", page)
+	box.draw(pdf, (page.Wd-bw)/2, (page.Ht-bh)/2, opts.deckName)
+	return nil
+}
+
+// writeTuckBoxPDF writes the template as a PDF of its own.
+func writeTuckBoxPDF(m *Manifest, opts options) error {
+	pdf := fpdf.NewCustom(&fpdf.InitType{OrientationStr: "P", UnitStr: "mm", SizeStr: "A4"})
+	pdf.SetAutoPageBreak(false, 0)
+	if err := addTuckBoxPage(pdf, newTuckBox(m, opts.cardThickness), opts); err != nil {
+		return err
+	}
+	return writeAtomic(opts.tuckBoxFile, func(w io.Writer) error {
+		defer pdf.Close()
+		return pdf.Output(w)
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// feedbackInput is a huh input whose description is recomputed from the
+// current value on every keystroke, so the user sees the consequences of
+// their input before the form is submitted.
+type feedbackInput struct {
+	*huh.Input
+	value    *string
+	feedback func(string) string
+}
+
+// newFeedbackInput binds input to value and wraps it. The input has to be
+// fully configured beforehand: its builder methods return the bare input,
+// not the wrapper.
+func newFeedbackInput(input *huh.Input, value *string, feedback func(string) string) *feedbackInput {
+	input.Value(value).Description(feedback(*value))
+	return &feedbackInput{Input: input, value: value, feedback: feedback}
+}
+
+// Update forwards the message to the wrapped input and refreshes the
+// description. It returns the wrapper so the form keeps using it.
+func (f *feedbackInput) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	_, cmd := f.Input.Update(msg)
+	f.Input.Description(f.feedback(*f.value))
+	return f, cmd
+}
+
+// symbolsFeedback describes the deck that the given symbols-per-card input
+// would produce: how many images it needs, how many cards it can have at
+// most and whether the available images suffice. A negative available count
+// means there is no limit. logo is the number of symbols per card, 0 or 1,
+// that are not drawn from the available images.
+func symbolsFeedback(input string, available, lambda, logo int) string {
+	suggestion := ""
+	if symbols, cards, ok := suggestSymbols(available, lambda); ok {
+		suggestion = fmt.Sprintf("; %d images suit up to %d symbols per card (%d cards)", available, symbols+logo, cards)
+	}
+
+	symbols, err := strconv.Atoi(input)
+	if err != nil {
+		counts := symbolCounts(lambda)
+		for i := range counts {
+			counts[i] += logo
+		}
+		return fmt.Sprintf("valid values: %s%s", formatCounts(counts), suggestion)
+	}
+	symbols -= logo
+	required, err := deckSize(symbols, lambda)
+	if err != nil {
+		return "✗ " + err.Error()
+	}
+
+	if available < 0 {
+		return fmt.Sprintf("✓ needs %d images, up to %d cards", required, required)
+	}
+	if available < required {
+		if cards, err := partialDeckCards(symbols, lambda, available); err == nil && cards >= 2 {
+			return fmt.Sprintf("⚠ needs %d images, only %d available: partial deck of %d cards%s", required, available, cards, suggestion)
+		}
+		return fmt.Sprintf("✗ needs %d images, up to %d cards; only %d available%s", required, required, available, suggestion)
+	}
+	return fmt.Sprintf("✓ needs %d images, up to %d cards; %d available", required, required, available)
+}
+
+// validateSymbols rejects symbols-per-card values that cannot form a deck
+// from the available images, not even a partial one. A negative available
+// count means there is no limit. logo is as for symbolsFeedback.
+func validateSymbols(input string, available, lambda, logo int) error {
+	symbols, err := strconv.Atoi(input)
+	if err != nil {
+		return errors.New("enter a number")
+	}
+	symbols -= logo
+	required, err := deckSize(symbols, lambda)
+	if err != nil {
+		return err
+	}
+	if available >= 0 && available < required {
+		// A partial deck is offered after the form.
+		if cards, err := partialDeckCards(symbols, lambda, available); err == nil && cards >= 2 {
+			return nil
+		}
+		return fmt.Errorf("not enough images: required %d, available %d", required, available)
+	}
+	return nil
+}
+
+// cardCountOptions lists the possible numbers of cards of a deck whose full
+// version has full cards, the full deck first.
+func cardCountOptions(full int) []huh.Option[int] {
+	options := []huh.Option[int]{huh.NewOption(fmt.Sprintf("%d (full deck)", full), full)}
+	for n := full - 1; n >= 1; n-- {
+		options = append(options, huh.NewOption(strconv.Itoa(n), n))
+	}
+	return options
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSymbolsMatchesFeedback(t *testing.T) {
+	tests := []struct {
+		input           string
+		available, logo int
+		wantErr         bool
+		feedbackPrefix  string
+	}{
+		{input: "4", available: 13, logo: 0, feedbackPrefix: "✓"},
+		{input: "4", available: 2, logo: 0, wantErr: true, feedbackPrefix: "✗"},
+		// With a logo, 4 symbols per card are a deck of 3 from 7 images;
+		// 5 of them still make a partial deck.
+		{input: "4", available: 7, logo: 1, feedbackPrefix: "✓"},
+		{input: "4", available: 5, logo: 1, feedbackPrefix: "⚠"},
+		{input: "4", available: 2, logo: 1, wantErr: true, feedbackPrefix: "✗"},
+	}
+	for _, tt := range tests {
+		err := validateSymbols(tt.input, tt.available, 1, tt.logo)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateSymbols(%q, %d, logo %d) = %v, want error %v", tt.input, tt.available, tt.logo, err, tt.wantErr)
+		}
+		if feedback := symbolsFeedback(tt.input, tt.available, 1, tt.logo); !strings.HasPrefix(feedback, tt.feedbackPrefix) {
+			t.Errorf("symbolsFeedback(%q, %d, logo %d) = %q, want prefix %q", tt.input, tt.available, tt.logo, feedback, tt.feedbackPrefix)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+)
+
+// deckViolation describes a card, or pair of cards, that breaks the Dobble
+// property.
+type deckViolation struct {
+	CardA, CardB int
+	Shared       int
+}
+
+func (v deckViolation) String() string {
+	if v.CardA == v.CardB {
+		return fmt.Sprintf("card %d contains a symbol more than once", v.CardA)
+	}
+	return fmt.Sprintf("cards %d and %d share %d symbols", v.CardA, v.CardB, v.Shared)
+}
+
+// validateDeck checks that no card repeats a symbol and that every pair of
+// cards shares exactly lambda symbols, one in a classic deck.
+func validateDeck(cards [][]string, lambda int) []deckViolation {
+	var violations []deckViolation
+
+	sets := make([]map[string]bool, len(cards))
+	for i, card := range cards {
+		sets[i] = make(map[string]bool, len(card))
+		for _, symbol := range card {
+			sets[i][symbol] = true
+		}
+		if len(sets[i]) != len(card) {
+			violations = append(violations, deckViolation{CardA: i, CardB: i})
+		}
+	}
+
+	for i := range cards {
+		for j := i + 1; j < len(cards); j++ {
+			shared := 0
+			for symbol := range sets[i] {
+				if sets[j][symbol] {
+					shared++
+				}
+			}
+			if shared != max(lambda, 1) {
+				violations = append(violations, deckViolation{CardA: i, CardB: j, Shared: shared})
+			}
+		}
+	}
+
+	return violations
+}
+
+// runValidate implements the validate command, which checks the deck stored
+// in a manifest file.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dobble validate <manifest.json>")
+		fs.PrintDefaults()
+	}
+	var logs logConfig
+	logs.register(fs)
+	fs.Parse(args)
+	if err := logs.apply(fs); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one manifest file")
+	}
+
+	m, err := loadManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	violations := validateDeck(m.symbolCards(), m.Lambda)
+	for _, v := range violations {
+		slog.Error("Invalid deck", "problem", v.String())
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("deck has %d violations", len(violations))
+	}
+
+	slog.Info("Deck is valid", "cards", len(m.Cards))
+	return nil
+}
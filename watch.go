@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// watchInterval is how often the inputs are checked for changes. Polling
+// works everywhere and is cheap for a directory of symbol images.
+const watchInterval = 500 * time.Millisecond
+
+// fileState is what changes to a watched file are detected by.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// watchPaths returns the inputs of the deck: the image directory and every
+// file named by a flag.
+func (o options) watchPaths() []string {
+	paths := []string{o.imgDir}
+	paths = append(paths, o.images...)
+	for _, p := range []string{o.textSymbols, o.font, o.glyphFont, o.glyphList, o.symbolList, o.urlList, o.back.Image, o.logo, o.iccProfile, o.constraintsFile, o.translationsFile} {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// outputPaths returns the files and directories a run writes, so watching
+// ignores them even when they lie in a watched directory.
+func (o options) outputPaths() []string {
+	paths := []string{o.generatedDir, o.manifestPath()}
+	for _, format := range o.formats {
+		paths = append(paths, o.outputPath(format))
+	}
+	for _, p := range []string{o.stats, o.usageCSV, o.tuckBoxFile, o.tempDir, o.pprofDir} {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// snapshot records the state of the files under paths. Hidden files, the
+// temporary .part files of writeAtomic and the skipped outputs are left out.
+func snapshot(paths []string, skip map[string]bool) map[string]fileState {
+	files := make(map[string]fileState)
+	for _, root := range paths {
+		// Missing files are not an error: they show up as removed.
+		filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if p != root && (isHidden(d.Name()) || skip[p]) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if p != root && (isHidden(d.Name()) || skip[p] || filepath.Ext(p) == ".part") {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				files[p] = fileState{modTime: info.ModTime(), size: info.Size()}
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// waitForChange blocks until the files under paths differ from last and have
+// stopped changing, so a batch of copied images triggers one regeneration.
+func waitForChange(paths []string, skip map[string]bool, last map[string]fileState) map[string]fileState {
+	for {
+		time.Sleep(watchInterval)
+		cur := snapshot(paths, skip)
+		if maps.Equal(cur, last) {
+			continue
+		}
+		for {
+			time.Sleep(watchInterval)
+			next := snapshot(paths, skip)
+			if maps.Equal(next, cur) {
+				return cur
+			}
+			cur = next
+		}
+	}
+}
+
+// watch generates the deck and regenerates it whenever its inputs change,
+// until the process is interrupted. Failed runs are logged and the previous
+// PDF is kept. The seed stays the same, so only the changed symbols move.
+func watch(opts options) error {
+	var preview *watchPreview
+	if opts.watchAddr != "" {
+		preview = &watchPreview{}
+		go func() {
+			slog.Info("Serving preview", "url", "http://"+opts.watchAddr)
+			if err := http.ListenAndServe(opts.watchAddr, preview.routes()); err != nil {
+				slog.Error("Preview server failed", "error", err)
+			}
+		}()
+	}
+
+	paths := opts.watchPaths()
+	// Outputs with placeholders change every run, so all of them are kept.
+	skip := make(map[string]bool)
+	addSkip := func(o options) {
+		for _, p := range o.outputPaths() {
+			skip[filepath.Clean(p)] = true
+		}
+	}
+	addSkip(opts)
+	state := snapshot(paths, skip)
+	for first := true; ; first = false {
+		run := opts
+		run.images = slices.Clone(opts.images)
+		_, err := generate(&run)
+		addSkip(run)
+		if first && errors.Is(err, errOutputExists) {
+			return err
+		}
+		// The first run checked the output; rebuilds replace it, also
+		// after a failed first run.
+		opts.force = true
+		if err != nil {
+			slog.Error("Generation failed", "error", err)
+		} else if preview != nil && run.wants("pdf") {
+			preview.update(run.output)
+		}
+
+		slog.Info("Watching for changes", "paths", paths)
+		state = waitForChange(paths, skip, state)
+		slog.Info("Inputs changed, regenerating")
+	}
+}
+
+// watchPreview serves the latest PDF in a page that reloads it after every
+// regeneration.
+type watchPreview struct {
+	mu      sync.Mutex
+	path    string
+	version int
+}
+
+func (p *watchPreview) update(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.path = path
+	p.version++
+}
+
+func (p *watchPreview) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, watchPreviewPage)
+	})
+	mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		fmt.Fprint(w, p.version)
+	})
+	mux.HandleFunc("GET /deck.pdf", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		path := p.path
+		p.mu.Unlock()
+		if path == "" {
+			http.Error(w, "no PDF generated yet", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		http.ServeFile(w, r, path)
+	})
+	return mux
+}
+
+const watchPreviewPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Dobble preview</title>
+<style>html, body, iframe { margin: 0; width: 100%; height: 100%; border: 0; }</style>
+</head>
+<body>
+<iframe id="pdf" src="/deck.pdf"></iframe>
+<script>
+let version = null;
+setInterval(async () => {
+	const v = await fetch("/version").then(r => r.text()).catch(() => version);
+	if (version !== null && v !== version) {
+		document.getElementById("pdf").src = "/deck.pdf?v=" + v;
+	}
+	version = v;
+}, 1000);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSnapshotSkipsOutputs(t *testing.T) {
+	dir := t.TempDir()
+	opts := options{
+		imgDir:       dir,
+		output:       filepath.Join(dir, "deck.pdf"),
+		formats:      []string{"pdf", "png"},
+		pngDir:       filepath.Join(dir, "cards"),
+		stats:        filepath.Join(dir, "stats.json"),
+		generatedDir: filepath.Join(dir, "generated"),
+	}
+	for _, f := range []string{"apple.png", "deck.pdf", "deck.json", "stats.json", "deck.pdf-123.part", "cards/card_001.png", "generated/shape_1.png"} {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	skip := make(map[string]bool)
+	for _, p := range opts.outputPaths() {
+		skip[filepath.Clean(p)] = true
+	}
+	var files []string
+	for p := range snapshot(opts.watchPaths(), skip) {
+		files = append(files, filepath.Base(p))
+	}
+	if !slices.Equal(files, []string{"apple.png"}) {
+		t.Errorf("snapshot watches %q, want only apple.png", files)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// zipSep separates the archive from the entry in the path of a symbol that
+// is read directly from a zip file, as in "clipart.zip!/animals/cat.png".
+const zipSep = "!/"
+
+// isZip reports whether path names a zip archive.
+func isZip(p string) bool {
+	return strings.EqualFold(path.Ext(p), ".zip")
+}
+
+// splitZipPath splits a symbol path into archive and entry. ok is false for
+// plain files.
+func splitZipPath(p string) (archive, entry string, ok bool) {
+	archive, entry, ok = strings.Cut(p, zipSep)
+	return archive, entry, ok && isZip(archive)
+}
+
+// discoverZipImages lists the supported images in a zip archive that pass
+// the filter. Folders inside the archive are searched regardless of
+// filter.Recursive, since clipart packs usually group their images; hidden
+// and macOS metadata entries are skipped.
+func discoverZipImages(archive string, filter imageFilter) ([]string, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image archive: %w", err)
+	}
+	defer r.Close()
+
+	var images []string
+	for _, f := range r.File {
+		name := f.Name
+		if f.FileInfo().IsDir() || strings.HasPrefix(name, "__MACOSX/") || slices.ContainsFunc(strings.Split(name, "/"), isHidden) {
+			continue
+		}
+		if supportedImageExts[strings.ToLower(path.Ext(name))] && filter.match(name) {
+			images = append(images, archive+zipSep+name)
+		}
+	}
+
+	return images, nil
+}
+
+// readSymbolFile returns the contents of a symbol, which is either a plain
+// file or an entry of a zip archive.
+func readSymbolFile(p string) ([]byte, error) {
+	archive, entry, ok := splitZipPath(p)
+	if !ok {
+		return os.ReadFile(p)
+	}
+
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	f, err := r.Open(entry)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}